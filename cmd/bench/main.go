@@ -1,20 +1,31 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"opentui-bench/internal/cache"
 	"opentui-bench/internal/db"
+	"opentui-bench/internal/record"
 	"opentui-bench/internal/runner"
+	"opentui-bench/internal/stats"
 	"opentui-bench/internal/web"
 )
 
@@ -37,16 +48,26 @@ func main() {
 	rootCmd.PersistentFlags().StringVar(&dbPath, "db", defaultDBPath(), "database path")
 
 	rootCmd.AddCommand(recordCmd())
+	rootCmd.AddCommand(importCmd())
+	rootCmd.AddCommand(exportCmd())
 	rootCmd.AddCommand(listCmd())
 	rootCmd.AddCommand(showCmd())
+	rootCmd.AddCommand(logsCmd())
 	rootCmd.AddCommand(compareCmd())
+	rootCmd.AddCommand(abCmd())
+	rootCmd.AddCommand(bisectCmd())
 	rootCmd.AddCommand(trendCmd())
 	rootCmd.AddCommand(deleteCmd())
 	rootCmd.AddCommand(serveCmd())
 	rootCmd.AddCommand(hasCommitCmd())
 	rootCmd.AddCommand(latestCommitCmd())
+	rootCmd.AddCommand(reindexCmd())
 	rootCmd.AddCommand(backfillCmd())
+	rootCmd.AddCommand(watchCmd())
+	rootCmd.AddCommand(workerCmd())
 	rootCmd.AddCommand(flamegraphCmd())
+	rootCmd.AddCommand(dbCmd())
+	rootCmd.AddCommand(gcCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -57,6 +78,7 @@ func main() {
 func recordCmd() *cobra.Command {
 	var cfg runner.RunConfig
 	var profileStr string
+	var targetCI string
 
 	cmd := &cobra.Command{
 		Use:   "record",
@@ -83,6 +105,10 @@ func recordCmd() *cobra.Command {
 				return fmt.Errorf("invalid profile mode: %s", profileStr)
 			}
 
+			if cfg.TargetCI, err = parsePercent(targetCI); err != nil {
+				return fmt.Errorf("invalid --target-ci: %w", err)
+			}
+
 			runID, err := runner.Run(cmd.Context(), database, cfg)
 			if err != nil {
 				return err
@@ -94,8 +120,13 @@ func recordCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&cfg.RepoPath, "repo", "", "path to opentui repo (required)")
+	cmd.Flags().StringVar(&cfg.Commit, "commit", "HEAD", "commit to benchmark (built in a separate worktree)")
+	cmd.Flags().StringVar(&cfg.WorkDir, "worktree", "", "reuse this worktree directory between runs (default: temporary)")
+	addHarnessFlags(cmd, &cfg)
+	cmd.Flags().BoolVar(&cfg.TimeBuild, "time-build", false, "also time a zig build with an empty cache and record it as build_time")
 	cmd.Flags().StringVar(&cfg.ZigOptimize, "optimize", "ReleaseFast", "zig optimization level")
-	cmd.Flags().IntVar(&cfg.Samples, "samples", 1, "number of benchmark samples")
+	cmd.Flags().IntVar(&cfg.Samples, "samples", 1, "number of benchmark samples (minimum when --target-ci is set)")
+	addTargetCIFlags(cmd, &cfg, &targetCI)
 	cmd.Flags().StringVar(&cfg.Filter, "filter", "", "filter benchmarks by category")
 	cmd.Flags().StringVar(&cfg.FilterBenchmark, "filter-bench", "", "filter benchmarks by name")
 	cmd.Flags().StringVar(&cfg.Notes, "notes", "", "optional notes")
@@ -110,6 +141,191 @@ func recordCmd() *cobra.Command {
 	return cmd
 }
 
+func importCmd() *cobra.Command {
+	var file, repoPath, commit, formatStr string
+	var meta record.RunMetadata
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import pre-recorded benchmark output",
+		Long: `Import benchmark output produced elsewhere (another machine, a CI container)
+without building or running anything. Native JSON lines, hyperfine
+--export-json files and go test -bench output are accepted; the format is
+detected automatically unless --format is given.
+
+Commit metadata is read from --repo when given, otherwise --commit and
+--commit-date are required. Explicit flags override repo metadata.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if repoPath == "" && commit == "" {
+				return fmt.Errorf("either --commit or --repo is required")
+			}
+			// History is ordered by commit, so a run without a commit date
+			// would sort as the oldest one.
+			if repoPath == "" && meta.CommitDate == "" {
+				return fmt.Errorf("--commit-date is required without --repo")
+			}
+			if meta.CommitDate != "" {
+				if _, err := time.Parse(time.RFC3339, meta.CommitDate); err != nil {
+					return fmt.Errorf("invalid --commit-date: %w", err)
+				}
+			}
+			format, err := record.ParseFormat(formatStr)
+			if err != nil {
+				return err
+			}
+
+			var input io.Reader = os.Stdin
+			if file != "" && file != "-" {
+				f, err := os.Open(file)
+				if err != nil {
+					return fmt.Errorf("open input: %w", err)
+				}
+				defer func() { _ = f.Close() }()
+				input = f
+			}
+			raw, err := io.ReadAll(input)
+			if err != nil {
+				return fmt.Errorf("read input: %w", err)
+			}
+			data, format, err := record.Convert(raw, format)
+			if err != nil {
+				return err
+			}
+
+			set, err := record.ParseSamples(bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+			if len(set.Keys) == 0 {
+				return fmt.Errorf("no benchmark results found in input (format %s)", format)
+			}
+
+			runMeta, err := importMetadata(cmd.Context(), repoPath, commit, meta)
+			if err != nil {
+				return err
+			}
+
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			if repoPath != "" {
+				if err := runner.ReadChangedFiles(cmd.Context(), database, repoPath, &runMeta, runner.OSRunner{}); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to read changed files: %v\n", err)
+				}
+			}
+
+			runID, count, err := record.Record(database, bytes.NewReader(data), runMeta)
+			if err != nil {
+				return err
+			}
+
+			color.Green("Imported run #%d (%d benchmarks, commit %s)", runID, count, runMeta.CommitHash)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&file, "file", "f", "", "input file (default: stdin)")
+	cmd.Flags().StringVar(&formatStr, "format", string(record.FormatAuto), "input format (auto, jsonl, hyperfine, gobench)")
+	cmd.Flags().StringVar(&repoPath, "repo", "", "read commit metadata from this repo")
+	cmd.Flags().StringVar(&commit, "commit", "", "commit the results belong to (default with --repo: HEAD)")
+	cmd.Flags().StringVar(&meta.CommitMessage, "commit-message", "", "commit subject (overrides --repo)")
+	cmd.Flags().StringVar(&meta.CommitDate, "commit-date", "", "commit date, RFC 3339 (required without --repo)")
+	cmd.Flags().StringVar(&meta.Branch, "branch", "", "branch name")
+	cmd.Flags().StringVar(&meta.MachineID, "machine", "", "machine the results were produced on")
+	cmd.Flags().StringVar(&meta.Notes, "notes", "", "optional notes")
+	cmd.Flags().StringVar(&meta.ZigOptimize, "optimize", "ReleaseFast", "optimization level the results were built with")
+
+	return cmd
+}
+
+// importMetadata returns the metadata of an imported run: read from repoPath
+// when given, with the fields set in flags taking precedence.
+func importMetadata(ctx context.Context, repoPath, commit string, flags record.RunMetadata) (record.RunMetadata, error) {
+	meta := record.RunMetadata{
+		CommitHashFull: commit,
+		CommitHash:     shortHash(commit),
+	}
+	if repoPath != "" {
+		var err error
+		meta, err = runner.ReadGitMeta(ctx, repoPath, commit, runner.OSRunner{})
+		if err != nil {
+			return meta, fmt.Errorf("read git meta: %w", err)
+		}
+	}
+
+	if flags.CommitMessage != "" {
+		meta.CommitMessage = flags.CommitMessage
+	}
+	if flags.CommitDate != "" {
+		meta.CommitDate = flags.CommitDate
+	}
+	if flags.Branch != "" {
+		meta.Branch = flags.Branch
+	}
+	if flags.MachineID != "" {
+		meta.MachineID = flags.MachineID
+	}
+	meta.Notes = flags.Notes
+	meta.ZigOptimize = flags.ZigOptimize
+	return meta, nil
+}
+
+func exportCmd() *cobra.Command {
+	var format, outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "export [run_id or commit]",
+		Short: "Export run results (gobench: benchstat-compatible text)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != string(record.FormatGoBench) {
+				return fmt.Errorf("unsupported export format: %s", format)
+			}
+
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			run, err := resolveRun(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			if outputFile == "" {
+				return record.ExportGoBench(os.Stdout, database, run.ID)
+			}
+
+			var buf bytes.Buffer
+			if err := record.ExportGoBench(&buf, database, run.ID); err != nil {
+				return err
+			}
+			if err := os.WriteFile(outputFile, buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+			color.Green("Wrote %s (%d bytes)", outputFile, buf.Len())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", string(record.FormatGoBench), "export format (gobench)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file (default: stdout)")
+
+	return cmd
+}
+
 func listCmd() *cobra.Command {
 	var limit int
 	var branch, since string
@@ -140,15 +356,22 @@ func listCmd() *cobra.Command {
 
 			cyan := color.New(color.FgCyan)
 			dim := color.New(color.Faint)
+			red := color.New(color.FgRed)
+			yellow := color.New(color.FgYellow)
 
 			_, _ = cyan.Printf("%-6s %-10s %-12s %-20s %s\n", "ID", "Commit", "Branch", "Date", "Notes")
 			_, _ = dim.Println(strings.Repeat("-", 70))
 
+			runIDs := make([]int64, len(runs))
+			for i, r := range runs {
+				runIDs[i] = r.ID
+			}
+			counts, err := database.CountResultsForRuns(runIDs)
+			if err != nil {
+				return err
+			}
+
 			for _, r := range runs {
-				count, err := database.CountResultsForRun(r.ID)
-				if err != nil {
-					return err
-				}
 				notes := r.Notes
 				if len(notes) > 30 {
 					notes = notes[:27] + "..."
@@ -157,8 +380,16 @@ func listCmd() *cobra.Command {
 				if len(date) > 19 {
 					date = date[:19]
 				}
-				fmt.Printf("%-6d %-10s %-12s %-20s %s (%d benchmarks)\n",
-					r.ID, r.CommitHash, r.Branch, date, notes, count)
+				fmt.Printf("%-6d %-10s %-12s %-20s %s (%d benchmarks)",
+					r.ID, r.CommitHash, r.Branch, date, notes, counts[r.ID])
+				switch r.Status {
+				case db.RunComplete:
+					fmt.Println()
+				case db.RunFailed:
+					_, _ = red.Printf(" FAILED: %s\n", r.FailureReason)
+				default:
+					_, _ = yellow.Printf(" %s\n", r.Status)
+				}
 			}
 
 			return nil
@@ -173,6 +404,8 @@ func listCmd() *cobra.Command {
 }
 
 func showCmd() *cobra.Command {
+	var showSamples bool
+
 	cmd := &cobra.Command{
 		Use:   "show [run_id or commit]",
 		Short: "Show details of a run",
@@ -188,17 +421,9 @@ func showCmd() *cobra.Command {
 				}
 			}()
 
-			var run *db.Run
-			if id, err := strconv.ParseInt(args[0], 10, 64); err == nil {
-				run, err = database.GetRun(id)
-				if err != nil {
-					return fmt.Errorf("run not found: %w", err)
-				}
-			} else {
-				run, err = database.GetRunByCommit(args[0])
-				if err != nil {
-					return fmt.Errorf("run not found for commit: %w", err)
-				}
+			run, err := resolveRun(database, args[0])
+			if err != nil {
+				return err
 			}
 
 			cyan := color.New(color.FgCyan)
@@ -213,6 +438,30 @@ func showCmd() *cobra.Command {
 			if run.Notes != "" {
 				fmt.Printf("Notes:   %s\n", run.Notes)
 			}
+			if run.Status != db.RunComplete {
+				color.Red("Status:  %s", run.Status)
+			}
+			if run.FailureReason != "" {
+				color.Red("Reason:  %s", run.FailureReason)
+			}
+			commit, err := database.GetCommitInfo(run.ID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if commit != nil {
+				printCommitInfo(commit)
+			}
+			if env, err := database.GetRunEnvironment(run.ID); err == nil {
+				fmt.Printf("Machine: %s (%d cores, kernel %s, governor %s)\n",
+					env.CPUModel, env.CPUCores, env.KernelVersion, valueOr(env.CPUGovernor, "n/a"))
+				fmt.Printf("Zig:     %s\n", valueOr(env.ZigVersion, "unknown"))
+				_, _ = dim.Printf("Env:     %s\n", env.Fingerprint)
+			}
+			metrics, err := database.GetRunMetrics(run.ID)
+			if err != nil {
+				return err
+			}
+			printRunMetrics(metrics)
 			fmt.Println()
 
 			results, err := database.GetResultsForRun(run.ID)
@@ -221,16 +470,96 @@ func showCmd() *cobra.Command {
 			}
 
 			printResults(results)
+
+			if showSamples {
+				for _, r := range results {
+					samples, err := database.GetSamplesForResult(r.ID)
+					if err != nil {
+						return err
+					}
+					printSamples(r, samples)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&showSamples, "samples", false, "also print every raw sample per benchmark")
+
+	return cmd
+}
+
+func logsCmd() *cobra.Command {
+	var outputFile string
+
+	cmd := &cobra.Command{
+		Use:   "logs [run_id or commit] [kind]",
+		Short: "List a run's logs, or print one (e.g. build.log, sample.log, perf.log)",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			run, err := resolveRun(database, args[0])
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				logs, err := database.ListRunArtifacts(run.ID)
+				if err != nil {
+					return err
+				}
+				if len(logs) == 0 {
+					fmt.Printf("No logs stored for run #%d\n", run.ID)
+					return nil
+				}
+
+				cyan := color.New(color.FgCyan)
+				_, _ = cyan.Printf("%-16s %10s  %s\n", "Kind", "Size (gz)", "Created")
+				for _, l := range logs {
+					fmt.Printf("%-16s %10d  %s\n", l.Kind, l.Size, l.CreatedAt)
+				}
+				return nil
+			}
+
+			log, err := database.GetRunArtifact(run.ID, args[1])
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("run #%d has no %s", run.ID, args[1])
+			}
+			if err != nil {
+				return err
+			}
+
+			if outputFile == "" {
+				_, err = os.Stdout.Write(log.Data)
+				return err
+			}
+			if err := os.WriteFile(outputFile, log.Data, 0o644); err != nil {
+				return err
+			}
+			color.Green("Wrote %s (%d bytes)", outputFile, len(log.Data))
 			return nil
 		},
 	}
 
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file (default: stdout)")
+
 	return cmd
 }
 
 func compareCmd() *cobra.Command {
 	var threshold float64
 	var filter string
+	var pool bool
+	var branch string
 
 	cmd := &cobra.Command{
 		Use:   "compare [commit1] [commit2]",
@@ -250,7 +579,7 @@ func compareCmd() *cobra.Command {
 			var run1, run2 *db.Run
 
 			if len(args) == 1 {
-				run2, err = database.GetLatestRun()
+				run2, err = database.GetLatestRun(branch)
 				if err != nil {
 					return fmt.Errorf("no runs found: %w", err)
 				}
@@ -269,11 +598,11 @@ func compareCmd() *cobra.Command {
 				}
 			}
 
-			results1, err := database.GetResultsForRun(run1.ID)
+			results1, runs1, err := record.LoadPooledResults(database, run1, pool)
 			if err != nil {
 				return err
 			}
-			results2, err := database.GetResultsForRun(run2.ID)
+			results2, runs2, err := record.LoadPooledResults(database, run2, pool)
 			if err != nil {
 				return err
 			}
@@ -285,15 +614,15 @@ func compareCmd() *cobra.Command {
 			yellow := color.New(color.FgYellow)
 
 			_, _ = cyan.Printf("Comparing %s vs %s\n", run1.CommitHash, run2.CommitHash)
-			_, _ = dim.Printf("Baseline: %s (%s)\n", run1.CommitHash, shortDate(run1.RunDate))
-			_, _ = dim.Printf("Current:  %s (%s)\n", run2.CommitHash, shortDate(run2.RunDate))
+			_, _ = dim.Printf("Baseline: %s (%s)%s\n", run1.CommitHash, shortDate(run1.RunDate), pooledNote(runs1))
+			_, _ = dim.Printf("Current:  %s (%s)%s\n", run2.CommitHash, shortDate(run2.RunDate), pooledNote(runs2))
 			_, _ = dim.Printf("Threshold: %.1f%%\n\n", threshold)
 
 			type resultKey struct {
 				Category string
 				Name     string
 			}
-			results2Map := make(map[resultKey]db.Result)
+			results2Map := make(map[resultKey]record.PooledResult)
 			for _, r := range results2 {
 				results2Map[resultKey{Category: r.Category, Name: r.Name}] = r
 			}
@@ -345,6 +674,31 @@ func compareCmd() *cobra.Command {
 				} else {
 					_, _ = yellow.Printf("%+.1f%%\n", change)
 				}
+
+				for _, pair := range record.PairMemStats(r1.MemStats, r2.MemStats) {
+					fmt.Printf("  %-48s %12s %12s ",
+						pair.Baseline.StatName,
+						formatBytes(pair.Baseline.Bytes),
+						formatBytes(pair.Current.Bytes))
+					if pair.Baseline.Bytes == 0 {
+						_, _ = dim.Printf("n/a\n")
+						continue
+					}
+					memChange := float64(pair.Current.Bytes-pair.Baseline.Bytes) / float64(pair.Baseline.Bytes) * 100
+					switch {
+					case memChange > threshold:
+						_, _ = red.Printf("%+.1f%%\n", memChange)
+					case memChange < -5:
+						_, _ = green.Printf("%+.1f%%\n", memChange)
+					default:
+						_, _ = dim.Printf("%+.1f%%\n", memChange)
+					}
+				}
+
+				if len(runs1) > 1 || len(runs2) > 1 {
+					printPooledRuns("baseline", r1.Runs)
+					printPooledRuns("current", r2.Runs)
+				}
 			}
 
 			_, _ = dim.Println(strings.Repeat("-", 90))
@@ -361,17 +715,49 @@ func compareCmd() *cobra.Command {
 
 	cmd.Flags().Float64Var(&threshold, "threshold", 10, "regression threshold percentage")
 	cmd.Flags().StringVar(&filter, "filter", "", "filter benchmarks by name")
+	cmd.Flags().BoolVar(&pool, "pool", false, "pool all runs of each commit (same machine and optimize mode)")
+	cmd.Flags().StringVar(&branch, "branch", "main", "branch whose latest run is compared when only commit1 is given")
 
 	return cmd
 }
 
-func trendCmd() *cobra.Command {
-	var limit int
+// pooledNote describes which runs a compare side was pooled from.
+func pooledNote(runs []db.Run) string {
+	if len(runs) < 2 {
+		return ""
+	}
+	ids := make([]string, len(runs))
+	for i, r := range runs {
+		ids[i] = "#" + strconv.FormatInt(r.ID, 10)
+	}
+	return fmt.Sprintf(", pooled from runs %s", strings.Join(ids, " "))
+}
+
+// printPooledRuns prints the per-run values behind a pooled result.
+func printPooledRuns(label string, results []db.Result) {
+	dim := color.New(color.Faint)
+	for _, r := range results {
+		_, _ = dim.Printf("  %-8s run #%-6d %12s  ±%s (%d samples)\n",
+			label, r.RunID, formatDuration(r.AvgNs), formatDuration(r.StdDevNs), r.SampleCount)
+	}
+}
+
+func abCmd() *cobra.Command {
+	var cfg runner.RunConfig
 
 	cmd := &cobra.Command{
-		Use:   "trend [benchmark_name]",
-		Short: "Show performance trend over time",
-		Args:  cobra.ExactArgs(1),
+		Use:   "ab [base] [head]",
+		Short: "Benchmark two commits with interleaved samples and compare them",
+		Long: `Build base and head in separate worktrees, then alternate benchmark samples
+between them for --rounds rounds, switching which commit goes first every
+round (ABBA...). Interleaving spreads machine drift over both commits, which
+makes the comparison far more reliable than two separate record calls. Both
+runs are stored and linked as a pair; they are kept out of trends, regression
+baselines and the commits watch considers benchmarked.
+
+Example:
+  bench ab --repo ~/insmo.com/opentui main my-branch --rounds 10`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			database, err := db.Open(dbPath)
 			if err != nil {
@@ -383,69 +769,404 @@ func trendCmd() *cobra.Command {
 				}
 			}()
 
-			trends, err := database.GetTrend(args[0], limit)
+			color.White("Benchmarking %s vs %s (%d rounds)...", args[0], args[1], cfg.Samples)
+			res, err := runner.RunAB(cmd.Context(), database, cfg, args[0], args[1])
 			if err != nil {
 				return err
 			}
+			color.Green("Recorded pair #%d (base run #%d, head run #%d)\n", res.PairID, res.BaseRunID, res.HeadRunID)
 
-			if len(trends) == 0 {
-				fmt.Printf("No results found matching '%s'\n", args[0])
-				return nil
-			}
-
-			cyan := color.New(color.FgCyan)
-			dim := color.New(color.Faint)
-
-			_, _ = cyan.Printf("Trend for: %s\n\n", trends[0].Result.Name)
-			_, _ = cyan.Printf("%-10s %-12s %12s %s\n", "Commit", "Date", "Avg", "Trend")
-			_, _ = dim.Println(strings.Repeat("-", 60))
-
-			var maxNs int64
-			for _, t := range trends {
-				if t.Result.AvgNs > maxNs {
-					maxNs = t.Result.AvgNs
-				}
+			baseResults, err := database.GetResultsForRun(res.BaseRunID)
+			if err != nil {
+				return err
 			}
-
-			for i := len(trends) - 1; i >= 0; i-- {
-				t := trends[i]
-				date := t.Run.RunDate
-				if len(date) > 10 {
-					date = date[:10]
-				}
-
-				barLen := 0
-				if maxNs > 0 {
-					barLen = int(float64(t.Result.AvgNs) / float64(maxNs) * 20)
-				}
-				if barLen > 20 {
-					barLen = 20
-				} else if barLen < 0 {
-					barLen = 0
-				}
-				bar := strings.Repeat("█", barLen) + strings.Repeat("░", 20-barLen)
-
-				fmt.Printf("%-10s %-12s %12s %s\n",
-					t.Run.CommitHash,
-					date,
-					formatDuration(t.Result.AvgNs),
-					bar)
+			headResults, err := database.GetResultsForRun(res.HeadRunID)
+			if err != nil {
+				return err
 			}
 
+			printABComparison(baseResults, headResults)
 			return nil
 		},
 	}
 
-	cmd.Flags().IntVar(&limit, "limit", 20, "max data points")
+	cmd.Flags().StringVar(&cfg.RepoPath, "repo", "", "path to opentui repo (required)")
+	cmd.Flags().IntVar(&cfg.Samples, "rounds", 10, "number of ABBA rounds (samples per commit)")
+	addHarnessFlags(cmd, &cfg)
+	cmd.Flags().StringVar(&cfg.ZigOptimize, "optimize", "ReleaseFast", "zig optimization level")
+	cmd.Flags().StringVar(&cfg.Filter, "filter", "", "filter benchmarks by category")
+	cmd.Flags().StringVar(&cfg.FilterBenchmark, "filter-bench", "", "filter benchmarks by name")
+	cmd.Flags().StringVar(&cfg.Notes, "notes", "", "optional notes (default: ab <base>..<head>)")
+	cmd.Flags().StringVar(&cfg.MachineID, "machine", "", "machine identifier")
+
+	if err := cmd.MarkFlagRequired("repo"); err != nil {
+		panic(err)
+	}
 
 	return cmd
 }
 
-func deleteCmd() *cobra.Command {
-	var before string
+func printABComparison(baseResults, headResults []db.Result) {
+	cyan := color.New(color.FgCyan)
+	dim := color.New(color.Faint)
+	red := color.New(color.FgRed)
+	green := color.New(color.FgGreen)
+	yellow := color.New(color.FgYellow)
 
-	cmd := &cobra.Command{
-		Use:   "delete [run_id]",
+	type resultKey struct {
+		Category string
+		Name     string
+	}
+	headMap := make(map[resultKey]db.Result)
+	for _, r := range headResults {
+		headMap[resultKey{Category: r.Category, Name: r.Name}] = r
+	}
+
+	_, _ = cyan.Printf("%-50s %12s %12s %10s  %s\n", "Benchmark", "Base", "Head", "Change", "Verdict")
+	_, _ = dim.Println(strings.Repeat("-", 100))
+
+	slower, faster := 0, 0
+	for _, base := range baseResults {
+		head, ok := headMap[resultKey{Category: base.Category, Name: base.Name}]
+		if !ok {
+			continue
+		}
+
+		c := stats.CompareMeans(runStat(base), runStat(head))
+
+		fmt.Printf("%-50s %12s %12s %+9.1f%%  ",
+			truncate(base.Name, 50),
+			formatDuration(base.AvgNs),
+			formatDuration(head.AvgNs),
+			c.ChangePercent)
+
+		switch {
+		case !c.Sufficient:
+			_, _ = yellow.Println("insufficient data")
+		case c.Significant && c.T > 0:
+			_, _ = red.Println("slower")
+			slower++
+		case c.Significant:
+			_, _ = green.Println("faster")
+			faster++
+		default:
+			_, _ = dim.Println("no significant change")
+		}
+	}
+
+	_, _ = dim.Println(strings.Repeat("-", 100))
+	fmt.Printf("\nSummary: %d significantly slower, %d significantly faster (95%% Welch t-test)\n", slower, faster)
+}
+
+func runStat(r db.Result) stats.RunStat {
+	sem := float64(0)
+	if r.SampleCount >= 2 {
+		sem = float64(r.StdDevNs) / math.Sqrt(float64(r.SampleCount))
+	}
+	return stats.RunStat{
+		RunID:       r.RunID,
+		Mean:        float64(r.AvgNs),
+		Sem:         sem,
+		SampleCount: r.SampleCount,
+		StdDev:      float64(r.StdDevNs),
+	}
+}
+
+func bisectCmd() *cobra.Command {
+	var cfg runner.RunConfig
+	var good, bad, category, bench string
+
+	cmd := &cobra.Command{
+		Use:   "bisect",
+		Short: "Find the commit that regressed a benchmark",
+		Long: `Binary-search the first-parent commits between --good and --bad for the
+first commit where --category/--bench regressed. Each tested commit is
+recorded as a run and compared against the good commit with the same
+regression test used by /api/regressions. Commits that fail to build or
+benchmark are skipped, like git bisect skip.
+
+Example:
+  bench bisect --repo ~/insmo.com/opentui --good v0.1.0 --bad main --category render --bench "render 80x24"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			res, err := runner.Bisect(cmd.Context(), database, cfg, good, bad, category, bench)
+			if err != nil {
+				return err
+			}
+
+			cyan := color.New(color.FgCyan)
+			dim := color.New(color.Faint)
+			red := color.New(color.FgRed)
+			green := color.New(color.FgGreen)
+			yellow := color.New(color.FgYellow)
+
+			fmt.Println()
+			_, _ = cyan.Println("Tested commits")
+			_, _ = dim.Println(strings.Repeat("-", 50))
+			for _, step := range res.Steps {
+				switch step.Status {
+				case runner.BisectBad:
+					_, _ = red.Printf("%-10s bad   run #%-6d %+.1f%%\n", shortHash(step.Commit), step.RunID, *step.ChangePercent)
+				case runner.BisectSkip:
+					_, _ = yellow.Printf("%-10s skip  %s\n", shortHash(step.Commit), truncate(strings.SplitN(step.Reason, "\n", 2)[0], 60))
+				default:
+					_, _ = green.Printf("%-10s good  run #%d\n", shortHash(step.Commit), step.RunID)
+				}
+			}
+
+			if len(res.Candidates) > 0 {
+				fmt.Println()
+				_, _ = red.Println("Skipped commits leave the first bad commit ambiguous; it is one of:")
+				for _, commit := range res.Candidates {
+					fmt.Printf("  %s\n", shortHash(commit))
+				}
+				return nil
+			}
+
+			meta, err := runner.ReadGitMeta(cmd.Context(), cfg.RepoPath, res.FirstBad, runner.OSRunner{})
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			_, _ = red.Printf("First bad commit: %s %s\n", meta.CommitHash, meta.CommitMessage)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.RepoPath, "repo", "", "path to opentui repo (required)")
+	cmd.Flags().StringVar(&good, "good", "", "commit known to be fast (required)")
+	cmd.Flags().StringVar(&bad, "bad", "", "commit known to be slow (required)")
+	cmd.Flags().StringVar(&category, "category", "", "category of the benchmark (required)")
+	cmd.Flags().StringVar(&bench, "bench", "", "benchmark name to bisect (required)")
+	cmd.Flags().StringVar(&cfg.WorkDir, "worktree", "", "reuse this worktree directory between commits (default: temporary per commit)")
+	addHarnessFlags(cmd, &cfg)
+	cmd.Flags().StringVar(&cfg.ZigOptimize, "optimize", "ReleaseFast", "zig optimization level")
+	cmd.Flags().IntVar(&cfg.Samples, "samples", 5, "number of benchmark samples per commit")
+	cmd.Flags().StringVar(&cfg.Notes, "notes", "", "notes for recorded runs (default: bisect <good>..<bad>)")
+	cmd.Flags().StringVar(&cfg.MachineID, "machine", "", "machine identifier")
+
+	for _, name := range []string{"repo", "good", "bad", "category", "bench"} {
+		if err := cmd.MarkFlagRequired(name); err != nil {
+			panic(err)
+		}
+	}
+
+	return cmd
+}
+
+func trendCmd() *cobra.Command {
+	var limit int
+	var pool bool
+	var mem string
+	var metric string
+
+	cmd := &cobra.Command{
+		Use:   "trend [benchmark_name]",
+		Short: "Show performance trend over time",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if metric != "" {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			if metric != "" {
+				return printMetricTrend(database, metric, limit)
+			}
+			if mem != "" {
+				if pool {
+					return errors.New("--mem cannot be combined with --pool")
+				}
+				return printMemTrend(database, args[0], mem, limit)
+			}
+
+			trends, err := database.GetTrend(args[0], limit)
+			if err != nil {
+				return err
+			}
+
+			if len(trends) == 0 {
+				fmt.Printf("No results found matching '%s'\n", args[0])
+				return nil
+			}
+			if pool {
+				pooled := record.PoolTrend(trends)
+				trends = trends[:0]
+				for _, p := range pooled {
+					trends = append(trends, db.TrendPoint{Run: p.Run, Result: p.Result.Result})
+				}
+			}
+
+			cyan := color.New(color.FgCyan)
+			dim := color.New(color.Faint)
+
+			_, _ = cyan.Printf("Trend for: %s\n\n", trends[0].Result.Name)
+			_, _ = cyan.Printf("%-10s %-12s %12s %s\n", "Commit", "Date", "Avg", "Trend")
+			_, _ = dim.Println(strings.Repeat("-", 60))
+
+			var maxNs int64
+			for _, t := range trends {
+				if t.Result.AvgNs > maxNs {
+					maxNs = t.Result.AvgNs
+				}
+			}
+
+			for i := len(trends) - 1; i >= 0; i-- {
+				t := trends[i]
+				date := t.Run.RunDate
+				if len(date) > 10 {
+					date = date[:10]
+				}
+
+				fmt.Printf("%-10s %-12s %12s %s\n",
+					t.Run.CommitHash,
+					date,
+					formatDuration(t.Result.AvgNs),
+					trendBar(t.Result.AvgNs, maxNs))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 20, "max data points")
+	cmd.Flags().BoolVar(&pool, "pool", false, "show one pooled point per commit (same machine and optimize mode)")
+	cmd.Flags().StringVar(&mem, "mem", "", "show the trend of a memory stat (e.g. peak_bytes) instead of time")
+	cmd.Flags().StringVar(&metric, "metric", "", "show the trend of a run metric (build_time, size:opentui-bench, ...) instead of a benchmark")
+
+	return cmd
+}
+
+// printMemTrend prints the average of one memory stat per run, with the
+// min-max range across samples.
+func printMemTrend(database *db.DB, name, stat string, limit int) error {
+	trends, err := database.GetMemTrend(name, stat, limit)
+	if err != nil {
+		return err
+	}
+	if len(trends) == 0 {
+		fmt.Printf("No %s stats found for benchmarks matching '%s'\n", stat, name)
+		return nil
+	}
+
+	cyan := color.New(color.FgCyan)
+	dim := color.New(color.Faint)
+
+	_, _ = cyan.Printf("Memory trend for: %s (%s)\n\n", trends[0].Result.Name, stat)
+	_, _ = cyan.Printf("%-10s %-12s %12s %-20s %s\n", "Commit", "Date", "Avg", "Trend", "Range")
+	_, _ = dim.Println(strings.Repeat("-", 80))
+
+	var maxBytes int64
+	for _, t := range trends {
+		maxBytes = max(maxBytes, t.Stat.Bytes)
+	}
+
+	for i := len(trends) - 1; i >= 0; i-- {
+		t := trends[i]
+		fmt.Printf("%-10s %-12s %12s %s ",
+			t.Run.CommitHash,
+			shortDate(t.Run.RunDate),
+			formatBytes(t.Stat.Bytes),
+			trendBar(t.Stat.Bytes, maxBytes))
+		_, _ = dim.Printf("%s-%s\n", formatBytes(t.Stat.MinBytes), formatBytes(t.Stat.MaxBytes))
+	}
+	return nil
+}
+
+// printMetricTrend prints one run metric, such as the build time or the size
+// of a built artifact, per run.
+func printMetricTrend(database *db.DB, name string, limit int) error {
+	trends, err := database.GetMetricTrend(name, limit)
+	if err != nil {
+		return err
+	}
+	if len(trends) == 0 {
+		fmt.Printf("No runs recorded metric '%s'\n", name)
+		return nil
+	}
+
+	cyan := color.New(color.FgCyan)
+	dim := color.New(color.Faint)
+
+	_, _ = cyan.Printf("Trend for: %s\n\n", name)
+	_, _ = cyan.Printf("%-10s %-12s %12s %s\n", "Commit", "Date", "Value", "Trend")
+	_, _ = dim.Println(strings.Repeat("-", 60))
+
+	var maxValue int64
+	for _, t := range trends {
+		maxValue = max(maxValue, t.Metric.Value)
+	}
+
+	for i := len(trends) - 1; i >= 0; i-- {
+		t := trends[i]
+		fmt.Printf("%-10s %-12s %12s %s\n",
+			t.Run.CommitHash,
+			shortDate(t.Run.RunDate),
+			formatMetric(t.Metric),
+			trendBar(t.Metric.Value, maxValue))
+	}
+	return nil
+}
+
+// printRunMetrics prints the build time and artifact sizes of a run.
+func printRunMetrics(metrics []db.RunMetric) {
+	var sizes []string
+	for _, m := range metrics {
+		switch {
+		case m.Name == runner.MetricBuildTime:
+			fmt.Printf("Build:   %s\n", formatMetric(m))
+		case strings.HasPrefix(m.Name, runner.MetricSizePrefix):
+			sizes = append(sizes, strings.TrimPrefix(m.Name, runner.MetricSizePrefix)+" "+formatMetric(m))
+		default:
+			fmt.Printf("%s: %s\n", m.Name, formatMetric(m))
+		}
+	}
+	if len(sizes) > 0 {
+		fmt.Printf("Size:    %s\n", strings.Join(sizes, ", "))
+	}
+}
+
+func formatMetric(m db.RunMetric) string {
+	if m.Unit == db.MetricUnitBytes {
+		return formatBytes(m.Value)
+	}
+	return formatDuration(m.Value)
+}
+
+// trendBar renders value as a 20-character bar relative to maxValue.
+func trendBar(value, maxValue int64) string {
+	barLen := 0
+	if maxValue > 0 {
+		barLen = int(float64(value) / float64(maxValue) * 20)
+	}
+	barLen = max(0, min(barLen, 20))
+	return strings.Repeat("█", barLen) + strings.Repeat("░", 20-barLen)
+}
+
+func deleteCmd() *cobra.Command {
+	var before string
+
+	cmd := &cobra.Command{
+		Use:   "delete [run_id]",
 		Short: "Delete runs",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			database, err := db.Open(dbPath)
@@ -509,6 +1230,12 @@ func serveCmd() *cobra.Command {
 				}
 			}()
 
+			// A database copied without its blob store would serve runs
+			// whose profiles all fail to load.
+			if err := database.CheckBlobStore(); err != nil {
+				return err
+			}
+
 			addr := fmt.Sprintf(":%d", port)
 			server := web.NewServer(database, addr)
 			return server.Start(open)
@@ -555,9 +1282,11 @@ func hasCommitCmd() *cobra.Command {
 }
 
 func latestCommitCmd() *cobra.Command {
+	var branch string
+
 	cmd := &cobra.Command{
 		Use:   "latest-commit",
-		Short: "Print the most recently recorded commit hash (full)",
+		Short: "Print the newest recorded commit hash (full) of a branch in commit history order",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			database, err := db.Open(dbPath)
 			if err != nil {
@@ -569,7 +1298,7 @@ func latestCommitCmd() *cobra.Command {
 				}
 			}()
 
-			run, err := database.GetLatestRun()
+			run, err := database.GetLatestRun(branch)
 			if err != nil {
 				return fmt.Errorf("no recorded commits")
 			}
@@ -579,6 +1308,45 @@ func latestCommitCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVar(&branch, "branch", "main", "branch to report (falls back to any branch without runs; empty for all)")
+
+	return cmd
+}
+
+func reindexCmd() *cobra.Command {
+	var repoPath string
+
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Store the first-parent sequence of runs recorded without one",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			updated, total, err := runner.SequenceRuns(cmd.Context(), database, repoPath, runner.OSRunner{})
+			if err != nil {
+				return err
+			}
+			if updated < total {
+				color.Yellow("%d commits not found in %s", total-updated, repoPath)
+			}
+			color.Green("Sequenced %d of %d commits", updated, total)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&repoPath, "repo", "", "path to opentui repo (required)")
+	if err := cmd.MarkFlagRequired("repo"); err != nil {
+		panic(err)
+	}
+
 	return cmd
 }
 
@@ -586,9 +1354,11 @@ func backfillCmd() *cobra.Command {
 	var count int
 	var start string
 	var dryRun bool
+	var maxFailures int
 	var flamegraph bool
 	var cfg runner.RunConfig
 	var profileStr string
+	var targetCI string
 
 	cmd := &cobra.Command{
 		Use:   "backfill",
@@ -596,7 +1366,9 @@ func backfillCmd() *cobra.Command {
 		Long: `Backfill benchmarks for historical commits.
 
 This command iterates through N commits starting from --start (default: HEAD),
-running benchmarks for any that haven't been recorded yet.
+running benchmarks for any that haven't been recorded yet. Each commit is built
+in a separate git worktree, so the --repo checkout is left untouched and can be
+used while the backfill runs.
 
 Best run locally on stable hardware for consistent results.
 The GitHub CI runner has variable performance that makes trends noisy.
@@ -632,17 +1404,27 @@ Example:
 				cfg.Profile = runner.ProfileCPU
 			}
 
-			return runBackfill(cmd.Context(), database, count, start, dryRun, cfg)
+			if cfg.TargetCI, err = parsePercent(targetCI); err != nil {
+				return fmt.Errorf("invalid --target-ci: %w", err)
+			}
+
+			return runBackfill(cmd.Context(), database, count, start, dryRun, maxFailures, cfg)
 		},
 	}
 
 	cmd.Flags().IntVar(&count, "count", 10, "number of commits to backfill")
 	cmd.Flags().StringVar(&start, "start", "HEAD", "commit to start from")
+	cmd.Flags().StringVar(&cfg.Branch, "branch", "", "branch to record the runs under (default: the branch --start names)")
 	cmd.Flags().StringVar(&cfg.RepoPath, "repo", "", "path to opentui repo (required)")
+	cmd.Flags().StringVar(&cfg.WorkDir, "worktree", "", "reuse this worktree directory between commits (default: temporary per commit)")
+	addHarnessFlags(cmd, &cfg)
+	cmd.Flags().BoolVar(&cfg.TimeBuild, "time-build", false, "also time a zig build with an empty cache and record it as build_time")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show which commits would be recorded without running benchmarks")
+	cmd.Flags().IntVar(&maxFailures, "max-failures", 2, "skip commits whose runs failed this many times (0: always retry)")
 	cmd.Flags().StringVar(&cfg.Notes, "notes", "backfill", "notes to add to recorded runs")
 	cmd.Flags().StringVar(&cfg.ZigOptimize, "optimize", "ReleaseFast", "zig optimization level")
-	cmd.Flags().IntVar(&cfg.Samples, "samples", 3, "number of benchmark samples")
+	cmd.Flags().IntVar(&cfg.Samples, "samples", 3, "number of benchmark samples (minimum when --target-ci is set)")
+	addTargetCIFlags(cmd, &cfg, &targetCI)
 	cmd.Flags().StringVar(&cfg.Filter, "filter", "", "filter benchmarks by category")
 	cmd.Flags().StringVar(&cfg.FilterBenchmark, "filter-bench", "", "filter benchmarks by name")
 	cmd.Flags().StringVar(&cfg.MachineID, "machine", "", "machine identifier")
@@ -657,6 +1439,170 @@ Example:
 	return cmd
 }
 
+func watchCmd() *cobra.Command {
+	var cfg runner.RunConfig
+	var wcfg watchConfig
+	var profileStr string
+	var targetCI string
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Continuously record new commits of a branch",
+		Long: `Watch a branch and record every new commit.
+
+Each poll fetches --remote/--branch, walks its first-parent history back to
+the newest recorded commit and records the commits after it one at a time,
+oldest first. The database is the queue, so a restarted watcher picks up where
+it stopped. A file lock (default: <db>.watch.lock) keeps a second watcher or
+cron invocation from running benchmarks concurrently.
+
+Example:
+  bench watch --repo ~/repos/opentui --remote origin --branch main --interval 30m
+
+  # Single pass for cron, uploading the database after each recorded commit
+  bench watch --repo ~/repos/opentui --once --post-run ./upload-db.sh`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Profile = runner.ProfileMode(profileStr)
+			switch cfg.Profile {
+			case runner.ProfileNone, runner.ProfileCPU:
+			default:
+				return fmt.Errorf("invalid profile mode: %s", profileStr)
+			}
+
+			var err error
+			if cfg.TargetCI, err = parsePercent(targetCI); err != nil {
+				return fmt.Errorf("invalid --target-ci: %w", err)
+			}
+
+			if wcfg.lockPath == "" {
+				wcfg.lockPath = dbPath + ".watch.lock"
+			}
+			lock, err := runner.AcquireLock(wcfg.lockPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := lock.Release(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error releasing lock: %v\n", err)
+				}
+			}()
+
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return runWatch(ctx, database, wcfg, cfg)
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.RepoPath, "repo", "", "path to opentui repo (required)")
+	cmd.Flags().StringVar(&wcfg.remote, "remote", "origin", "git remote to fetch")
+	cmd.Flags().StringVar(&wcfg.branch, "branch", "main", "branch to watch")
+	cmd.Flags().DurationVar(&wcfg.interval, "interval", 30*time.Minute, "time between polls")
+	cmd.Flags().BoolVar(&wcfg.once, "once", false, "poll once, record pending commits and exit")
+	cmd.Flags().IntVar(&wcfg.maxDepth, "max-depth", 100, "how far back to look for the newest recorded commit")
+	cmd.Flags().IntVar(&wcfg.maxFailures, "max-failures", 2, "skip commits whose runs failed this many times (0: always retry)")
+	cmd.Flags().StringVar(&wcfg.lockPath, "lock", "", "lock file path (default: <db>.watch.lock)")
+	cmd.Flags().StringVar(&wcfg.postRun, "post-run", "", "shell command to run after each recorded commit")
+	cmd.Flags().StringVar(&cfg.WorkDir, "worktree", "", "reuse this worktree directory between commits (default: temporary per commit)")
+	addHarnessFlags(cmd, &cfg)
+	cmd.Flags().BoolVar(&cfg.TimeBuild, "time-build", false, "also time a zig build with an empty cache and record it as build_time")
+	cmd.Flags().StringVar(&cfg.Notes, "notes", "", "notes to add to recorded runs")
+	cmd.Flags().StringVar(&cfg.ZigOptimize, "optimize", "ReleaseFast", "zig optimization level")
+	cmd.Flags().IntVar(&cfg.Samples, "samples", 3, "number of benchmark samples (minimum when --target-ci is set)")
+	addTargetCIFlags(cmd, &cfg, &targetCI)
+	cmd.Flags().StringVar(&cfg.MachineID, "machine", "", "machine identifier")
+	cmd.Flags().StringVar(&profileStr, "profile", string(runner.ProfileNone), "profile mode (none, cpu)")
+	cmd.Flags().IntVar(&cfg.PerfFreq, "perf-freq", 997, "perf sampling frequency")
+
+	if err := cmd.MarkFlagRequired("repo"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func workerCmd() *cobra.Command {
+	var cfg runner.RunConfig
+	var wcfg workerConfig
+	var profileStr string
+
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Execute queued benchmark jobs",
+		Long: `Claim jobs from the jobs table (submitted with POST /api/jobs) and record
+them with the same runner as bench record. Jobs run highest priority first.
+The flags below are defaults; each job's config may override samples,
+profiling, optimize mode, filters, target CI and notes.
+
+A failed job is retried until it used --max-attempts attempts. A job
+interrupted by stopping the worker goes back to the queue without using an
+attempt. While a job runs, the worker renews its lease every quarter of
+--lease; a job whose lease ran out, because its worker died, is requeued (or
+failed once out of attempts) by the next worker.
+
+Example:
+  bench worker --repo ~/repos/opentui --remote origin --worktree ~/repos/bench-wt`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Profile = runner.ProfileMode(profileStr)
+			switch cfg.Profile {
+			case runner.ProfileNone, runner.ProfileCPU:
+			default:
+				return fmt.Errorf("invalid profile mode: %s", profileStr)
+			}
+			if wcfg.lease <= 0 {
+				return fmt.Errorf("--lease must be positive")
+			}
+
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return runWorker(ctx, database, wcfg, cfg)
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.RepoPath, "repo", "", "path to opentui repo (required)")
+	cmd.Flags().StringVar(&wcfg.remote, "remote", "", "git remote to fetch before each job (default: no fetch)")
+	cmd.Flags().DurationVar(&wcfg.poll, "poll", 10*time.Second, "how often to check for new jobs")
+	cmd.Flags().BoolVar(&wcfg.once, "once", false, "exit once the queue is empty")
+	cmd.Flags().IntVar(&wcfg.maxAttempts, "max-attempts", 2, "attempts before a job is marked failed")
+	cmd.Flags().DurationVar(&wcfg.lease, "lease", 10*time.Minute, "requeue running jobs whose worker sent no heartbeat for this long")
+	cmd.Flags().StringVar(&cfg.WorkDir, "worktree", "", "reuse this worktree directory between jobs (default: temporary per job)")
+	addHarnessFlags(cmd, &cfg)
+	cmd.Flags().BoolVar(&cfg.TimeBuild, "time-build", false, "also time a zig build with an empty cache and record it as build_time")
+	cmd.Flags().StringVar(&cfg.ZigOptimize, "optimize", "ReleaseFast", "default zig optimization level")
+	cmd.Flags().IntVar(&cfg.Samples, "samples", 3, "default number of benchmark samples")
+	cmd.Flags().IntVar(&cfg.MaxSamples, "max-samples", 30, "default upper bound on samples with target_ci")
+	cmd.Flags().StringVar(&cfg.MachineID, "machine", "", "machine identifier")
+	cmd.Flags().StringVar(&profileStr, "profile", string(runner.ProfileNone), "default profile mode (none, cpu)")
+	cmd.Flags().IntVar(&cfg.PerfFreq, "perf-freq", 997, "default perf sampling frequency")
+
+	if err := cmd.MarkFlagRequired("repo"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
 func flamegraphCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "flamegraph",
@@ -760,18 +1706,226 @@ func flamegraphSVGCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file (default: stdout)")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "output file (default: stdout)")
+
+	return cmd
+}
+
+func flamegraphStacksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stacks [commit] [benchmark_name]",
+		Short: "Output raw folded stacks for a specific benchmark",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			run, err := database.GetRunByCommit(args[0])
+			if err != nil {
+				return fmt.Errorf("run not found: %w", err)
+			}
+
+			fg, err := database.GetFlamegraph(run.ID, args[1])
+			if err != nil {
+				return fmt.Errorf("flamegraph not found: %w", err)
+			}
+
+			fmt.Print(fg.FoldedStacks)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func flamegraphDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff [commit1] [commit2] [benchmark_name]",
+		Short: "Generate differential flamegraph between two commits for a benchmark",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			run1, err := database.GetRunByCommit(args[0])
+			if err != nil {
+				return fmt.Errorf("run1 not found: %w", err)
+			}
+
+			run2, err := database.GetRunByCommit(args[1])
+			if err != nil {
+				return fmt.Errorf("run2 not found: %w", err)
+			}
+
+			fg1, err := database.GetFlamegraph(run1.ID, args[2])
+			if err != nil {
+				return fmt.Errorf("flamegraph for %s not found: %w", args[0], err)
+			}
+
+			fg2, err := database.GetFlamegraph(run2.ID, args[2])
+			if err != nil {
+				return fmt.Errorf("flamegraph for %s not found: %w", args[1], err)
+			}
+
+			tmpDir, err := os.MkdirTemp("", "flamegraph-diff-")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = os.RemoveAll(tmpDir) }()
+
+			stacks1 := filepath.Join(tmpDir, "stacks1.folded")
+			stacks2 := filepath.Join(tmpDir, "stacks2.folded")
+
+			if err := os.WriteFile(stacks1, []byte(fg1.FoldedStacks), 0o644); err != nil {
+				return err
+			}
+			if err := os.WriteFile(stacks2, []byte(fg2.FoldedStacks), 0o644); err != nil {
+				return err
+			}
+
+			diffCmd := exec.Command("inferno-diff-folded", stacks1, stacks2)
+			diffOut, err := diffCmd.Output()
+			if err != nil {
+				return fmt.Errorf("inferno-diff-folded: %w (is inferno installed?)", err)
+			}
+
+			fgCmd := exec.Command("inferno-flamegraph")
+			fgCmd.Stdin = strings.NewReader(string(diffOut))
+			svg, err := fgCmd.Output()
+			if err != nil {
+				return fmt.Errorf("inferno-flamegraph: %w", err)
+			}
+
+			_, _ = os.Stdout.Write(svg)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func dbCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Inspect and migrate the database",
+	}
+
+	cmd.AddCommand(dbMigrateCmd())
+	cmd.AddCommand(dbVersionCmd())
+	cmd.AddCommand(dbBlobsCmd())
+	cmd.AddCommand(dbMergeJobsCmd())
+
+	return cmd
+}
+
+func dbMergeJobsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "merge-jobs <other.db>",
+		Short: "Copy jobs from another copy of the database",
+		Long: `Copy the jobs of another copy of the database that this one lacks.
+
+scripts/run-benchmarks.sh replaces the server's database with the local one,
+so it first merges the jobs submitted to the server since the download.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.Open(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			n, err := database.MergeJobs(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged %d jobs\n", n)
+			return nil
+		},
+	}
+}
+
+func dbMigrateCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations",
+		Long: `Apply pending schema migrations.
+
+Every other command migrates the database when it opens it; this one does it
+explicitly, or with --dry-run only lists what would be applied.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := db.OpenUnmigrated(dbPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := database.Close(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+				}
+			}()
+
+			pending, err := database.PendingMigrations()
+			if err != nil {
+				return err
+			}
+			if len(pending) == 0 {
+				color.Green("Schema is up to date (version %d)", db.LatestSchemaVersion())
+				return nil
+			}
+
+			if dryRun {
+				cyan := color.New(color.FgCyan)
+				_, _ = cyan.Printf("Would apply %d migration(s):\n", len(pending))
+				for _, m := range pending {
+					fmt.Printf("  %04d_%s\n", m.Version, m.Name)
+				}
+				return nil
+			}
+
+			applied, err := database.Migrate()
+			for _, m := range applied {
+				fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				return err
+			}
+			color.Green("Schema is at version %d", db.LatestSchemaVersion())
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list pending migrations without applying them")
 
 	return cmd
 }
 
-func flamegraphStacksCmd() *cobra.Command {
+func dbVersionCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "stacks [commit] [benchmark_name]",
-		Short: "Output raw folded stacks for a specific benchmark",
-		Args:  cobra.ExactArgs(2),
+		Use:   "version",
+		Short: "Show the schema version and applied migrations",
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			database, err := db.Open(dbPath)
+			database, err := db.OpenUnmigrated(dbPath)
 			if err != nil {
 				return err
 			}
@@ -781,17 +1935,27 @@ func flamegraphStacksCmd() *cobra.Command {
 				}
 			}()
 
-			run, err := database.GetRunByCommit(args[0])
+			version, err := database.SchemaVersion()
 			if err != nil {
-				return fmt.Errorf("run not found: %w", err)
+				return err
 			}
-
-			fg, err := database.GetFlamegraph(run.ID, args[1])
+			applied, err := database.AppliedMigrations()
 			if err != nil {
-				return fmt.Errorf("flamegraph not found: %w", err)
+				return err
 			}
 
-			fmt.Print(fg.FoldedStacks)
+			latest := db.LatestSchemaVersion()
+			fmt.Printf("Schema version: %d (this bench supports %d)\n", version, latest)
+			for _, m := range applied {
+				fmt.Printf("  %04d_%-24s applied %s\n", m.Version, m.Name, m.AppliedAt)
+			}
+
+			switch {
+			case version > latest:
+				color.Yellow("The database was migrated by a newer bench; upgrade this binary to use it.")
+			case version < latest:
+				color.Yellow("%d migration(s) pending; run `bench db migrate`.", latest-version)
+			}
 			return nil
 		},
 	}
@@ -799,11 +1963,112 @@ func flamegraphStacksCmd() *cobra.Command {
 	return cmd
 }
 
-func flamegraphDiffCmd() *cobra.Command {
+func dbBlobsCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "diff [commit1] [commit2] [benchmark_name]",
-		Short: "Generate differential flamegraph between two commits for a benchmark",
-		Args:  cobra.ExactArgs(3),
+		Use:   "blobs",
+		Short: "Move artifact payloads between the database and a blob store",
+		Long: `Move artifact payloads between the database and a blob store.
+
+The blob store is a directory next to the database (<db>.blobs) holding CPU
+profiles and rendered SVGs as files named by their sha256, with identical
+payloads stored once. While it exists, new artifacts are written to it, which
+keeps the database small and quick to copy. Copy the directory along with the
+database; its files never change, so syncing only transfers new ones, as
+scripts/run-benchmarks.sh does for the Fly deployment.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "out",
+		Short: "Move artifact payloads out of the database into the blob store",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMoveBlobs(true)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "in",
+		Short: "Move artifact payloads back into the database and remove the blob store",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMoveBlobs(false)
+		},
+	})
+
+	return cmd
+}
+
+func runMoveBlobs(out bool) error {
+	database, err := db.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing database: %v\n", err)
+		}
+	}()
+
+	dir := database.BlobStore().Dir()
+	before := dbFileSize(dbPath)
+	if out {
+		count, bytes, err := database.MoveArtifactsToBlobStore()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Moved %d artifacts (%s) to %s\n", count, formatBytes(bytes), dir)
+	} else {
+		if !database.BlobStore().Enabled() {
+			color.Yellow("No blob store at %s", dir)
+			return nil
+		}
+		count, bytes, err := database.MoveArtifactsFromBlobStore()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Moved %d artifacts (%s) from %s into the database\n", count, formatBytes(bytes), dir)
+	}
+
+	if err := database.Vacuum(); err != nil {
+		return err
+	}
+	color.Green("Database: %s -> %s", formatBytes(before), formatBytes(dbFileSize(dbPath)))
+	return nil
+}
+
+func gcCmd() *cobra.Command {
+	var keepProfiles int
+	var svgMaxAge time.Duration
+	var svgCacheDir string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Drop old profiles and cached SVGs to reclaim space",
+		Long: `Drop old profiles and cached SVGs to reclaim space.
+
+Runs, results and memory stats are kept forever, and by default so is
+everything else: pruning needs an explicit retention policy. With
+--keep-profiles, profiles are kept for the newest N profiled runs of each
+branch (in history order); older runs lose their CPU profiles, flamegraph
+stacks and the SVGs rendered from them. With --svg-max-age, flamegraph and
+callgraph SVGs older than that are dropped on their own, since the server
+renders them again from the profile on request. Without either flag gc only
+reports, as with --dry-run.
+
+Blob files no artifact references are removed as well. Afterwards the
+database is vacuumed, and the server's SVG cache directories of affected and
+deleted runs are removed.
+
+Example:
+  # Report what a policy would reclaim
+  bench gc --keep-profiles 10 --dry-run
+
+  # Keep the last 10 profiled runs per branch and SVGs from the last 3 days
+  bench gc --keep-profiles 10 --svg-max-age 72h
+
+  # Keep all profiles and SVGs; only remove unreferenced blobs and vacuum
+  bench gc --keep-profiles -1`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			database, err := db.Open(dbPath)
 			if err != nil {
@@ -815,63 +2080,111 @@ func flamegraphDiffCmd() *cobra.Command {
 				}
 			}()
 
-			run1, err := database.GetRunByCommit(args[0])
-			if err != nil {
-				return fmt.Errorf("run1 not found: %w", err)
+			if !cmd.Flags().Changed("keep-profiles") && !cmd.Flags().Changed("svg-max-age") && !dryRun {
+				color.Yellow("No retention policy given (--keep-profiles, --svg-max-age); only reporting")
+				dryRun = true
 			}
 
-			run2, err := database.GetRunByCommit(args[1])
-			if err != nil {
-				return fmt.Errorf("run2 not found: %w", err)
+			policy := db.GCPolicy{KeepProfiles: keepProfiles}
+			if svgMaxAge > 0 {
+				policy.SVGsBefore = time.Now().Add(-svgMaxAge)
 			}
-
-			fg1, err := database.GetFlamegraph(run1.ID, args[2])
+			report, err := database.CollectGarbage(policy, dryRun)
 			if err != nil {
-				return fmt.Errorf("flamegraph for %s not found: %w", args[0], err)
+				return err
 			}
 
-			fg2, err := database.GetFlamegraph(run2.ID, args[2])
+			svgCache, err := cache.NewSVGCache(svgCacheDir, 0)
 			if err != nil {
-				return fmt.Errorf("flamegraph for %s not found: %w", args[1], err)
+				return err
 			}
-
-			tmpDir, err := os.MkdirTemp("", "flamegraph-diff-")
+			cachedRuns, err := gcCachedRuns(database, svgCache, report.RunIDs)
 			if err != nil {
 				return err
 			}
-			defer func() { _ = os.RemoveAll(tmpDir) }()
-
-			stacks1 := filepath.Join(tmpDir, "stacks1.folded")
-			stacks2 := filepath.Join(tmpDir, "stacks2.folded")
+			var cacheBytes int64
+			for _, runID := range cachedRuns {
+				size, err := svgCache.RunSize(runID)
+				if err != nil {
+					return err
+				}
+				cacheBytes += size
+			}
 
-			if err := os.WriteFile(stacks1, []byte(fg1.FoldedStacks), 0o644); err != nil {
-				return err
+			cyan := color.New(color.FgCyan)
+			if dryRun {
+				_, _ = cyan.Println("Would reclaim:")
+			} else {
+				_, _ = cyan.Println("Reclaimed:")
 			}
-			if err := os.WriteFile(stacks2, []byte(fg2.FoldedStacks), 0o644); err != nil {
-				return err
+			for _, item := range report.Items {
+				fmt.Printf("  %-20s %6d  %10s\n", item.Kind, item.Count, formatBytes(item.Bytes))
 			}
+			fmt.Printf("  %-20s %6d  %10s\n", "SVG cache (runs)", len(cachedRuns), formatBytes(cacheBytes))
+			fmt.Printf("  %-20s %6s  %10s\n", "total", "", formatBytes(report.Bytes()+cacheBytes))
+			fmt.Printf("Runs affected: %d\n", len(report.RunIDs))
 
-			diffCmd := exec.Command("inferno-diff-folded", stacks1, stacks2)
-			diffOut, err := diffCmd.Output()
-			if err != nil {
-				return fmt.Errorf("inferno-diff-folded: %w (is inferno installed?)", err)
+			if dryRun {
+				return nil
 			}
 
-			fgCmd := exec.Command("inferno-flamegraph")
-			fgCmd.Stdin = strings.NewReader(string(diffOut))
-			svg, err := fgCmd.Output()
-			if err != nil {
-				return fmt.Errorf("inferno-flamegraph: %w", err)
+			for _, runID := range cachedRuns {
+				if err := svgCache.DeleteRun(runID); err != nil {
+					return err
+				}
 			}
 
-			_, _ = os.Stdout.Write(svg)
+			before := dbFileSize(dbPath)
+			if err := database.Vacuum(); err != nil {
+				return err
+			}
+			color.Green("Vacuumed database: %s -> %s", formatBytes(before), formatBytes(dbFileSize(dbPath)))
 			return nil
 		},
 	}
 
+	cmd.Flags().IntVar(&keepProfiles, "keep-profiles", -1, "profiled runs per branch to keep profiles for (-1: keep all)")
+	cmd.Flags().DurationVar(&svgMaxAge, "svg-max-age", 0, "drop cached SVGs older than this (0: keep all)")
+	cmd.Flags().StringVar(&svgCacheDir, "svg-cache-dir", cache.DefaultSVGCacheDir(), "server SVG cache directory")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report what would be reclaimed without deleting anything")
+
 	return cmd
 }
 
+// gcCachedRuns returns the runs in the SVG cache that gc removes: those that
+// lost profiles or SVGs, and those no longer in the database.
+func gcCachedRuns(database *db.DB, svgCache *cache.SVGCache, affected []int64) ([]int64, error) {
+	cached, err := svgCache.RunIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var remove []int64
+	for _, runID := range cached {
+		if slices.Contains(affected, runID) {
+			remove = append(remove, runID)
+			continue
+		}
+		if _, err := database.GetRun(runID); errors.Is(err, sql.ErrNoRows) {
+			remove = append(remove, runID)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return remove, nil
+}
+
+// dbFileSize returns the size of a database including its write-ahead log.
+func dbFileSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
 type commitInfo struct {
 	hash    string
 	short   string
@@ -879,11 +2192,25 @@ type commitInfo struct {
 	date    string
 }
 
-func runBackfill(ctx context.Context, database *db.DB, count int, start string, dryRun bool, cfg runner.RunConfig) error {
-	zigDir := filepath.Join(cfg.RepoPath, "packages/core/src/zig")
+func runBackfill(ctx context.Context, database *db.DB, count int, start string, dryRun bool, maxFailures int, cfg runner.RunConfig) error {
+	if cfg.Harness == "" || cfg.Harness == runner.HarnessZig {
+		zigDir := filepath.Join(cfg.RepoPath, "packages/core/src/zig")
+		if _, err := os.Stat(zigDir); os.IsNotExist(err) {
+			return fmt.Errorf("zig directory not found: %s", zigDir)
+		}
+	}
+
+	// The commits are recorded by hash, so the branch comes from --start.
+	if cfg.Branch == "" {
+		branch, err := runner.RefBranch(ctx, cfg.RepoPath, start, runner.OSRunner{})
+		if err != nil {
+			return fmt.Errorf("resolve branch of %s: %w", start, err)
+		}
+		cfg.Branch = branch
+	}
 
-	if _, err := os.Stat(zigDir); os.IsNotExist(err) {
-		return fmt.Errorf("zig directory not found: %s", zigDir)
+	if _, _, err := runner.SequenceRuns(ctx, database, cfg.RepoPath, runner.OSRunner{}); err != nil {
+		return fmt.Errorf("sequence runs: %w", err)
 	}
 
 	out, err := runGitCommand(ctx, cfg.RepoPath, "log", "--reverse", fmt.Sprintf("-%d", count), start, "--format=%H|%h|%s|%cI")
@@ -914,9 +2241,18 @@ func runBackfill(ctx context.Context, database *db.DB, count int, start string,
 		if err != nil {
 			return fmt.Errorf("check commit %s: %w", c.short, err)
 		}
-		if !exists {
-			unrecorded = append(unrecorded, c)
+		if exists {
+			continue
+		}
+		broken, err := runner.IsKnownBroken(database, c.hash, maxFailures)
+		if err != nil {
+			return err
+		}
+		if broken {
+			color.Yellow("Skipping %s: failed %d or more times before", c.short, maxFailures)
+			continue
 		}
+		unrecorded = append(unrecorded, c)
 	}
 
 	if len(unrecorded) == 0 {
@@ -942,26 +2278,11 @@ func runBackfill(ctx context.Context, database *db.DB, count int, start string,
 		return nil
 	}
 
-	origHead, err := runGitCommand(ctx, cfg.RepoPath, "rev-parse", "HEAD")
-	if err != nil {
-		return fmt.Errorf("get HEAD: %w", err)
-	}
-	origHead = strings.TrimSpace(origHead)
-
-	status, _ := runGitCommand(ctx, cfg.RepoPath, "status", "--porcelain")
-	if strings.TrimSpace(status) != "" {
-		return fmt.Errorf("opentui repo has uncommitted changes, please commit or stash first")
-	}
-
 	for i, c := range unrecorded {
 		_, _ = cyan.Printf("\n[%d/%d] Recording %s: %s\n", i+1, len(unrecorded), c.short, truncate(c.message, 50))
 
-		if _, err := runGitCommand(ctx, cfg.RepoPath, "checkout", c.hash); err != nil {
-			color.Red("  Failed to checkout: %v", err)
-			continue
-		}
-
 		runCfg := cfg
+		runCfg.Commit = c.hash
 		runCfg.Notes = fmt.Sprintf("%s (commit %d/%d)", cfg.Notes, i+1, len(unrecorded))
 
 		color.White("  Running benchmarks (v2)...")
@@ -971,17 +2292,233 @@ func runBackfill(ctx context.Context, database *db.DB, count int, start string,
 		} else {
 			color.Green("  Done (Run #%d)", runID)
 		}
+	}
+
+	color.Green("\nBackfill complete")
+	return nil
+}
+
+type watchConfig struct {
+	remote      string
+	branch      string
+	interval    time.Duration
+	once        bool
+	maxDepth    int
+	maxFailures int
+	lockPath    string
+	postRun     string
+}
+
+func runWatch(ctx context.Context, database *db.DB, wcfg watchConfig, cfg runner.RunConfig) error {
+	cyan := color.New(color.FgCyan)
+	dim := color.New(color.Faint)
+	gitRunner := runner.OSRunner{}
+
+	for {
+		_, _ = dim.Printf("[%s] Fetching %s/%s\n", time.Now().Format(time.RFC3339), wcfg.remote, wcfg.branch)
+
+		pending, err := func() ([]string, error) {
+			ref, err := runner.FetchRef(ctx, cfg.RepoPath, wcfg.remote, wcfg.branch, gitRunner)
+			if err != nil {
+				return nil, fmt.Errorf("fetch: %w", err)
+			}
+			// Runs recorded without a sequence (older databases, imports
+			// without --repo) are sequenced once their commit is fetched.
+			if _, _, err := runner.SequenceRuns(ctx, database, cfg.RepoPath, gitRunner); err != nil {
+				return nil, fmt.Errorf("sequence runs: %w", err)
+			}
+			return runner.PendingCommits(ctx, database, cfg.RepoPath, ref, wcfg.maxDepth, wcfg.maxFailures, gitRunner)
+		}()
+		if err != nil {
+			if wcfg.once {
+				return err
+			}
+			color.Red("  %v", err)
+		} else if len(pending) == 0 {
+			color.Green("  Up to date")
+		}
+
+		for i, hash := range pending {
+			if ctx.Err() != nil {
+				break
+			}
+			_, _ = cyan.Printf("\n[%d/%d] Recording %s\n", i+1, len(pending), shortHash(hash))
+
+			runCfg := cfg
+			runCfg.Commit = hash
+			runCfg.Branch = wcfg.branch
+			runID, err := runner.Run(ctx, database, runCfg)
+			if err != nil {
+				color.Red("  Failed: %v", err)
+				continue
+			}
+			color.Green("  Done (Run #%d)", runID)
+
+			if wcfg.postRun != "" {
+				post := exec.CommandContext(ctx, "sh", "-c", wcfg.postRun)
+				post.Stdout = os.Stdout
+				post.Stderr = os.Stderr
+				post.Env = append(os.Environ(), fmt.Sprintf("BENCH_RUN_ID=%d", runID), "BENCH_COMMIT="+hash)
+				if err := post.Run(); err != nil {
+					color.Red("  Post-run command failed: %v", err)
+				}
+			}
+		}
+
+		if wcfg.once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Stopping watcher")
+			return nil
+		case <-time.After(wcfg.interval):
+		}
+	}
+}
+
+type workerConfig struct {
+	remote      string
+	poll        time.Duration
+	once        bool
+	maxAttempts int
+	lease       time.Duration
+}
+
+func runWorker(ctx context.Context, database *db.DB, wcfg workerConfig, cfg runner.RunConfig) error {
+	cyan := color.New(color.FgCyan)
+	now := func() string { return time.Now().Format(time.RFC3339) }
+
+	fmt.Println("Waiting for jobs...")
+	for {
+		if ctx.Err() != nil {
+			fmt.Println("Stopping worker")
+			return nil
+		}
+
+		// Jobs without a heartbeat for the lease belong to a worker that died.
+		stale := time.Now().Add(-wcfg.lease).Format(time.RFC3339)
+		if n, err := database.RequeueStaleJobs(stale, wcfg.maxAttempts, now()); err != nil {
+			return fmt.Errorf("recover stale jobs: %w", err)
+		} else if n > 0 {
+			color.Yellow("Recovered %d jobs of a worker that stopped", n)
+		}
+
+		job, err := database.ClaimJob(now())
+		if err == sql.ErrNoRows {
+			if wcfg.once {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(wcfg.poll):
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("claim job: %w", err)
+		}
+
+		_, _ = cyan.Printf("\nJob #%d: %s (attempt %d)\n", job.ID, job.Commit, job.Attempts)
+
+		heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+		heartbeatDone := make(chan struct{})
+		go func() {
+			defer close(heartbeatDone)
+			heartbeatJob(heartbeatCtx, database, job.ID, wcfg.lease/4)
+		}()
+
+		runID, err := func() (int64, error) {
+			if !runner.ValidCommitRef(job.Commit) {
+				return 0, fmt.Errorf("invalid commit ref %q", job.Commit)
+			}
+			jobCfg, err := runner.ParseJobConfig(job.Config)
+			if err != nil {
+				return 0, err
+			}
+			if wcfg.remote != "" {
+				if _, err := runGitCommand(ctx, cfg.RepoPath, "fetch", wcfg.remote); err != nil {
+					return 0, fmt.Errorf("fetch: %w", err)
+				}
+			}
+			runCfg := jobCfg.Apply(cfg)
+			runCfg.Commit = job.Commit
+			if runCfg.Notes == "" {
+				runCfg.Notes = fmt.Sprintf("job #%d", job.ID)
+			}
+			return runner.Run(ctx, database, runCfg)
+		}()
+		stopHeartbeat()
+		<-heartbeatDone
+		if err != nil && ctx.Err() != nil {
+			// An interrupted job is not the job's fault; the next worker
+			// runs it again without counting this attempt.
+			if rerr := database.ReleaseJob(job.ID); rerr != nil {
+				return fmt.Errorf("requeue job #%d: %w", job.ID, rerr)
+			}
+			color.Yellow("  Interrupted, job #%d requeued", job.ID)
+			continue
+		}
+		if err != nil {
+			retry := job.Attempts < wcfg.maxAttempts
+			color.Red("  Failed: %v", err)
+			if ferr := database.FailJob(job.ID, err.Error(), retry, now()); ferr != nil {
+				return fmt.Errorf("update job #%d: %w", job.ID, ferr)
+			}
+			continue
+		}
 
-		// Reset repo to clean state
-		_, _ = runGitCommand(ctx, cfg.RepoPath, "reset", "--hard", "HEAD")
+		if err := database.CompleteJob(job.ID, runID, now()); err != nil {
+			return fmt.Errorf("update job #%d: %w", job.ID, err)
+		}
+		color.Green("  Done (Run #%d)", runID)
 	}
+}
 
-	if _, err := runGitCommand(ctx, cfg.RepoPath, "checkout", origHead); err != nil {
-		_, _ = yellow.Printf("\nWarning: failed to restore HEAD to %s: %v\n", shortHash(origHead), err)
+// heartbeatJob renews the lease of a running job every interval until ctx is
+// done, so other workers do not take over a long but healthy job.
+func heartbeatJob(ctx context.Context, database *db.DB, id int64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := database.HeartbeatJob(id, now.Format(time.RFC3339)); err != nil {
+				color.Yellow("  Warning: failed to renew the lease of job #%d: %v", id, err)
+			}
+		}
 	}
+}
 
-	color.Green("\nBackfill complete")
-	return nil
+func addHarnessFlags(cmd *cobra.Command, cfg *runner.RunConfig) {
+	cmd.Flags().StringVar(&cfg.Harness, "harness", runner.HarnessZig, "benchmark harness (zig, command, bun)")
+	cmd.Flags().StringVar(&cfg.Command, "command", "", "shell command (command harness) or script (bun harness)")
+	cmd.Flags().StringVar(&cfg.HarnessDir, "harness-dir", "", "worktree-relative directory the harness runs in")
+}
+
+func addTargetCIFlags(cmd *cobra.Command, cfg *runner.RunConfig, targetCI *string) {
+	cmd.Flags().StringVar(targetCI, "target-ci", "", "keep sampling until the 95% CI is within this percent of the mean (e.g. 2%)")
+	cmd.Flags().IntVar(&cfg.MaxSamples, "max-samples", 30, "upper bound on samples per benchmark with --target-ci")
+	cmd.Flags().StringVar(&cfg.TargetFilter, "target-filter", "", "only require --target-ci for benchmarks whose name contains this")
+}
+
+// parsePercent parses "2%" or "2" as 2. An empty string yields 0.
+func parsePercent(value string) (float64, error) {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if value == "" {
+		return 0, nil
+	}
+	pct, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if pct < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return pct, nil
 }
 
 func runGitCommand(ctx context.Context, repoPath string, args ...string) (string, error) {
@@ -1042,6 +2579,26 @@ func printResults(results []db.Result) {
 	}
 }
 
+func printSamples(r db.Result, samples []db.Sample) {
+	cyan := color.New(color.FgCyan)
+	dim := color.New(color.Faint)
+
+	_, _ = cyan.Printf("\n%s / %s\n", r.Category, r.Name)
+	if len(samples) == 0 {
+		_, _ = dim.Println("  no raw samples recorded")
+		return
+	}
+	_, _ = dim.Printf("  %-4s %12s %12s %12s %12s\n", "#", "Min", "Avg", "Max", "Iterations")
+	for _, s := range samples {
+		fmt.Printf("  %-4d %12s %12s %12s %12d\n",
+			s.SampleIndex+1,
+			formatDuration(s.MinNs),
+			formatDuration(s.AvgNs),
+			formatDuration(s.MaxNs),
+			s.Iterations)
+	}
+}
+
 func formatDuration(ns int64) string {
 	if ns < 1000 {
 		return fmt.Sprintf("%dns", ns)
@@ -1053,6 +2610,21 @@ func formatDuration(ns int64) string {
 	return fmt.Sprintf("%.2fs", float64(ns)/1_000_000_000)
 }
 
+func formatBytes(bytes int64) string {
+	abs := bytes
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs < 1024 {
+		return fmt.Sprintf("%dB", bytes)
+	} else if abs < 1024*1024 {
+		return fmt.Sprintf("%.1fKiB", float64(bytes)/1024)
+	} else if abs < 1024*1024*1024 {
+		return fmt.Sprintf("%.1fMiB", float64(bytes)/(1024*1024))
+	}
+	return fmt.Sprintf("%.2fGiB", float64(bytes)/(1024*1024*1024))
+}
+
 func shortDate(value string) string {
 	if len(value) > 10 {
 		return value[:10]
@@ -1060,6 +2632,81 @@ func shortDate(value string) string {
 	return value
 }
 
+// printCommitInfo prints the author, parents, tags and body of a run's
+// commit, followed by its diffstat with Zig sources counted separately.
+func printCommitInfo(c *db.CommitInfo) {
+	dim := color.New(color.Faint)
+
+	fmt.Printf("Author:  %s <%s>\n", c.AuthorName, c.AuthorEmail)
+	parents := make([]string, len(c.Parents))
+	for i, p := range c.Parents {
+		parents[i] = shortHash(p)
+	}
+	fmt.Printf("Parents: %s\n", valueOr(strings.Join(parents, " "), "none"))
+	if len(c.Tags) > 0 {
+		fmt.Printf("Tags:    %s\n", strings.Join(c.Tags, ", "))
+	}
+	if c.Body != "" {
+		for line := range strings.SplitSeq(c.Body, "\n") {
+			_, _ = dim.Printf("         %s\n", line)
+		}
+	}
+	if c.DiffBase == "" {
+		return
+	}
+
+	additions, deletions, zigFiles := 0, 0, 0
+	for _, f := range c.ChangedFiles {
+		additions += f.Additions
+		deletions += f.Deletions
+		if strings.HasSuffix(f.Path, ".zig") {
+			zigFiles++
+		}
+	}
+	fmt.Printf("Changes: %d files (+%d -%d) since %s, %d Zig files\n",
+		len(c.ChangedFiles), additions, deletions, shortHash(c.DiffBase), zigFiles)
+
+	const maxFiles = 20
+	for i, f := range c.ChangedFiles {
+		if i == maxFiles {
+			_, _ = dim.Printf("         ... and %d more\n", len(c.ChangedFiles)-maxFiles)
+			break
+		}
+		stat := "binary"
+		if !f.Binary {
+			stat = fmt.Sprintf("+%d -%d", f.Additions, f.Deletions)
+		}
+		path := f.Path
+		if f.OldPath != "" {
+			path = f.OldPath + " => " + f.Path
+		}
+		_, _ = dim.Printf("         %-12s %s\n", stat, path)
+	}
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// resolveRun looks up a run by numeric ID or, failing that, by commit hash.
+func resolveRun(database *db.DB, arg string) (*db.Run, error) {
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		run, err := database.GetRun(id)
+		if err != nil {
+			return nil, fmt.Errorf("run not found: %w", err)
+		}
+		return run, nil
+	}
+	run, err := database.GetRunByCommit(arg)
+	if err != nil {
+		return nil, fmt.Errorf("run not found for commit: %w", err)
+	}
+	return run, nil
+}
+
 func shortHash(value string) string {
 	if len(value) <= 7 {
 		return value
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"opentui-bench/internal/record"
+)
+
+// initRepo creates a git repo with one commit on main.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if _, err := runGitCommand(context.Background(), dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportMetadata(t *testing.T) {
+	repo := initRepo(t)
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip("no hostname")
+	}
+
+	meta, err := importMetadata(context.Background(), repo, "", record.RunMetadata{ZigOptimize: "ReleaseFast"})
+	if err != nil {
+		t.Fatalf("importMetadata: %v", err)
+	}
+	if meta.MachineID != hostname {
+		t.Errorf("machine = %q, want the repo machine %q", meta.MachineID, hostname)
+	}
+	if meta.Branch != "main" || meta.CommitDate == "" || meta.CommitSeq != 1 {
+		t.Errorf("meta = %+v, want branch, date and seq from the repo", meta)
+	}
+
+	meta, err = importMetadata(context.Background(), repo, "", record.RunMetadata{MachineID: "ci", Branch: "release", CommitDate: "2024-01-01T00:00:00Z"})
+	if err != nil {
+		t.Fatalf("importMetadata: %v", err)
+	}
+	if meta.MachineID != "ci" || meta.Branch != "release" || meta.CommitDate != "2024-01-01T00:00:00Z" {
+		t.Errorf("meta = %+v, want the flags to override the repo", meta)
+	}
+}
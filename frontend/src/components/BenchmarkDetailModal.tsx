@@ -115,6 +115,11 @@ const BenchmarkDetailModal: Component<BenchmarkDetailModalProps> = (props) => {
                   <div class="font-mono text-[10px] md:text-[12px] truncate">
                     <span class="text-text-muted mr-2">{m.name}:</span>
                     <span>{formatBytes(m.bytes)}</span>
+                    <Show when={m.min_bytes !== undefined && m.min_bytes !== m.max_bytes}>
+                      <span class="text-text-muted ml-2">
+                        ({formatBytes(m.min_bytes)}–{formatBytes(m.max_bytes)})
+                      </span>
+                    </Show>
                   </div>
                 )}
               </For>
@@ -1,11 +1,14 @@
 import { For } from "solid-js";
 import type { Component } from "solid-js";
 import { Button } from "./Button";
+import type { RunLog } from "../services/api";
 
 interface BenchmarkFilterBarProps {
   run?: {
+    id: number;
     commit_hash: string;
     commit_message: string;
+    logs?: RunLog[];
   } | null;
   filter: string;
   setFilter: (v: string) => void;
@@ -39,6 +42,23 @@ const BenchmarkFilterBar: Component<BenchmarkFilterBarProps> = (props) => {
             {props.run.commit_message}
           </div>
         )}
+        {showRunInfo() && props.run?.logs?.length ? (
+          <div class="text-[10px] text-text-muted font-mono hidden md:flex gap-3">
+            <span class="uppercase tracking-wider">Logs</span>
+            <For each={props.run.logs}>
+              {(log) => (
+                <a
+                  href={`/api/runs/${props.run!.id}/logs/${encodeURIComponent(log.kind)}`}
+                  target="_blank"
+                  class="underline decoration-dotted hover:decoration-solid hover:text-black underline-offset-2"
+                  title={`${log.size} bytes compressed`}
+                >
+                  {log.kind}
+                </a>
+              )}
+            </For>
+          </div>
+        ) : null}
       </div>
 
       <div class="flex gap-2 w-full md:w-auto overflow-x-auto pb-1 md:pb-0 [&::-webkit-scrollbar]:hidden [-ms-overflow-style:'none'] [scrollbar-width:'none']">
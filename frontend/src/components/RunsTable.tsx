@@ -49,7 +49,13 @@ const RunsTable: Component<RunsTableProps> = (props) => {
                 </td>
                 <td class="px-4 py-3 opacity-80">{run.branch}</td>
                 <td class="px-4 py-3 opacity-80">{new Date(run.run_date).toLocaleString()}</td>
-                <td class="px-4 py-3 font-bold">{run.result_count}</td>
+                <td class="px-4 py-3 font-bold">
+                  <Show when={run.status === "failed"} fallback={run.result_count}>
+                    <span class="text-danger" title={run.failure_reason}>
+                      FAILED
+                    </span>
+                  </Show>
+                </td>
               </tr>
             )}
           </For>
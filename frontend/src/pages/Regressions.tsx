@@ -3,7 +3,7 @@ import type { Component } from "solid-js";
 import { useNavigate } from "@solidjs/router";
 import { api } from "../services/api";
 import type { Regression } from "../services/api";
-import { formatNs } from "../utils/format";
+import { formatBytes, formatNs } from "../utils/format";
 import { Check, AlertTriangle, Loader2, ArrowRight } from "lucide-solid";
 
 const GITHUB_REPO_URL = "https://github.com/anomalyco/opentui";
@@ -50,10 +50,16 @@ const CommitLink: Component<{ hash?: string; hashFull?: string }> = (props) => {
 const RegressionRow: Component<{ regression: Regression; runId?: number | null }> = (props) => {
   const navigate = useNavigate();
   const reg = () => props.regression;
+  const isMemory = () => reg().baseline_bytes !== undefined;
+  const isRunMetric = () => reg().category === "run";
 
   const handleClick = () => {
     // Navigate to the run that introduced the regression
     const targetRunId = reg().introduced_run_id ?? props.runId ?? reg().baseline_run_id;
+    if (isRunMetric()) {
+      navigate(`/benchmarks/${targetRunId}`);
+      return;
+    }
     const targetResultId = reg().introduced_result_id ?? reg().latest_result_id;
     navigate(`/benchmarks/${targetRunId}?bench_id=${targetResultId}`);
   };
@@ -68,12 +74,24 @@ const RegressionRow: Component<{ regression: Regression; runId?: number | null }
         <div class="text-[11px] text-text-muted">{reg().category}</div>
       </td>
       <td class="py-3 px-4 font-mono text-[13px] text-danger">
+        <Show when={isMemory() && !isRunMetric()}>
+          <span class="text-text-muted mr-1.5">{reg().metric}</span>
+        </Show>
         +{reg().change_percent.toFixed(1)}%
       </td>
       <td class="py-3 px-4">
         <CommitLink hash={reg().baseline_commit_hash} hashFull={reg().baseline_commit_hash_full} />
         <div class="text-[11px] text-text-muted">
-          {formatNs(reg().baseline_ci_lower_ns)} - {formatNs(reg().baseline_ci_upper_ns)}
+          <Show
+            when={isMemory()}
+            fallback={
+              <>
+                {formatNs(reg().baseline_ci_lower_ns)} - {formatNs(reg().baseline_ci_upper_ns)}
+              </>
+            }
+          >
+            {formatBytes(reg().baseline_bytes)} → {formatBytes(reg().latest_bytes)}
+          </Show>
         </div>
       </td>
       <td class="py-3 px-4">
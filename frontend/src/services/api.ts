@@ -4,9 +4,17 @@ export interface Run {
   commit_message: string;
   branch: string;
   run_date: string;
+  status: "pending" | "building" | "running" | "profiling" | "complete" | "failed";
+  failure_reason?: string;
   result_count: number;
 }
 
+export interface RunLog {
+  kind: string;
+  size: number;
+  created_at: string;
+}
+
 export interface BenchmarkResult {
   id: number;
   name: string;
@@ -19,11 +27,38 @@ export interface BenchmarkResult {
   std_dev_ns: number;
   sample_count: number;
   iterations: number;
-  mem_stats?: { name: string; bytes: number }[];
+  mem_stats?: MemStat[];
+}
+
+/** A memory stat aggregated over samples; `bytes` is the average. */
+export interface MemStat {
+  name: string;
+  bytes: number;
+  min_bytes?: number;
+  max_bytes?: number;
+  sample_count?: number;
+}
+
+/** A per-run measurement: build time in ns, or an artifact size in bytes. */
+export interface RunMetric {
+  name: string;
+  value: number;
+  unit: "ns" | "bytes";
 }
 
 export interface RunDetails extends Run {
+  logs: RunLog[];
   results: BenchmarkResult[];
+  metrics?: RunMetric[];
+}
+
+/** One run's value behind a result pooled across runs of a commit. */
+export interface PooledRun {
+  run_id: number;
+  result_id: number;
+  avg_ns: number;
+  std_dev_ns: number;
+  sample_count: number;
 }
 
 export interface TrendPoint {
@@ -42,6 +77,8 @@ export interface TrendPoint {
   regression_status?: "ok" | "regressed" | "baseline" | "insufficient";
   baseline_run_id?: number;
   change_percent?: number;
+  run_count?: number;
+  runs?: PooledRun[];
 }
 
 export interface TrendResponse {
@@ -51,22 +88,72 @@ export interface TrendResponse {
   baseline_ci_upper_ns?: number;
 }
 
+export interface MemTrendPoint {
+  run_id: number;
+  result_id: number;
+  commit_hash: string;
+  run_date: string;
+  category: string;
+  name: string;
+  bytes: number;
+  min_bytes: number;
+  max_bytes: number;
+  sample_count: number;
+}
+
+export interface MemTrendResponse {
+  stat: string;
+  points: MemTrendPoint[];
+}
+
+export interface MetricTrendPoint {
+  run_id: number;
+  commit_hash: string;
+  run_date: string;
+  value: number;
+  unit: "ns" | "bytes";
+}
+
+export interface MetricTrendResponse {
+  name: string;
+  points: MetricTrendPoint[];
+}
+
 export interface CompareResult {
+  baseline_run_ids?: number[];
+  current_run_ids?: number[];
   comparisons: {
     name: string;
     category: string;
     baseline_ns: number;
     current_ns: number;
     change_percent: number;
+    mem_stats?: {
+      name: string;
+      baseline_bytes: number;
+      current_bytes: number;
+      change_percent: number;
+    }[];
+    baseline_runs?: PooledRun[];
+    current_runs?: PooledRun[];
   }[];
 }
 
 export interface Regression {
   name: string;
   category: string;
+  /**
+   * "time", the memory stat that regressed (e.g. "peak_bytes"), or, for
+   * category "run", the run metric (e.g. "build_time", "size:libopentui.so").
+   */
+  metric: string;
+  latest_bytes?: number;
+  baseline_bytes?: number;
+  change_bytes?: number;
   latest_result_id: number;
   latest_ci_lower_ns: number;
   latest_ci_upper_ns: number;
+  latest_runs?: PooledRun[];
   baseline_run_id: number;
   baseline_commit_hash: string;
   baseline_commit_hash_full: string;
@@ -90,6 +177,11 @@ export interface RegressionsResponse {
   min_points: number;
   baseline_offset: number;
   insufficient_history?: boolean;
+  pooled_run_ids?: number[];
+  mem_threshold_bytes?: number;
+  mem_threshold_pct?: number;
+  size_threshold_pct?: number;
+  build_time_threshold_pct?: number;
   regressions: Regression[];
 }
 
@@ -108,18 +200,32 @@ export const api = {
   getRunDetails: async (id: number) => {
     return fetchJson<RunDetails>(`/api/runs/${id}`);
   },
-  getCompare: async (baseId: number, currId: number) => {
-    return fetchJson<CompareResult>(`/api/compare?id_a=${baseId}&id_b=${currId}`);
+  getCompare: async (baseId: number, currId: number, pool = false) => {
+    const poolParam = pool ? "&pool=1" : "";
+    return fetchJson<CompareResult>(`/api/compare?id_a=${baseId}&id_b=${currId}${poolParam}`);
+  },
+  getTrend: async (name: string, limit = 100, pool = false) => {
+    const poolParam = pool ? "&pool=1" : "";
+    return fetchJson<TrendResponse>(
+      `/api/trend?name=${encodeURIComponent(name)}&limit=${limit}${poolParam}`,
+    );
   },
-  getTrend: async (name: string, limit = 100) => {
-    return fetchJson<TrendResponse>(`/api/trend?name=${encodeURIComponent(name)}&limit=${limit}`);
+  getMemTrend: async (name: string, stat: string, limit = 100) => {
+    return fetchJson<MemTrendResponse>(
+      `/api/mem-trend?name=${encodeURIComponent(name)}&stat=${encodeURIComponent(stat)}&limit=${limit}`,
+    );
+  },
+  getMetricTrend: async (name: string, limit = 100) => {
+    return fetchJson<MetricTrendResponse>(
+      `/api/metric-trend?name=${encodeURIComponent(name)}&limit=${limit}`,
+    );
   },
   getFlamegraphs: async (runId: number) => {
     return fetchJson<{ result_id: number; type: string }[]>(`/api/runs/${runId}/flamegraphs`);
   },
   getRegressions: async (
     runId?: number,
-    options?: { window?: number; minPoints?: number; baselineOffset?: number },
+    options?: { window?: number; minPoints?: number; baselineOffset?: number; pool?: boolean },
   ) => {
     const params = new URLSearchParams();
     if (runId) {
@@ -134,6 +240,9 @@ export const api = {
     if (options?.baselineOffset !== undefined) {
       params.set("baseline_offset", String(options.baselineOffset));
     }
+    if (options?.pool) {
+      params.set("pool", "1");
+    }
     const query = params.toString();
     const url = query ? `/api/regressions?${query}` : "/api/regressions";
     return fetchJson<RegressionsResponse>(url);
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,6 +18,18 @@ type SVGCache struct {
 	maxRuns  int
 }
 
+// DefaultSVGCacheDir is where the server caches rendered SVGs: SVG_CACHE_DIR
+// if set, otherwise a directory under the user's cache.
+func DefaultSVGCacheDir() string {
+	if dir := os.Getenv("SVG_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".cache", "opentui-bench", "svg")
+	}
+	return "/data/svg-cache"
+}
+
 func NewSVGCache(cacheDir string, maxRuns int) (*SVGCache, error) {
 	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
 		return nil, fmt.Errorf("create cache dir: %w", err)
@@ -120,14 +133,32 @@ func (c *SVGCache) PruneOldRuns(keepRunIDs []int64) error {
 		keepSet[id] = true
 	}
 
+	runIDs, err := c.RunIDs()
+	if err != nil {
+		return err
+	}
+	for _, runID := range runIDs {
+		if !keepSet[runID] {
+			if err := os.RemoveAll(c.runDir(runID)); err != nil {
+				return fmt.Errorf("remove run-%d cache: %w", runID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunIDs returns the runs with SVGs in the cache.
+func (c *SVGCache) RunIDs() ([]int64, error) {
 	entries, err := os.ReadDir(c.cacheDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return nil, nil
 		}
-		return fmt.Errorf("read cache dir: %w", err)
+		return nil, fmt.Errorf("read cache dir: %w", err)
 	}
 
+	var runIDs []int64
 	for _, entry := range entries {
 		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "run-") {
 			continue
@@ -138,16 +169,31 @@ func (c *SVGCache) PruneOldRuns(keepRunIDs []int64) error {
 		if err != nil {
 			continue
 		}
+		runIDs = append(runIDs, runID)
+	}
+	return runIDs, nil
+}
 
-		if !keepSet[runID] {
-			runDir := filepath.Join(c.cacheDir, entry.Name())
-			if err := os.RemoveAll(runDir); err != nil {
-				return fmt.Errorf("remove run-%d cache: %w", runID, err)
+// RunSize returns the bytes cached for a run.
+func (c *SVGCache) RunSize(runID int64) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(c.runDir(runID), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
 			}
+			size += info.Size()
 		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("size run cache: %w", err)
 	}
-
-	return nil
+	return size, nil
 }
 
 func (c *SVGCache) DeleteRun(runID int64) error {
@@ -0,0 +1,328 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errNoBlobStore is returned for an artifact in a blob store that does not
+// exist, such as in a database copied without its <db>.blobs directory.
+var errNoBlobStore = errors.New("artifact is in the blob store, which is missing")
+
+// BlobStore is a content-addressed directory of artifact payloads, named by
+// their sha256, that keeps large profiles and SVGs out of the database file.
+// Identical payloads are stored once.
+//
+// Every database has one next to it (<db>.blobs). It is in use while its
+// directory exists: new artifacts are written to it, and artifacts moved into
+// it are read from it. MoveArtifactsToBlobStore creates it and
+// MoveArtifactsFromBlobStore removes it.
+type BlobStore struct {
+	dir string
+}
+
+func NewBlobStore(dir string) *BlobStore {
+	return &BlobStore{dir: dir}
+}
+
+func (s *BlobStore) Dir() string {
+	return s.dir
+}
+
+// Enabled reports whether the store directory exists.
+func (s *BlobStore) Enabled() bool {
+	info, err := os.Stat(s.dir)
+	return err == nil && info.IsDir()
+}
+
+func (s *BlobStore) path(sum string) string {
+	return filepath.Join(s.dir, sum[:2], sum[2:])
+}
+
+// Put stores data unless a blob with the same content exists and returns its
+// sha256.
+func (s *BlobStore) Put(data []byte) (string, error) {
+	hash := sha256.Sum256(data)
+	sum := hex.EncodeToString(hash[:])
+	path := s.path(sum)
+	if _, err := os.Stat(path); err == nil {
+		return sum, nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create blob dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "blob-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("create temp blob: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return "", fmt.Errorf("write temp blob: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return "", fmt.Errorf("sync temp blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close temp blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("rename blob: %w", err)
+	}
+	return sum, nil
+}
+
+// Get reads a blob and checks it against its sha256.
+func (s *BlobStore) Get(sum string) ([]byte, error) {
+	if len(sum) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob sha256 %q", sum)
+	}
+	data, err := os.ReadFile(s.path(sum))
+	if err != nil {
+		return nil, fmt.Errorf("read blob: %w", err)
+	}
+	hash := sha256.Sum256(data)
+	if hex.EncodeToString(hash[:]) != sum {
+		return nil, fmt.Errorf("blob %s is corrupt", sum)
+	}
+	return data, nil
+}
+
+// Remove deletes a blob. Missing blobs are ignored.
+func (s *BlobStore) Remove(sum string) error {
+	if err := os.Remove(s.path(sum)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove blob: %w", err)
+	}
+	return nil
+}
+
+// List returns the size of every blob in the store by sha256.
+func (s *BlobStore) List() (map[string]int64, error) {
+	blobs := make(map[string]int64)
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(d.Name(), ".tmp") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		blobs[filepath.Base(filepath.Dir(path))+d.Name()] = info.Size()
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("list blobs: %w", err)
+	}
+	return blobs, nil
+}
+
+// CheckBlobStore returns an error if artifacts are stored in a blob store
+// that does not exist, such as in a database copied without its <db>.blobs
+// directory, whose profiles could not be read.
+func (db *DB) CheckBlobStore() error {
+	if db.blobs.Enabled() {
+		return nil
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM artifacts WHERE blob_sha256 IS NOT NULL`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%d artifacts are in the blob store %s, which is missing; copy it along with the database", count, db.blobs.Dir())
+	}
+	return nil
+}
+
+// BlobStore returns the database's blob store.
+func (db *DB) BlobStore() *BlobStore {
+	return db.blobs
+}
+
+// artifactPayload returns the columns to store for a's payload: the payload
+// itself, or with the blob store in use an empty data_blob and the blob's
+// sha256 and size.
+func artifactPayload(blobs *BlobStore, a *Artifact) ([]byte, sql.NullString, sql.NullInt64, error) {
+	if !blobs.Enabled() {
+		return a.DataBlob, sql.NullString{}, sql.NullInt64{}, nil
+	}
+	sum, err := blobs.Put(a.DataBlob)
+	if err != nil {
+		return nil, sql.NullString{}, sql.NullInt64{}, err
+	}
+	return []byte{}, sql.NullString{String: sum, Valid: true}, sql.NullInt64{Int64: int64(len(a.DataBlob)), Valid: true}, nil
+}
+
+// queryer is the part of *sql.DB and *sql.Tx the blob accounting needs.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// unreferencedBlobs returns the blobs in the store, with their sizes, that no
+// artifact references once the artifacts in dropped are deleted.
+func unreferencedBlobs(q queryer, blobs *BlobStore, dropped map[int64]bool) (map[string]int64, error) {
+	stored, err := blobs.List()
+	if err != nil || len(stored) == 0 {
+		return nil, err
+	}
+
+	rows, err := q.Query(`SELECT id, blob_sha256 FROM artifacts WHERE blob_sha256 IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var id int64
+		var sum string
+		if err := rows.Scan(&id, &sum); err != nil {
+			return nil, err
+		}
+		if !dropped[id] {
+			delete(stored, sum)
+		}
+	}
+	return stored, rows.Err()
+}
+
+// MoveArtifactsToBlobStore moves every artifact payload stored in the database
+// into the blob store, creating it, and returns how many artifacts it moved
+// and their bytes. From then on new artifacts are written to the store too.
+// The database file only shrinks after a Vacuum.
+func (db *DB) MoveArtifactsToBlobStore() (int, int64, error) {
+	created := !db.blobs.Enabled()
+	if err := os.MkdirAll(db.blobs.dir, 0o755); err != nil {
+		return 0, 0, fmt.Errorf("create blob store: %w", err)
+	}
+
+	moved, bytes, err := db.moveArtifactsToBlobStore()
+	if err != nil && created {
+		_ = os.RemoveAll(db.blobs.dir)
+	}
+	return moved, bytes, err
+}
+
+func (db *DB) moveArtifactsToBlobStore() (int, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ids, err := queryIDs(tx, `SELECT id FROM artifacts WHERE blob_sha256 IS NULL ORDER BY id`)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Payloads are read one at a time, since profiles can be large.
+	var bytes int64
+	for _, id := range ids {
+		var data []byte
+		if err := tx.QueryRow(`SELECT data_blob FROM artifacts WHERE id = ?`, id).Scan(&data); err != nil {
+			return 0, 0, fmt.Errorf("read artifact %d: %w", id, err)
+		}
+		sum, err := db.blobs.Put(data)
+		if err != nil {
+			return 0, 0, fmt.Errorf("store artifact %d: %w", id, err)
+		}
+		if _, err := tx.Exec(`UPDATE artifacts SET data_blob = X'', blob_sha256 = ?, blob_size = ? WHERE id = ?`,
+			sum, len(data), id); err != nil {
+			return 0, 0, fmt.Errorf("update artifact %d: %w", id, err)
+		}
+		bytes += int64(len(data))
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return len(ids), bytes, nil
+}
+
+// MoveArtifactsFromBlobStore moves every artifact payload in the blob store
+// back into the database and removes the store, so new artifacts are stored
+// in the database again. It returns how many artifacts it moved and their
+// bytes.
+func (db *DB) MoveArtifactsFromBlobStore() (int, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ids, err := queryIDs(tx, `SELECT id FROM artifacts WHERE blob_sha256 IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var bytes int64
+	for _, id := range ids {
+		var sum string
+		if err := tx.QueryRow(`SELECT blob_sha256 FROM artifacts WHERE id = ?`, id).Scan(&sum); err != nil {
+			return 0, 0, fmt.Errorf("read artifact %d: %w", id, err)
+		}
+		data, err := db.blobs.Get(sum)
+		if err != nil {
+			return 0, 0, fmt.Errorf("load artifact %d: %w", id, err)
+		}
+		if _, err := tx.Exec(`UPDATE artifacts SET data_blob = ?, blob_sha256 = NULL, blob_size = NULL WHERE id = ?`,
+			data, id); err != nil {
+			return 0, 0, fmt.Errorf("update artifact %d: %w", id, err)
+		}
+		bytes += int64(len(data))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	// The store is removed under the write lock, after checking no artifact
+	// was written to it since.
+	err = db.InTx(func(tx *Tx) error {
+		var remaining int
+		if err := tx.tx.QueryRow(`SELECT COUNT(*) FROM artifacts WHERE blob_sha256 IS NOT NULL`).Scan(&remaining); err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return fmt.Errorf("%d artifacts were added to the blob store meanwhile; run again", remaining)
+		}
+		if err := os.RemoveAll(db.blobs.dir); err != nil {
+			return fmt.Errorf("remove blob store: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(ids), bytes, nil
+}
+
+func queryIDs(q queryer, query string, args ...any) ([]int64, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
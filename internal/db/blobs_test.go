@@ -0,0 +1,223 @@
+package db
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBlobStoreRoundTrip(t *testing.T) {
+	database, runIDs := generateDB(t, 2, 1)
+
+	var resultIDs []int64
+	for _, runID := range runIDs {
+		results, err := database.GetResultsForRun(runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultIDs = append(resultIDs, results[0].ID)
+	}
+	profile := bytes.Repeat([]byte("pprof"), 1000)
+	for _, resultID := range resultIDs {
+		if _, err := database.InsertArtifact(&Artifact{ResultID: resultID, Kind: ArtifactCPUProfile, DataBlob: profile, CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, size, err := database.MoveArtifactsToBlobStore()
+	if err != nil {
+		t.Fatalf("MoveArtifactsToBlobStore: %v", err)
+	}
+	if moved != 2 || size != int64(2*len(profile)) {
+		t.Fatalf("moved %d artifacts (%d bytes), want 2 (%d bytes)", moved, size, 2*len(profile))
+	}
+	blobs, err := database.BlobStore().List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 1 {
+		t.Fatalf("store has %d blobs, want 1 for identical profiles", len(blobs))
+	}
+
+	// With the store in use, new artifacts are written to it.
+	svg := []byte("<svg/>")
+	if _, err := database.InsertArtifact(&Artifact{ResultID: resultIDs[0], Kind: ArtifactFlamegraphSVG, DataBlob: svg, CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	var inline int
+	if err := database.QueryRow(`SELECT COALESCE(SUM(length(data_blob)), 0) FROM artifacts`).Scan(&inline); err != nil {
+		t.Fatal(err)
+	}
+	if inline != 0 {
+		t.Fatalf("%d payload bytes left in the database", inline)
+	}
+	a, err := database.GetArtifact(resultIDs[0], ArtifactCPUProfile)
+	if err != nil {
+		t.Fatalf("GetArtifact: %v", err)
+	}
+	if !bytes.Equal(a.DataBlob, profile) {
+		t.Fatalf("profile read back as %d bytes", len(a.DataBlob))
+	}
+	listed, err := database.ListArtifactsForResult(resultIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range listed {
+		if want := map[string]int64{ArtifactCPUProfile: int64(len(profile)), ArtifactFlamegraphSVG: int64(len(svg))}[l.Kind]; l.DataSize != want {
+			t.Errorf("%s: size %d, want %d", l.Kind, l.DataSize, want)
+		}
+	}
+
+	// Dropping the first run's artifacts frees only the SVG blob; the
+	// profile blob is still referenced by the second run.
+	report, err := database.CollectGarbage(GCPolicy{KeepProfiles: 1}, false)
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	last := report.Items[len(report.Items)-1]
+	if last.Kind != GCBlobs || last.Count != 1 || last.Bytes != int64(len(svg)) {
+		t.Fatalf("report = %+v, want the SVG blob freed", report.Items)
+	}
+
+	moved, _, err = database.MoveArtifactsFromBlobStore()
+	if err != nil {
+		t.Fatalf("MoveArtifactsFromBlobStore: %v", err)
+	}
+	if moved != 1 {
+		t.Fatalf("moved %d artifacts back, want 1", moved)
+	}
+	if database.BlobStore().Enabled() {
+		t.Fatal("blob store still exists")
+	}
+	a, err = database.GetArtifact(resultIDs[1], ArtifactCPUProfile)
+	if err != nil {
+		t.Fatalf("GetArtifact: %v", err)
+	}
+	if !bytes.Equal(a.DataBlob, profile) {
+		t.Fatalf("profile read back as %d bytes", len(a.DataBlob))
+	}
+}
+
+func TestCollectGarbageKeepsBlobsOnFailedCommit(t *testing.T) {
+	database, runIDs := generateDB(t, 2, 1)
+	if _, _, err := database.MoveArtifactsToBlobStore(); err != nil {
+		t.Fatal(err)
+	}
+	results, err := database.GetResultsForRun(runIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := []byte("old profile")
+	id, err := database.InsertArtifact(&Artifact{ResultID: results[0].ID, Kind: ArtifactCPUProfile, DataBlob: profile, CreatedAt: "2024-01-01T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := database.GetResultsForRun(runIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.InsertArtifact(&Artifact{ResultID: newer[0].ID, Kind: ArtifactCPUProfile, DataBlob: []byte("new profile"), CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A deferred foreign key makes the commit, not the delete, fail.
+	if _, err := database.Exec(`CREATE TABLE pins (artifact_id INTEGER REFERENCES artifacts(id) DEFERRABLE INITIALLY DEFERRED)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`INSERT INTO pins VALUES (?)`, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := database.CollectGarbage(GCPolicy{KeepProfiles: 1}, false); err == nil {
+		t.Fatal("CollectGarbage succeeded despite the failing commit")
+	}
+
+	// SQLite keeps a transaction whose commit failed open on its
+	// connection; closing the database rolls it back.
+	if err := database.Close(); err != nil {
+		t.Fatal(err)
+	}
+	database, err = OpenUnmigrated(database.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+	a, err := database.GetArtifact(results[0].ID, ArtifactCPUProfile)
+	if err != nil {
+		t.Fatalf("profile lost after a failed collection: %v", err)
+	}
+	if !bytes.Equal(a.DataBlob, profile) {
+		t.Fatalf("profile read back as %q", a.DataBlob)
+	}
+}
+
+func TestCheckBlobStore(t *testing.T) {
+	database, runIDs := generateDB(t, 1, 1)
+	if err := database.CheckBlobStore(); err != nil {
+		t.Fatalf("database without a store: %v", err)
+	}
+
+	results, err := database.GetResultsForRun(runIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.InsertArtifact(&Artifact{ResultID: results[0].ID, Kind: ArtifactCPUProfile, DataBlob: []byte("pprof"), CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := database.MoveArtifactsToBlobStore(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CheckBlobStore(); err != nil {
+		t.Fatalf("database with its store: %v", err)
+	}
+
+	// A copy of the database without the store.
+	if err := os.RemoveAll(database.BlobStore().Dir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CheckBlobStore(); err == nil {
+		t.Fatal("CheckBlobStore accepted artifacts whose blob store is missing")
+	}
+}
+
+func TestInsertArtifactWritesBlobUnderWriteLock(t *testing.T) {
+	database, runIDs := generateDB(t, 1, 1)
+	results, err := database.GetResultsForRun(runIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(database.BlobStore().Dir(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A collection holding the write lock must not see the blob appear
+	// before the row referencing it.
+	lock, err := database.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := database.InsertArtifact(&Artifact{ResultID: results[0].ID, Kind: ArtifactCPUProfile, DataBlob: []byte("pprof"), CreatedAt: "2024-01-01T00:00:00Z"})
+		done <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+	blobs, err := database.BlobStore().List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 0 {
+		t.Fatalf("blob written before the write lock was acquired: %v", blobs)
+	}
+	if err := lock.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("InsertArtifact: %v", err)
+	}
+	if _, err := database.GetArtifact(results[0].ID, ArtifactCPUProfile); err != nil {
+		t.Fatalf("GetArtifact: %v", err)
+	}
+}
@@ -3,109 +3,85 @@ package db
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-const schemaSQL = `
-CREATE TABLE IF NOT EXISTS runs (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    commit_hash TEXT NOT NULL,
-    commit_hash_full TEXT,
-    commit_message TEXT,
-    commit_date TEXT,
-    branch TEXT,
-    run_date TEXT NOT NULL,
-    machine_id TEXT,
-    notes TEXT,
-    zig_optimize TEXT DEFAULT 'ReleaseFast'
-);
-CREATE INDEX IF NOT EXISTS idx_runs_commit ON runs(commit_hash);
-CREATE INDEX IF NOT EXISTS idx_runs_date ON runs(run_date);
-CREATE INDEX IF NOT EXISTS idx_runs_branch ON runs(branch);
-
-CREATE TABLE IF NOT EXISTS results (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
-    category TEXT NOT NULL,
-    name TEXT NOT NULL,
-    min_ns INTEGER NOT NULL,
-    avg_ns INTEGER NOT NULL,
-    max_ns INTEGER NOT NULL,
-    std_dev_ns INTEGER NOT NULL DEFAULT 0,
-    p50_ns INTEGER NOT NULL DEFAULT 0,
-    p95_ns INTEGER NOT NULL DEFAULT 0,
-    p99_ns INTEGER NOT NULL DEFAULT 0,
-    total_ns INTEGER NOT NULL,
-    iterations INTEGER NOT NULL,
-    sample_count INTEGER NOT NULL DEFAULT 1
-);
-CREATE INDEX IF NOT EXISTS idx_results_run ON results(run_id);
-CREATE INDEX IF NOT EXISTS idx_results_name ON results(name);
-CREATE INDEX IF NOT EXISTS idx_results_category ON results(category);
-
-CREATE TABLE IF NOT EXISTS mem_stats (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
-    stat_name TEXT NOT NULL,
-    bytes INTEGER NOT NULL
-);
-CREATE INDEX IF NOT EXISTS idx_mem_stats_result ON mem_stats(result_id);
-
-CREATE TABLE IF NOT EXISTS flamegraphs (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
-    benchmark_name TEXT NOT NULL,
-    folded_stacks_gz BLOB NOT NULL,
-    sampling_freq INTEGER NOT NULL DEFAULT 997,
-    created_at TEXT NOT NULL
-);
-CREATE UNIQUE INDEX IF NOT EXISTS idx_flamegraphs_run_benchmark ON flamegraphs(run_id, benchmark_name);
-
-CREATE TABLE IF NOT EXISTS artifacts (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
-    kind TEXT NOT NULL,
-    data_blob BLOB NOT NULL,
-    metadata TEXT NOT NULL DEFAULT '{}',
-    created_at TEXT NOT NULL,
-    UNIQUE(result_id, kind)
-);
-CREATE INDEX IF NOT EXISTS idx_artifacts_result_kind ON artifacts(result_id, kind);
-`
+const (
+	// busyTimeout is how long a statement waits for another connection's
+	// write lock before failing with SQLITE_BUSY.
+	busyTimeout = 10 * time.Second
+	// maxOpenConns bounds the pool; SQLite serializes writers anyway, and
+	// WAL readers do not need more than a handful of connections.
+	maxOpenConns = 8
+)
 
 type DB struct {
 	*sql.DB
-	path string
+	path  string
+	blobs *BlobStore
 }
 
 func (db *DB) Path() string {
 	return db.path
 }
 
+// Open opens the database at dbPath, creating it if needed, and applies
+// pending schema migrations.
 func Open(dbPath string) (*DB, error) {
+	database, err := OpenUnmigrated(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := database.Migrate(); err != nil {
+		_ = database.Close()
+		return nil, fmt.Errorf("migrate database: %w", err)
+	}
+	return database, nil
+}
+
+// OpenUnmigrated opens the database without touching its schema, for
+// inspecting or migrating it explicitly.
+func OpenUnmigrated(dbPath string) (*DB, error) {
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("create db directory: %w", err)
 	}
 
+	// WAL lets readers (bench serve) work while a run is being recorded.
+	// Writers from other processes wait up to busyTimeout for each other,
+	// and take the write lock when their transaction begins, since a
+	// deferred transaction that later needs it fails instead of waiting.
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	params.Set("_txlock", "immediate")
+
 	dsn := dbPath
 	if strings.Contains(dbPath, "?") {
-		dsn += "&_pragma=foreign_keys(1)"
+		dsn += "&" + params.Encode()
 	} else {
-		dsn += "?_pragma=foreign_keys(1)"
+		dsn += "?" + params.Encode()
 	}
 
 	sqlDB, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetMaxIdleConns(maxOpenConns)
+	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
 	if err := sqlDB.Ping(); err != nil {
 		_ = sqlDB.Close()
 		return nil, fmt.Errorf("ping database: %w", err)
@@ -116,169 +92,154 @@ func Open(dbPath string) (*DB, error) {
 		return nil, fmt.Errorf("enable foreign keys: %w", err)
 	}
 
-	database := &DB{DB: sqlDB, path: dbPath}
-
-	if err := database.migrate(); err != nil {
-		_ = sqlDB.Close()
-		return nil, fmt.Errorf("migrate database: %w", err)
-	}
-
-	if _, err := sqlDB.Exec(schemaSQL); err != nil {
-		_ = sqlDB.Close()
-		return nil, fmt.Errorf("initialize schema: %w", err)
-	}
-
-	return database, nil
+	blobDir, _, _ := strings.Cut(dbPath, "?")
+	return &DB{DB: sqlDB, path: dbPath, blobs: NewBlobStore(blobDir + ".blobs")}, nil
 }
 
-func (db *DB) migrate() error {
-	var tableName string
-	err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name='flamegraphs'`).Scan(&tableName)
-	if err == sql.ErrNoRows {
-		return nil
-	}
-	if err != nil {
-		return err
-	}
-
-	hasOldSchema, err := db.checkOldFlamegraphSchema()
-	if err != nil {
-		return err
-	}
-	if !hasOldSchema {
-		return nil
-	}
-
-	fmt.Println("Migrating flamegraphs table to compressed format...")
-
-	oldData, err := db.readOldFlamegraphs()
-	if err != nil {
-		return err
-	}
-
-	if err := db.performFlamegraphMigration(oldData); err != nil {
-		return err
-	}
-
-	if _, err := db.Exec(`VACUUM`); err != nil {
-		fmt.Printf("Warning: VACUUM failed: %v\n", err)
-	}
-
-	fmt.Printf("Migrated %d flamegraphs to compressed format\n", len(oldData))
-	return nil
+type Run struct {
+	ID             int64
+	CommitHash     string
+	CommitHashFull string
+	CommitMessage  string
+	CommitDate     string
+	Branch         string
+	RunDate        string
+	MachineID      string
+	Notes          string
+	ZigOptimize    string
+	Status         string
+	FailureReason  string
+	// CommitSeq is the commit's position in first-parent history (the
+	// number of first-parent commits up to and including it); 0 if unknown.
+	CommitSeq int64
+	// Harness is the benchmark suite adapter the run was recorded with.
+	// ZigOptimize is empty unless it is zig. An empty Harness is stored as
+	// zig.
+	Harness string
 }
 
-func (db *DB) checkOldFlamegraphSchema() (bool, error) {
-	rows, err := db.Query(`PRAGMA table_info(flamegraphs)`)
-	if err != nil {
-		return false, err
-	}
+// Run statuses. A run moves pending -> building -> running [-> profiling] ->
+// complete, or to failed from any step, or to cancelled when it was
+// interrupted.
+const (
+	RunPending   = "pending"
+	RunBuilding  = "building"
+	RunRunning   = "running"
+	RunProfiling = "profiling"
+	RunComplete  = "complete"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+)
 
-	var hasOldSchema bool
-	for rows.Next() {
-		var cid int
-		var name, colType string
-		var notNull, pk int
-		var dfltValue sql.NullString
-		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
-			_ = rows.Close()
-			return false, err
-		}
-		if name == "folded_stacks" || name == "svg" {
-			hasOldSchema = true
-		}
-	}
-	_ = rows.Close()
-	if err := rows.Err(); err != nil {
-		return false, err
-	}
-	return hasOldSchema, nil
+const runColumns = `id, commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize, status, failure_reason, commit_seq, harness`
+
+// historyKey orders runs along commit history rather than by when they were
+// recorded: first-parent sequence, then commit date, with run_date and id
+// breaking ties between runs of the same commit. alias is the runs table
+// alias including the trailing dot, or "".
+//
+// A run without a sequence (recorded before sequences were stored, or
+// imported without a repository) borrows the highest sequence of its branch's
+// runs committed at or before it, so it lands next to them by commit date
+// rather than below all history. Runs without either sort as oldest.
+func historyKey(alias string) string {
+	seq := historySeq(alias)
+	return fmt.Sprintf("%[2]s, COALESCE(julianday(%[1]scommit_date), 0), %[1]srun_date, %[1]sid", alias, seq)
 }
 
-type oldFlamegraph struct {
-	id            int64
-	runID         int64
-	benchmarkName string
-	foldedStacks  string
-	samplingFreq  int
-	createdAt     string
+// historyOrder sorts runs newest first by historyKey.
+func historyOrder(alias string) string {
+	seq := historySeq(alias)
+	return fmt.Sprintf("%[2]s DESC, COALESCE(julianday(%[1]scommit_date), 0) DESC, %[1]srun_date DESC, %[1]sid DESC", alias, seq)
 }
 
-func (db *DB) readOldFlamegraphs() ([]oldFlamegraph, error) {
-	rows, err := db.Query(`SELECT id, run_id, benchmark_name, folded_stacks, sampling_freq, created_at FROM flamegraphs`)
-	if err != nil {
-		return nil, fmt.Errorf("read old flamegraphs: %w", err)
-	}
-
-	var oldData []oldFlamegraph
-	for rows.Next() {
-		var fg oldFlamegraph
-		if err := rows.Scan(&fg.id, &fg.runID, &fg.benchmarkName, &fg.foldedStacks, &fg.samplingFreq, &fg.createdAt); err != nil {
-			_ = rows.Close()
-			return nil, fmt.Errorf("scan old flamegraph: %w", err)
-		}
-		oldData = append(oldData, fg)
-	}
-	_ = rows.Close()
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("read old flamegraphs: %w", err)
-	}
-	return oldData, nil
+// historySeq is the sequence historyKey orders by. COALESCE only evaluates
+// the subquery for runs without a sequence.
+func historySeq(alias string) string {
+	table := alias
+	if table == "" {
+		table = "runs."
+	}
+	return fmt.Sprintf(`COALESCE(%[1]scommit_seq, (
+		SELECT MAX(seq_runs.commit_seq) FROM runs seq_runs
+		WHERE seq_runs.branch IS %[1]sbranch AND julianday(seq_runs.commit_date) <= julianday(%[1]scommit_date)
+	), 0)`, table)
 }
 
-func (db *DB) performFlamegraphMigration(oldData []oldFlamegraph) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	if _, err := tx.Exec(`DROP TABLE flamegraphs`); err != nil {
-		return fmt.Errorf("drop old table: %w", err)
-	}
-
-	createSQL := `CREATE TABLE flamegraphs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
-		benchmark_name TEXT NOT NULL,
-		folded_stacks_gz BLOB NOT NULL,
-		sampling_freq INTEGER NOT NULL DEFAULT 997,
-		created_at TEXT NOT NULL
-	)`
-	if _, err := tx.Exec(createSQL); err != nil {
-		return fmt.Errorf("create new table: %w", err)
-	}
+// historyRun matches runs that belong to commit history. Runs recorded by
+// `bench ab` are linked in run_pairs: they measure one comparison under
+// interleaved sampling and are kept out of trends, baselines and the commits
+// watch considers benchmarked. alias is as for historyKey.
+func historyRun(alias string) string {
+	table := alias
+	if table == "" {
+		table = "runs."
+	}
+	return fmt.Sprintf(`NOT EXISTS (
+		SELECT 1 FROM run_pairs ab WHERE ab.base_run_id = %[1]sid OR ab.head_run_id = %[1]sid
+	)`, table)
+}
 
-	if _, err := tx.Exec(`CREATE UNIQUE INDEX idx_flamegraphs_run_benchmark ON flamegraphs(run_id, benchmark_name)`); err != nil {
-		return fmt.Errorf("create index: %w", err)
+// scanRun scans runColumns, followed by any extra columns of the row into
+// extra.
+func scanRun(row interface{ Scan(...any) error }, extra ...any) (*Run, error) {
+	var r Run
+	var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize, failureReason sql.NullString
+	var commitSeq sql.NullInt64
+	dest := []any{&r.ID, &r.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &r.RunDate,
+		&machineID, &notes, &zigOptimize, &r.Status, &failureReason, &commitSeq, &r.Harness}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
 	}
+	r.CommitSeq = commitSeq.Int64
+	r.CommitHashFull = commitHashFull.String
+	r.CommitMessage = commitMessage.String
+	r.CommitDate = commitDate.String
+	r.Branch = branch.String
+	r.MachineID = machineID.String
+	r.Notes = notes.String
+	r.ZigOptimize = zigOptimize.String
+	r.FailureReason = failureReason.String
+	return &r, nil
+}
 
-	for _, fg := range oldData {
-		compressed, err := gzipCompress([]byte(fg.foldedStacks))
-		if err != nil {
-			return fmt.Errorf("compress flamegraph %s: %w", fg.benchmarkName, err)
-		}
-		if _, err := tx.Exec(`INSERT INTO flamegraphs (run_id, benchmark_name, folded_stacks_gz, sampling_freq, created_at) VALUES (?, ?, ?, ?, ?)`,
-			fg.runID, fg.benchmarkName, compressed, fg.samplingFreq, fg.createdAt); err != nil {
-			return fmt.Errorf("insert flamegraph %s: %w", fg.benchmarkName, err)
-		}
-	}
+// RunEnvironment describes the machine and toolchain a run was recorded on.
+type RunEnvironment struct {
+	RunID             int64
+	Fingerprint       string
+	CPUModel          string
+	CPUCores          int
+	KernelVersion     string
+	CPUGovernor       string
+	ZigVersion        string
+	GoVersion         string
+	BenchVersion      string
+	PerfEventParanoid *int64
+	ProfilingAllowed  bool
+}
 
-	return tx.Commit()
+// CommitInfo is git metadata of a run's commit beyond the columns of runs.
+type CommitInfo struct {
+	RunID       int64
+	AuthorName  string
+	AuthorEmail string
+	Body        string
+	Parents     []string
+	Tags        []string
+	// DiffBase is the commit ChangedFiles is relative to: the closest
+	// previously recorded ancestor, or the first parent if none is recorded.
+	DiffBase     string
+	ChangedFiles []ChangedFile
 }
 
-type Run struct {
-	ID             int64
-	CommitHash     string
-	CommitHashFull string
-	CommitMessage  string
-	CommitDate     string
-	Branch         string
-	RunDate        string
-	MachineID      string
-	Notes          string
-	ZigOptimize    string
+// ChangedFile is one line of a diffstat. Binary files have no line counts.
+// OldPath is set for renamed files.
+type ChangedFile struct {
+	Path      string
+	OldPath   string
+	Additions int
+	Deletions int
+	Binary    bool
 }
 
 type Result struct {
@@ -299,11 +260,29 @@ type Result struct {
 	MemStats    []MemStat
 }
 
+// MemStat is one memory statistic of a result, aggregated over its samples:
+// Bytes is the average, MinBytes and MaxBytes the extremes. Stats recorded
+// before aggregation have all three set to the first sample's value.
 type MemStat struct {
-	ID       int64
-	ResultID int64
-	StatName string
-	Bytes    int64
+	ID          int64
+	ResultID    int64
+	StatName    string
+	Bytes       int64
+	MinBytes    int64
+	MaxBytes    int64
+	SampleCount int64
+}
+
+// Sample is a single benchmark invocation that was folded into a Result.
+type Sample struct {
+	ID          int64
+	ResultID    int64
+	SampleIndex int
+	MinNs       int64
+	AvgNs       int64
+	MaxNs       int64
+	TotalNs     int64
+	Iterations  int64
 }
 
 type Flamegraph struct {
@@ -337,18 +316,365 @@ func gzipDecompress(data []byte) ([]byte, error) {
 	return io.ReadAll(r)
 }
 
+// InsertRun inserts a run. An empty Status is stored as complete.
 func (db *DB) InsertRun(run *Run) (int64, error) {
-	res, err := db.Exec(`
-		INSERT INTO runs (commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	return insertRun(db.DB, run)
+}
+
+func insertRun(e execer, run *Run) (int64, error) {
+	status := run.Status
+	if status == "" {
+		status = RunComplete
+	}
+	harness := run.Harness
+	if harness == "" {
+		harness = "zig"
+	}
+	res, err := e.Exec(`
+		INSERT INTO runs (commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize, status, commit_seq, harness)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, 0), ?)`,
 		run.CommitHash, run.CommitHashFull, run.CommitMessage, run.CommitDate,
-		run.Branch, run.RunDate, run.MachineID, run.Notes, run.ZigOptimize)
+		run.Branch, run.RunDate, run.MachineID, run.Notes, run.ZigOptimize, status, run.CommitSeq, harness)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (db *DB) InsertRunEnvironment(env *RunEnvironment) error {
+	return insertRunEnvironment(db.DB, env)
+}
+
+func insertRunEnvironment(e execer, env *RunEnvironment) error {
+	_, err := e.Exec(`
+		INSERT INTO run_environment (run_id, fingerprint, cpu_model, cpu_cores, kernel_version, cpu_governor, zig_version, go_version, bench_version, perf_event_paranoid, profiling_allowed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		env.RunID, env.Fingerprint, env.CPUModel, env.CPUCores, env.KernelVersion, env.CPUGovernor,
+		env.ZigVersion, env.GoVersion, env.BenchVersion, env.PerfEventParanoid, env.ProfilingAllowed)
+	return err
+}
+
+func (db *DB) InsertCommitInfo(info *CommitInfo) error {
+	return db.InTx(func(tx *Tx) error {
+		return tx.InsertCommitInfo(info)
+	})
+}
+
+func insertCommitInfo(e execer, info *CommitInfo) error {
+	_, err := e.Exec(`
+		INSERT INTO run_commit (run_id, author_name, author_email, body, parents, tags, diff_base)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		info.RunID, info.AuthorName, info.AuthorEmail, info.Body,
+		strings.Join(info.Parents, " "), strings.Join(info.Tags, " "), info.DiffBase)
+	if err != nil {
+		return err
+	}
+	for _, f := range info.ChangedFiles {
+		var additions, deletions sql.NullInt64
+		if !f.Binary {
+			additions = sql.NullInt64{Int64: int64(f.Additions), Valid: true}
+			deletions = sql.NullInt64{Int64: int64(f.Deletions), Valid: true}
+		}
+		_, err = e.Exec(`
+			INSERT INTO run_changed_files (run_id, path, additions, deletions, old_path)
+			VALUES (?, ?, ?, ?, NULLIF(?, ''))`, info.RunID, f.Path, additions, deletions, f.OldPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCommitInfo returns the commit metadata recorded for a run.
+// Runs recorded before it was captured return sql.ErrNoRows.
+func (db *DB) GetCommitInfo(runID int64) (*CommitInfo, error) {
+	info := CommitInfo{RunID: runID}
+	var authorName, authorEmail, body, parents, tags, diffBase sql.NullString
+	err := db.QueryRow(`
+		SELECT author_name, author_email, body, parents, tags, diff_base
+		FROM run_commit WHERE run_id = ?`, runID).Scan(
+		&authorName, &authorEmail, &body, &parents, &tags, &diffBase)
+	if err != nil {
+		return nil, err
+	}
+	info.AuthorName = authorName.String
+	info.AuthorEmail = authorEmail.String
+	info.Body = body.String
+	info.Parents = strings.Fields(parents.String)
+	info.Tags = strings.Fields(tags.String)
+	info.DiffBase = diffBase.String
+
+	rows, err := db.Query(`
+		SELECT path, additions, deletions, old_path
+		FROM run_changed_files WHERE run_id = ? ORDER BY path`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var f ChangedFile
+		var additions, deletions sql.NullInt64
+		var oldPath sql.NullString
+		if err := rows.Scan(&f.Path, &additions, &deletions, &oldPath); err != nil {
+			return nil, err
+		}
+		f.OldPath = oldPath.String
+		f.Binary = !additions.Valid
+		f.Additions = int(additions.Int64)
+		f.Deletions = int(deletions.Int64)
+		info.ChangedFiles = append(info.ChangedFiles, f)
+	}
+	return &info, rows.Err()
+}
+
+// GetRunEnvironment returns the environment recorded for a run.
+// Runs recorded before environments were captured return sql.ErrNoRows.
+func (db *DB) GetRunEnvironment(runID int64) (*RunEnvironment, error) {
+	var env RunEnvironment
+	var cpuModel, kernelVersion, cpuGovernor, zigVersion, goVersion, benchVersion sql.NullString
+	var cpuCores, perfEventParanoid sql.NullInt64
+	err := db.QueryRow(`
+		SELECT run_id, fingerprint, cpu_model, cpu_cores, kernel_version, cpu_governor, zig_version, go_version, bench_version, perf_event_paranoid, profiling_allowed
+		FROM run_environment WHERE run_id = ?`, runID).Scan(
+		&env.RunID, &env.Fingerprint, &cpuModel, &cpuCores, &kernelVersion, &cpuGovernor,
+		&zigVersion, &goVersion, &benchVersion, &perfEventParanoid, &env.ProfilingAllowed)
+	if err != nil {
+		return nil, err
+	}
+	env.CPUModel = cpuModel.String
+	env.CPUCores = int(cpuCores.Int64)
+	env.KernelVersion = kernelVersion.String
+	env.CPUGovernor = cpuGovernor.String
+	env.ZigVersion = zigVersion.String
+	env.GoVersion = goVersion.String
+	env.BenchVersion = benchVersion.String
+	if perfEventParanoid.Valid {
+		env.PerfEventParanoid = &perfEventParanoid.Int64
+	}
+	return &env, nil
+}
+
+// RunPair links the two runs of an interleaved A/B benchmark.
+type RunPair struct {
+	ID        int64
+	BaseRunID int64
+	HeadRunID int64
+	Rounds    int
+	CreatedAt string
+}
+
+func (db *DB) InsertRunPair(p *RunPair) (int64, error) {
+	return insertRunPair(db, p)
+}
+
+func insertRunPair(e execer, p *RunPair) (int64, error) {
+	res, err := e.Exec(`
+		INSERT INTO run_pairs (base_run_id, head_run_id, rounds, created_at)
+		VALUES (?, ?, ?, ?)`,
+		p.BaseRunID, p.HeadRunID, p.Rounds, p.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (db *DB) GetRunPair(id int64) (*RunPair, error) {
+	var p RunPair
+	err := db.QueryRow(`
+		SELECT id, base_run_id, head_run_id, rounds, created_at
+		FROM run_pairs WHERE id = ?`, id).Scan(
+		&p.ID, &p.BaseRunID, &p.HeadRunID, &p.Rounds, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Job statuses.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Job is a queued request to benchmark a commit. Config holds the JSON
+// encoded run options; it is interpreted by the worker, not the database.
+type Job struct {
+	ID         int64
+	Commit     string
+	Config     string
+	Priority   int
+	Status     string
+	Attempts   int
+	RunID      int64 // 0 until the job produced a run
+	Error      string
+	CreatedAt  string
+	StartedAt  string
+	FinishedAt string
+}
+
+const jobColumns = `id, commit_ref, config, priority, status, attempts, run_id, error, created_at, started_at, finished_at`
+
+func scanJob(row interface{ Scan(...any) error }) (*Job, error) {
+	var j Job
+	var runID sql.NullInt64
+	var errMsg, startedAt, finishedAt sql.NullString
+	if err := row.Scan(&j.ID, &j.Commit, &j.Config, &j.Priority, &j.Status, &j.Attempts,
+		&runID, &errMsg, &j.CreatedAt, &startedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	j.RunID = runID.Int64
+	j.Error = errMsg.String
+	j.StartedAt = startedAt.String
+	j.FinishedAt = finishedAt.String
+	return &j, nil
+}
+
+func (db *DB) InsertJob(j *Job) (int64, error) {
+	res, err := db.Exec(`
+		INSERT INTO jobs (commit_ref, config, priority, status, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		j.Commit, j.Config, j.Priority, JobQueued, j.CreatedAt)
 	if err != nil {
 		return 0, err
 	}
 	return res.LastInsertId()
 }
 
+func (db *DB) GetJob(id int64) (*Job, error) {
+	return scanJob(db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+}
+
+// ListJobs returns jobs newest first, optionally filtered by status.
+func (db *DB) ListJobs(limit int, status string) ([]Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE 1=1`
+	var args []interface{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var jobs []Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimJob atomically marks the highest priority queued job as running and
+// returns it, so several workers can share one queue. It returns
+// sql.ErrNoRows when the queue is empty.
+func (db *DB) ClaimJob(now string) (*Job, error) {
+	return scanJob(db.QueryRow(`
+		UPDATE jobs SET status = ?, attempts = attempts + 1, started_at = ?, heartbeat_at = ?, error = NULL
+		WHERE id = (
+			SELECT id FROM jobs WHERE status = ?
+			ORDER BY priority DESC, id LIMIT 1
+		)
+		RETURNING `+jobColumns, JobRunning, now, now, JobQueued))
+}
+
+func (db *DB) CompleteJob(id, runID int64, now string) error {
+	_, err := db.Exec(`UPDATE jobs SET status = ?, run_id = ?, finished_at = ? WHERE id = ?`,
+		JobDone, runID, now, id)
+	return err
+}
+
+// ReleaseJob puts a job whose worker was interrupted back in the queue
+// without counting the attempt.
+func (db *DB) ReleaseJob(id int64) error {
+	_, err := db.Exec(`UPDATE jobs SET status = ?, attempts = MAX(attempts - 1, 0), started_at = NULL, heartbeat_at = NULL WHERE id = ? AND status = ?`,
+		JobQueued, id, JobRunning)
+	return err
+}
+
+// HeartbeatJob records that the worker running a job is still alive.
+func (db *DB) HeartbeatJob(id int64, now string) error {
+	_, err := db.Exec(`UPDATE jobs SET heartbeat_at = ? WHERE id = ? AND status = ?`, now, id, JobRunning)
+	return err
+}
+
+// RequeueStaleJobs recovers jobs claimed by workers that died: running jobs
+// whose last heartbeat (or start, without one) is before seenBefore go back
+// to the queue, or are marked failed once they used maxAttempts attempts, so
+// a job that kills its worker is not retried forever. It returns how many
+// jobs it recovered.
+func (db *DB) RequeueStaleJobs(seenBefore string, maxAttempts int, now string) (int64, error) {
+	res, err := db.Exec(`
+		UPDATE jobs
+		SET status = CASE WHEN attempts >= ? THEN ? ELSE ? END,
+		    error = 'worker stopped before finishing the job',
+		    finished_at = ?
+		WHERE status = ? AND julianday(COALESCE(heartbeat_at, started_at)) < julianday(?)`,
+		maxAttempts, JobFailed, JobQueued, now, JobRunning, seenBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FailJob records a failed attempt. With retry the job goes back to the
+// queue, otherwise it is marked failed for good.
+func (db *DB) FailJob(id int64, errMsg string, retry bool, now string) error {
+	status := JobFailed
+	if retry {
+		status = JobQueued
+	}
+	_, err := db.Exec(`UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ?`,
+		status, errMsg, now, id)
+	return err
+}
+
+// MergeJobs copies the jobs of the database file at path that this database
+// does not have, such as jobs submitted to a server's copy after it was
+// downloaded, and returns how many it copied. Jobs are matched by id.
+func (db *DB) MergeJobs(path string) (int64, error) {
+	ctx := context.Background()
+	// ATTACH applies to one connection, so the statements share one.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS other`, path); err != nil {
+		return 0, fmt.Errorf("attach %s: %w", path, err)
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, `DETACH DATABASE other`) }()
+
+	res, err := conn.ExecContext(ctx, `
+		INSERT INTO main.jobs (`+jobColumns+`)
+		SELECT `+jobColumns+` FROM other.jobs
+		WHERE id NOT IN (SELECT id FROM main.jobs)
+		ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("copy jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 func (db *DB) InsertResult(result *Result) (int64, error) {
 	res, err := db.Exec(`
 		INSERT INTO results (run_id, category, name, min_ns, avg_ns, max_ns, std_dev_ns, p50_ns, p95_ns, p99_ns, total_ns, iterations, sample_count)
@@ -363,16 +689,83 @@ func (db *DB) InsertResult(result *Result) (int64, error) {
 	return res.LastInsertId()
 }
 
+// InsertResultWithSamples inserts a result and its raw samples in one transaction.
+func (db *DB) InsertResultWithSamples(result *Result, samples []Sample) (int64, error) {
+	var resultID int64
+	err := db.InTx(func(tx *Tx) error {
+		var err error
+		resultID, err = tx.InsertResultWithSamples(result, samples)
+		return err
+	})
+	return resultID, err
+}
+
+func insertResultWithSamples(e execer, result *Result, samples []Sample) (int64, error) {
+	res, err := e.Exec(`
+		INSERT INTO results (run_id, category, name, min_ns, avg_ns, max_ns, std_dev_ns, p50_ns, p95_ns, p99_ns, total_ns, iterations, sample_count)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		result.RunID, result.Category, result.Name,
+		result.MinNs, result.AvgNs, result.MaxNs, result.StdDevNs,
+		result.P50Ns, result.P95Ns, result.P99Ns,
+		result.TotalNs, result.Iterations, result.SampleCount)
+	if err != nil {
+		return 0, err
+	}
+	resultID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, s := range samples {
+		if _, err := e.Exec(`
+			INSERT INTO samples (result_id, sample_index, min_ns, avg_ns, max_ns, total_ns, iterations)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			resultID, s.SampleIndex, s.MinNs, s.AvgNs, s.MaxNs, s.TotalNs, s.Iterations); err != nil {
+			return 0, fmt.Errorf("insert sample %d: %w", s.SampleIndex, err)
+		}
+	}
+	return resultID, nil
+}
+
+// GetSamplesForResult returns the raw samples of a result in recording order.
+func (db *DB) GetSamplesForResult(resultID int64) ([]Sample, error) {
+	rows, err := db.Query(`
+		SELECT id, result_id, sample_index, min_ns, avg_ns, max_ns, total_ns, iterations
+		FROM samples WHERE result_id = ? ORDER BY sample_index`, resultID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var samples []Sample
+	for rows.Next() {
+		var s Sample
+		if err := rows.Scan(&s.ID, &s.ResultID, &s.SampleIndex, &s.MinNs, &s.AvgNs, &s.MaxNs, &s.TotalNs, &s.Iterations); err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
 func (db *DB) InsertMemStat(stat *MemStat) error {
-	_, err := db.Exec(`
-		INSERT INTO mem_stats (result_id, stat_name, bytes)
-		VALUES (?, ?, ?)`,
-		stat.ResultID, stat.StatName, stat.Bytes)
+	return insertMemStat(db.DB, stat)
+}
+
+func insertMemStat(e execer, stat *MemStat) error {
+	_, err := e.Exec(`
+		INSERT INTO mem_stats (result_id, stat_name, bytes, min_bytes, max_bytes, sample_count)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		stat.ResultID, stat.StatName, stat.Bytes, stat.MinBytes, stat.MaxBytes, max(stat.SampleCount, 1))
 	return err
 }
 
 func (db *DB) ListRuns(limit int, branch string, since string) ([]Run, error) {
-	query := `SELECT id, commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize FROM runs WHERE 1=1`
+	query := `SELECT ` + runColumns + ` FROM runs WHERE 1=1`
 	args := []interface{}{}
 
 	if branch != "" {
@@ -384,7 +777,7 @@ func (db *DB) ListRuns(limit int, branch string, since string) ([]Run, error) {
 		args = append(args, since)
 	}
 
-	query += " ORDER BY run_date DESC"
+	query += " ORDER BY " + historyOrder("")
 	if limit > 0 {
 		query += " LIMIT ?"
 		args = append(args, limit)
@@ -402,98 +795,157 @@ func (db *DB) ListRuns(limit int, branch string, since string) ([]Run, error) {
 
 	var runs []Run
 	for rows.Next() {
-		var r Run
-		var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
-		if err := rows.Scan(&r.ID, &r.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &r.RunDate, &machineID, &notes, &zigOptimize); err != nil {
+		r, err := scanRun(rows)
+		if err != nil {
 			return nil, err
 		}
-		r.CommitHashFull = commitHashFull.String
-		r.CommitMessage = commitMessage.String
-		r.CommitDate = commitDate.String
-		r.Branch = branch.String
-		r.MachineID = machineID.String
-		r.Notes = notes.String
-		r.ZigOptimize = zigOptimize.String
-		runs = append(runs, r)
+		runs = append(runs, *r)
 	}
 	return runs, rows.Err()
 }
 
 func (db *DB) GetRun(id int64) (*Run, error) {
-	var r Run
-	var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
-	err := db.QueryRow(`
-		SELECT id, commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize
-		FROM runs WHERE id = ?`, id).Scan(
-		&r.ID, &r.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &r.RunDate, &machineID, &notes, &zigOptimize)
-	if err != nil {
-		return nil, err
-	}
-	r.CommitHashFull = commitHashFull.String
-	r.CommitMessage = commitMessage.String
-	r.CommitDate = commitDate.String
-	r.Branch = branch.String
-	r.MachineID = machineID.String
-	r.Notes = notes.String
-	r.ZigOptimize = zigOptimize.String
-	return &r, nil
+	return scanRun(db.QueryRow(`
+		SELECT `+runColumns+`
+		FROM runs WHERE id = ?`, id))
 }
 
-func (db *DB) GetRunByCommit(commitHash string) (*Run, error) {
-	var r Run
-	var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
-	err := db.QueryRow(`
-		SELECT id, commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize
-		FROM runs WHERE commit_hash = ? OR commit_hash_full = ? ORDER BY run_date DESC LIMIT 1`, commitHash, commitHash).Scan(
-		&r.ID, &r.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &r.RunDate, &machineID, &notes, &zigOptimize)
+// PoolKey identifies runs whose results may be pooled: complete runs of the
+// same commit on the same machine with the same harness and build mode.
+func (r Run) PoolKey() string {
+	return r.CommitHashFull + "\x00" + r.MachineID + "\x00" + r.Harness + "\x00" + r.ZigOptimize
+}
+
+// GetPoolableRuns returns the complete runs sharing run's PoolKey, newest
+// first, including run itself when it is complete.
+func (db *DB) GetPoolableRuns(run *Run) ([]Run, error) {
+	rows, err := db.Query(`
+		SELECT `+runColumns+`
+		FROM runs
+		WHERE status = 'complete' AND commit_hash_full = ?
+		  AND COALESCE(machine_id, '') = ? AND harness = ? AND COALESCE(zig_optimize, '') = ?
+		ORDER BY `+historyOrder(""), run.CommitHashFull, run.MachineID, run.Harness, run.ZigOptimize)
 	if err != nil {
 		return nil, err
 	}
-	r.CommitHashFull = commitHashFull.String
-	r.CommitMessage = commitMessage.String
-	r.CommitDate = commitDate.String
-	r.Branch = branch.String
-	r.MachineID = machineID.String
-	r.Notes = notes.String
-	r.ZigOptimize = zigOptimize.String
-	return &r, nil
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var runs []Run
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *r)
+	}
+	return runs, rows.Err()
 }
 
-func (db *DB) GetLatestRun() (*Run, error) {
-	var r Run
-	var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
-	err := db.QueryRow(`
-		SELECT id, commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize
-		FROM runs ORDER BY run_date DESC LIMIT 1`).Scan(
-		&r.ID, &r.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &r.RunDate, &machineID, &notes, &zigOptimize)
+// GetRunByCommit returns the latest complete run of a commit, falling back to
+// the latest run of any status.
+func (db *DB) GetRunByCommit(commitHash string) (*Run, error) {
+	return scanRun(db.QueryRow(`
+		SELECT `+runColumns+`
+		FROM runs WHERE commit_hash = ? OR commit_hash_full = ?
+		ORDER BY status = 'complete' DESC, run_date DESC LIMIT 1`, commitHash, commitHash))
+}
+
+// GetLatestRun returns the newest complete history run of branch in history
+// order.
+// Sequences of different branches are not comparable, so runs of other
+// branches are only considered when branch has none; an empty branch
+// considers all runs.
+func (db *DB) GetLatestRun(branch string) (*Run, error) {
+	return scanRun(db.QueryRow(`
+		SELECT `+runColumns+`
+		FROM runs WHERE status = 'complete' AND `+historyRun("")+`
+		ORDER BY (? = '' OR branch = ?) DESC, `+historyOrder("")+` LIMIT 1`, branch, branch))
+}
+
+// SetCommitSeq stores the first-parent sequence of every run of a commit.
+func (db *DB) SetCommitSeq(commitHashFull string, seq int64) error {
+	_, err := db.Exec(`UPDATE runs SET commit_seq = ? WHERE commit_hash_full = ?`, seq, commitHashFull)
+	return err
+}
+
+// ListUnsequencedCommits returns the distinct commits of runs recorded
+// without a first-parent sequence.
+func (db *DB) ListUnsequencedCommits() ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT commit_hash_full FROM runs
+		WHERE commit_seq IS NULL AND commit_hash_full IS NOT NULL AND commit_hash_full != ''`)
 	if err != nil {
 		return nil, err
 	}
-	r.CommitHashFull = commitHashFull.String
-	r.CommitMessage = commitMessage.String
-	r.CommitDate = commitDate.String
-	r.Branch = branch.String
-	r.MachineID = machineID.String
-	r.Notes = notes.String
-	r.ZigOptimize = zigOptimize.String
-	return &r, nil
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var commits []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		commits = append(commits, hash)
+	}
+	return commits, rows.Err()
 }
 
+// SetRunStatus moves a run to status. reason is stored as the failure reason
+// and should be empty unless status is RunFailed or RunCancelled.
+func (db *DB) SetRunStatus(id int64, status, reason string) error {
+	return setRunStatus(db.DB, id, status, reason)
+}
+
+func setRunStatus(e execer, id int64, status, reason string) error {
+	_, err := e.Exec(`UPDATE runs SET status = ?, failure_reason = NULLIF(?, '') WHERE id = ?`, status, reason, id)
+	return err
+}
+
+// HasCommit reports whether the commit has a completed history run.
 func (db *DB) HasCommit(commitHashFull string) (bool, error) {
 	var count int
-	err := db.QueryRow(`SELECT COUNT(*) FROM runs WHERE commit_hash_full = ?`, commitHashFull).Scan(&count)
+	err := db.QueryRow(`SELECT COUNT(*) FROM runs WHERE commit_hash_full = ? AND status = 'complete' AND `+historyRun("")+``, commitHashFull).Scan(&count)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
+// CountFailedRuns returns how many runs of the commit failed.
+func (db *DB) CountFailedRuns(commitHashFull string) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM runs WHERE commit_hash_full = ? AND status = 'failed'`, commitHashFull).Scan(&count)
+	return count, err
+}
+
+// GetResultsForRun returns the results of a run with their memory stats,
+// ordered by category and name.
 func (db *DB) GetResultsForRun(runID int64) ([]Result, error) {
-	rows, err := db.Query(`
-		SELECT id, run_id, category, name, min_ns, avg_ns, max_ns, 
+	return db.GetResultsInRuns([]int64{runID})
+}
+
+// GetResultsInRuns returns the results of several runs with their memory
+// stats, ordered by run ID, category and name. It issues two queries however
+// many runs and results there are.
+func (db *DB) GetResultsInRuns(runIDs []int64) (results []Result, err error) {
+	if len(runIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inPlaceholders(runIDs)
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT id, run_id, category, name, min_ns, avg_ns, max_ns,
 		       COALESCE(std_dev_ns, 0), COALESCE(p50_ns, 0), COALESCE(p95_ns, 0), COALESCE(p99_ns, 0),
 		       total_ns, iterations, COALESCE(sample_count, 1)
-		FROM results WHERE run_id = ? ORDER BY category, name`, runID)
+		FROM results WHERE run_id IN (%s) ORDER BY run_id, category, name`, placeholders), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -503,7 +955,6 @@ func (db *DB) GetResultsForRun(runID int64) ([]Result, error) {
 		}
 	}()
 
-	var results []Result
 	for rows.Next() {
 		var r Result
 		if err := rows.Scan(&r.ID, &r.RunID, &r.Category, &r.Name, &r.MinNs, &r.AvgNs, &r.MaxNs,
@@ -517,12 +968,12 @@ func (db *DB) GetResultsForRun(runID int64) ([]Result, error) {
 		return nil, err
 	}
 
+	memStats, err := db.getMemStatsInRuns(placeholders, args)
+	if err != nil {
+		return nil, err
+	}
 	for i := range results {
-		memStats, err := db.GetMemStatsForResult(results[i].ID)
-		if err != nil {
-			return nil, err
-		}
-		results[i].MemStats = memStats
+		results[i].MemStats = memStats[results[i].ID]
 	}
 
 	return results, nil
@@ -611,10 +1062,16 @@ func (db *DB) ListFlamegraphResults(runID int64) ([]ProfiledResult, error) {
 	return results, nil
 }
 
-func (db *DB) GetMemStatsForResult(resultID int64) ([]MemStat, error) {
-	rows, err := db.Query(`
-		SELECT id, result_id, stat_name, bytes
-		FROM mem_stats WHERE result_id = ?`, resultID)
+// getMemStatsInRuns returns the memory stats of all results of the runs
+// matched by placeholders, keyed by result ID.
+func (db *DB) getMemStatsInRuns(placeholders string, args []interface{}) (stats map[int64][]MemStat, err error) {
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT m.id, m.result_id, m.stat_name, m.bytes,
+			COALESCE(m.min_bytes, m.bytes), COALESCE(m.max_bytes, m.bytes), m.sample_count
+		FROM mem_stats m
+		JOIN results r ON r.id = m.result_id
+		WHERE r.run_id IN (%s)
+		ORDER BY m.id`, placeholders), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -624,38 +1081,79 @@ func (db *DB) GetMemStatsForResult(resultID int64) ([]MemStat, error) {
 		}
 	}()
 
-	var stats []MemStat
+	stats = make(map[int64][]MemStat)
 	for rows.Next() {
 		var s MemStat
-		if err := rows.Scan(&s.ID, &s.ResultID, &s.StatName, &s.Bytes); err != nil {
+		if err := rows.Scan(&s.ID, &s.ResultID, &s.StatName, &s.Bytes, &s.MinBytes, &s.MaxBytes, &s.SampleCount); err != nil {
 			return nil, err
 		}
-		stats = append(stats, s)
+		stats[s.ResultID] = append(stats[s.ResultID], s)
 	}
 	return stats, rows.Err()
 }
 
-func (db *DB) CountResultsForRun(runID int64) (int, error) {
-	var count int
-	err := db.QueryRow(`SELECT COUNT(*) FROM results WHERE run_id = ?`, runID).Scan(&count)
-	return count, err
+// CountResultsForRuns returns the number of results of each run, in one
+// query. Runs without results are missing from the map.
+func (db *DB) CountResultsForRuns(runIDs []int64) (counts map[int64]int, err error) {
+	counts = make(map[int64]int, len(runIDs))
+	if len(runIDs) == 0 {
+		return counts, nil
+	}
+	placeholders, args := inPlaceholders(runIDs)
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT run_id, COUNT(*) FROM results
+		WHERE run_id IN (%s)
+		GROUP BY run_id`, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var runID int64
+		var count int
+		if err := rows.Scan(&runID, &count); err != nil {
+			return nil, err
+		}
+		counts[runID] = count
+	}
+	return counts, rows.Err()
+}
+
+// inPlaceholders returns a "?,?,..." list for an IN clause over ids, with
+// the matching arguments.
+func inPlaceholders(ids []int64) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ","), args
 }
 
-func (db *DB) GetTrend(namePattern string, limit int) ([]struct {
+// TrendPoint is one result of a benchmark together with the run it belongs to.
+type TrendPoint struct {
 	Run    Run
 	Result Result
-}, error,
-) {
+}
+
+func (db *DB) GetTrend(namePattern string, limit int) ([]TrendPoint, error) {
 	query := `
 		SELECT 
-			ru.id, ru.commit_hash, ru.commit_hash_full, ru.commit_message, ru.commit_date, ru.branch, ru.run_date, ru.machine_id, ru.notes, ru.zig_optimize,
+			ru.id, ru.commit_hash, ru.commit_hash_full, ru.commit_message, ru.commit_date, ru.branch, ru.run_date, ru.machine_id, ru.notes, ru.zig_optimize, ru.harness,
 			r.id, r.run_id, r.category, r.name, r.min_ns, r.avg_ns, r.max_ns, 
 			COALESCE(r.std_dev_ns, 0), COALESCE(r.p50_ns, 0), COALESCE(r.p95_ns, 0), COALESCE(r.p99_ns, 0),
 			r.total_ns, r.iterations, COALESCE(r.sample_count, 1)
 		FROM results r
 		JOIN runs ru ON r.run_id = ru.id
-		WHERE r.name LIKE ?
-		ORDER BY ru.run_date DESC`
+		WHERE r.name LIKE ? AND ` + historyRun("ru.") + `
+		ORDER BY ` + historyOrder("ru.")
 
 	args := []interface{}{"%" + namePattern + "%"}
 	if limit > 0 {
@@ -673,10 +1171,7 @@ func (db *DB) GetTrend(namePattern string, limit int) ([]struct {
 		}
 	}()
 
-	var results []struct {
-		Run    Run
-		Result Result
-	}
+	var results []TrendPoint
 
 	for rows.Next() {
 		var run Run
@@ -684,7 +1179,7 @@ func (db *DB) GetTrend(namePattern string, limit int) ([]struct {
 		var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
 
 		if err := rows.Scan(
-			&run.ID, &run.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &run.RunDate, &machineID, &notes, &zigOptimize,
+			&run.ID, &run.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &run.RunDate, &machineID, &notes, &zigOptimize, &run.Harness,
 			&result.ID, &result.RunID, &result.Category, &result.Name, &result.MinNs, &result.AvgNs, &result.MaxNs,
 			&result.StdDevNs, &result.P50Ns, &result.P95Ns, &result.P99Ns,
 			&result.TotalNs, &result.Iterations, &result.SampleCount,
@@ -700,15 +1195,78 @@ func (db *DB) GetTrend(namePattern string, limit int) ([]struct {
 		run.Notes = notes.String
 		run.ZigOptimize = zigOptimize.String
 
-		results = append(results, struct {
-			Run    Run
-			Result Result
-		}{run, result})
+		results = append(results, TrendPoint{run, result})
 	}
 
 	return results, rows.Err()
 }
 
+// MemTrendPoint is one memory statistic of a benchmark together with the
+// result and run it belongs to.
+type MemTrendPoint struct {
+	Run    Run
+	Result Result
+	Stat   MemStat
+}
+
+// GetMemTrend returns the statName memory statistic of benchmarks matching
+// namePattern, newest first in history order.
+func (db *DB) GetMemTrend(namePattern, statName string, limit int) ([]MemTrendPoint, error) {
+	query := `
+		SELECT
+			ru.id, ru.commit_hash, ru.commit_hash_full, ru.commit_message, ru.commit_date, ru.branch, ru.run_date, ru.machine_id, ru.notes, ru.zig_optimize, ru.harness,
+			r.id, r.run_id, r.category, r.name, COALESCE(r.sample_count, 1),
+			m.id, m.stat_name, m.bytes, COALESCE(m.min_bytes, m.bytes), COALESCE(m.max_bytes, m.bytes), m.sample_count
+		FROM mem_stats m
+		JOIN results r ON m.result_id = r.id
+		JOIN runs ru ON r.run_id = ru.id
+		WHERE r.name LIKE ? AND m.stat_name = ? AND ` + historyRun("ru.") + `
+		ORDER BY ` + historyOrder("ru.")
+
+	args := []interface{}{"%" + namePattern + "%", statName}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var points []MemTrendPoint
+	for rows.Next() {
+		var p MemTrendPoint
+		var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
+
+		if err := rows.Scan(
+			&p.Run.ID, &p.Run.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &p.Run.RunDate, &machineID, &notes, &zigOptimize, &p.Run.Harness,
+			&p.Result.ID, &p.Result.RunID, &p.Result.Category, &p.Result.Name, &p.Result.SampleCount,
+			&p.Stat.ID, &p.Stat.StatName, &p.Stat.Bytes, &p.Stat.MinBytes, &p.Stat.MaxBytes, &p.Stat.SampleCount,
+		); err != nil {
+			return nil, err
+		}
+
+		p.Run.CommitHashFull = commitHashFull.String
+		p.Run.CommitMessage = commitMessage.String
+		p.Run.CommitDate = commitDate.String
+		p.Run.Branch = branch.String
+		p.Run.MachineID = machineID.String
+		p.Run.Notes = notes.String
+		p.Run.ZigOptimize = zigOptimize.String
+		p.Stat.ResultID = p.Result.ID
+
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
 func (db *DB) DeleteRun(id int64) error {
 	_, err := db.Exec(`DELETE FROM runs WHERE id = ?`, id)
 	return err
@@ -785,7 +1343,7 @@ func (db *DB) HasFlamegraph(runID int64, benchmarkName string) (bool, error) {
 }
 
 func (db *DB) GetRecentRunIDs(limit int) ([]int64, error) {
-	rows, err := db.Query(`SELECT id FROM runs ORDER BY run_date DESC LIMIT ?`, limit)
+	rows, err := db.Query(`SELECT id FROM runs ORDER BY `+historyOrder("")+` LIMIT ?`, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -816,40 +1374,80 @@ type Artifact struct {
 	CreatedAt string
 }
 
+// InsertArtifact stores an artifact, with its payload in the blob store if
+// that is in use. The blob is written in the same write transaction as the
+// row, so a concurrent CollectGarbage cannot remove an existing blob the new
+// row is about to reference.
 func (db *DB) InsertArtifact(a *Artifact) (int64, error) {
-	res, err := db.Exec(`
-		INSERT INTO artifacts (result_id, kind, data_blob, metadata, created_at)
-		VALUES (?, ?, ?, ?, ?)`,
-		a.ResultID, a.Kind, a.DataBlob, a.Metadata, a.CreatedAt)
+	var id int64
+	err := db.InTx(func(tx *Tx) error {
+		var err error
+		id, err = tx.InsertArtifact(a)
+		return err
+	})
+	return id, err
+}
+
+// insertArtifact must run in a write transaction when blobs is in use; see
+// InsertArtifact.
+func insertArtifact(e execer, blobs *BlobStore, a *Artifact) (int64, error) {
+	data, sum, size, err := artifactPayload(blobs, a)
+	if err != nil {
+		return 0, err
+	}
+	res, err := e.Exec(`
+		INSERT INTO artifacts (result_id, kind, data_blob, blob_sha256, blob_size, metadata, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.ResultID, a.Kind, data, sum, size, a.Metadata, a.CreatedAt)
 	if err != nil {
 		return 0, err
 	}
 	return res.LastInsertId()
 }
 
+// InsertArtifactIfMissing stores an artifact unless one of its kind exists
+// for the result, writing the payload like InsertArtifact.
 func (db *DB) InsertArtifactIfMissing(a *Artifact) error {
-	_, err := db.Exec(`
-		INSERT OR IGNORE INTO artifacts (result_id, kind, data_blob, metadata, created_at)
-		VALUES (?, ?, ?, ?, ?)`,
-		a.ResultID, a.Kind, a.DataBlob, a.Metadata, a.CreatedAt)
-	return err
+	return db.InTx(func(tx *Tx) error {
+		data, sum, size, err := artifactPayload(tx.blobs, a)
+		if err != nil {
+			return err
+		}
+		_, err = tx.tx.Exec(`
+			INSERT OR IGNORE INTO artifacts (result_id, kind, data_blob, blob_sha256, blob_size, metadata, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			a.ResultID, a.Kind, data, sum, size, a.Metadata, a.CreatedAt)
+		return err
+	})
 }
 
+// GetArtifact returns an artifact with its payload, read from the blob store
+// if it was stored there.
 func (db *DB) GetArtifact(resultID int64, kind string) (*Artifact, error) {
 	var a Artifact
+	var sum sql.NullString
 	err := db.QueryRow(`
-		SELECT id, result_id, kind, data_blob, metadata, created_at
+		SELECT id, result_id, kind, data_blob, blob_sha256, metadata, created_at
 		FROM artifacts WHERE result_id = ? AND kind = ?`, resultID, kind).Scan(
-		&a.ID, &a.ResultID, &a.Kind, &a.DataBlob, &a.Metadata, &a.CreatedAt)
+		&a.ID, &a.ResultID, &a.Kind, &a.DataBlob, &sum, &a.Metadata, &a.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if sum.Valid {
+		if !db.blobs.Enabled() {
+			return nil, fmt.Errorf("%w (%s)", errNoBlobStore, db.blobs.Dir())
+		}
+		if a.DataBlob, err = db.blobs.Get(sum.String); err != nil {
+			return nil, err
+		}
+	}
+	a.DataSize = int64(len(a.DataBlob))
 	return &a, nil
 }
 
 func (db *DB) ListArtifactsForResult(resultID int64) ([]Artifact, error) {
 	rows, err := db.Query(`
-		SELECT id, result_id, kind, length(data_blob), metadata, created_at
+		SELECT id, result_id, kind, COALESCE(blob_size, length(data_blob)), metadata, created_at
 		FROM artifacts WHERE result_id = ? ORDER BY kind`, resultID)
 	if err != nil {
 		return nil, err
@@ -871,32 +1469,254 @@ func (db *DB) ListArtifactsForResult(resultID int64) ([]Artifact, error) {
 	return artifacts, rows.Err()
 }
 
+// RunArtifact is a gzip-compressed blob attached to a whole run, such as the
+// output of a failed build.
+type RunArtifact struct {
+	ID        int64
+	RunID     int64
+	Kind      string
+	Data      []byte // uncompressed; empty in listings
+	Size      int64  // compressed size
+	CreatedAt string
+}
+
+// PutRunArtifact compresses data and stores it, replacing any artifact of
+// the same kind for the run.
+func (db *DB) PutRunArtifact(runID int64, kind string, data []byte, createdAt string) error {
+	return putRunArtifact(db.DB, runID, kind, data, createdAt)
+}
+
+func putRunArtifact(e execer, runID int64, kind string, data []byte, createdAt string) error {
+	gz, err := gzipCompress(data)
+	if err != nil {
+		return fmt.Errorf("compress %s: %w", kind, err)
+	}
+	_, err = e.Exec(`
+		INSERT OR REPLACE INTO run_artifacts (run_id, kind, data_gz, created_at)
+		VALUES (?, ?, ?, ?)`, runID, kind, gz, createdAt)
+	return err
+}
+
+func (db *DB) GetRunArtifact(runID int64, kind string) (*RunArtifact, error) {
+	var a RunArtifact
+	var gz []byte
+	err := db.QueryRow(`
+		SELECT id, run_id, kind, data_gz, created_at
+		FROM run_artifacts WHERE run_id = ? AND kind = ?`, runID, kind).Scan(
+		&a.ID, &a.RunID, &a.Kind, &gz, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.Size = int64(len(gz))
+	a.Data, err = gzipDecompress(gz)
+	if err != nil {
+		return nil, fmt.Errorf("decompress %s: %w", kind, err)
+	}
+	return &a, nil
+}
+
+func (db *DB) ListRunArtifacts(runID int64) ([]RunArtifact, error) {
+	rows, err := db.Query(`
+		SELECT id, run_id, kind, length(data_gz), created_at
+		FROM run_artifacts WHERE run_id = ? ORDER BY kind`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var artifacts []RunArtifact
+	for rows.Next() {
+		var a RunArtifact
+		if err := rows.Scan(&a.ID, &a.RunID, &a.Kind, &a.Size, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, rows.Err()
+}
+
+// RunMetric is a single measurement of a whole run rather than of one
+// benchmark, such as the build time or the size of a built artifact.
+type RunMetric struct {
+	RunID int64
+	Name  string
+	Value int64
+	Unit  string // MetricUnitNs or MetricUnitBytes
+}
+
+// Units of run metrics.
+const (
+	MetricUnitNs    = "ns"
+	MetricUnitBytes = "bytes"
+)
+
+// PutRunMetric stores m, replacing an earlier value of the same metric.
+func (db *DB) PutRunMetric(m *RunMetric) error {
+	return putRunMetric(db.DB, m)
+}
+
+func putRunMetric(e execer, m *RunMetric) error {
+	_, err := e.Exec(`
+		INSERT OR REPLACE INTO run_metrics (run_id, name, value, unit)
+		VALUES (?, ?, ?, ?)`, m.RunID, m.Name, m.Value, m.Unit)
+	return err
+}
+
+func (db *DB) GetRunMetrics(runID int64) ([]RunMetric, error) {
+	rows, err := db.Query(`
+		SELECT run_id, name, value, unit
+		FROM run_metrics WHERE run_id = ? ORDER BY name`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var metrics []RunMetric
+	for rows.Next() {
+		var m RunMetric
+		if err := rows.Scan(&m.RunID, &m.Name, &m.Value, &m.Unit); err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, rows.Err()
+}
+
+// GetRunMetricsInRuns returns the run metrics of several runs, keyed by run
+// ID.
+func (db *DB) GetRunMetricsInRuns(runIDs []int64) (map[int64][]RunMetric, error) {
+	metrics := make(map[int64][]RunMetric)
+	if len(runIDs) == 0 {
+		return metrics, nil
+	}
+
+	placeholders, args := inPlaceholders(runIDs)
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT run_id, name, value, unit
+		FROM run_metrics WHERE run_id IN (%s)
+		ORDER BY name`, placeholders), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for rows.Next() {
+		var m RunMetric
+		if err := rows.Scan(&m.RunID, &m.Name, &m.Value, &m.Unit); err != nil {
+			return nil, err
+		}
+		metrics[m.RunID] = append(metrics[m.RunID], m)
+	}
+	return metrics, rows.Err()
+}
+
+// MetricTrendPoint is one value of a run metric together with its run.
+type MetricTrendPoint struct {
+	Run    Run
+	Metric RunMetric
+}
+
+// GetMetricTrend returns the values of the run metric name, newest first in
+// history order.
+func (db *DB) GetMetricTrend(name string, limit int) ([]MetricTrendPoint, error) {
+	query := `
+		SELECT ` + runColumns + `, m.name, m.value, m.unit
+		FROM run_metrics m
+		JOIN runs ru ON m.run_id = ru.id
+		WHERE m.name = ? AND ` + historyRun("ru.") + `
+		ORDER BY ` + historyOrder("ru.")
+
+	args := []interface{}{name}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	var points []MetricTrendPoint
+	for rows.Next() {
+		var p MetricTrendPoint
+		run, err := scanRun(rows, &p.Metric.Name, &p.Metric.Value, &p.Metric.Unit)
+		if err != nil {
+			return nil, err
+		}
+		p.Run = *run
+		p.Metric.RunID = run.ID
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
 // ComparableRunsWindow fetches a window of runs comparable to the given run.
-// Comparable means same branch, machine_id, and zig_optimize.
-// Returns runs in reverse chronological order (most recent first).
+// Comparable means same branch, machine_id, harness and zig_optimize, and a
+// history run unless it is the given run itself. When
+// matchEnvironment is set, runs must also share the reference run's
+// environment fingerprint; runs recorded without an environment match any
+// fingerprint so history from before fingerprinting is not discarded.
+// Returns runs at or before the given run in history order, newest first.
 // The window parameter controls how many runs to return (including the reference run if found).
-func (db *DB) GetComparableRunsWindow(runID int64, window int) ([]Run, error) {
+func (db *DB) GetComparableRunsWindow(runID int64, window int, matchEnvironment bool) ([]Run, error) {
 	// First get the reference run to find its comparison criteria
 	refRun, err := db.GetRun(runID)
 	if err != nil {
 		return nil, err
 	}
 
+	fingerprint := ""
+	if matchEnvironment {
+		env, err := db.GetRunEnvironment(runID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if env != nil {
+			fingerprint = env.Fingerprint
+		}
+	}
+
 	query := `
-		SELECT id, commit_hash, commit_hash_full, commit_message, commit_date, branch, run_date, machine_id, notes, zig_optimize
+		SELECT ` + runColumns + `
 		FROM runs
-		WHERE (branch = ? OR (branch IS NULL AND ? = ''))
+		WHERE status = 'complete'
+		  AND (id = ? OR ` + historyRun("") + `)
+		  AND (branch = ? OR (branch IS NULL AND ? = ''))
 		  AND (machine_id = ? OR (machine_id IS NULL AND ? = ''))
+		  AND harness = ?
 		  AND (zig_optimize = ? OR (zig_optimize IS NULL AND ? = ''))
-		  AND run_date <= ?
-		ORDER BY run_date DESC
+		  AND (? = '' OR COALESCE((SELECT fingerprint FROM run_environment WHERE run_id = runs.id), ?) = ?)
+		  AND (` + historyKey("") + `) <= (SELECT ` + historyKey("") + ` FROM runs WHERE id = ?)
+		ORDER BY ` + historyOrder("") + `
 		LIMIT ?`
 
 	rows, err := db.Query(query,
+		refRun.ID,
 		refRun.Branch, refRun.Branch,
 		refRun.MachineID, refRun.MachineID,
+		refRun.Harness,
 		refRun.ZigOptimize, refRun.ZigOptimize,
-		refRun.RunDate,
+		fingerprint, fingerprint, fingerprint,
+		refRun.ID,
 		window)
 	if err != nil {
 		return nil, err
@@ -909,45 +1729,32 @@ func (db *DB) GetComparableRunsWindow(runID int64, window int) ([]Run, error) {
 
 	var runs []Run
 	for rows.Next() {
-		var r Run
-		var commitHashFull, commitMessage, commitDate, branch, machineID, notes, zigOptimize sql.NullString
-		if err := rows.Scan(&r.ID, &r.CommitHash, &commitHashFull, &commitMessage, &commitDate, &branch, &r.RunDate, &machineID, &notes, &zigOptimize); err != nil {
+		r, err := scanRun(rows)
+		if err != nil {
 			return nil, err
 		}
-		r.CommitHashFull = commitHashFull.String
-		r.CommitMessage = commitMessage.String
-		r.CommitDate = commitDate.String
-		r.Branch = branch.String
-		r.MachineID = machineID.String
-		r.Notes = notes.String
-		r.ZigOptimize = zigOptimize.String
-		runs = append(runs, r)
+		runs = append(runs, *r)
 	}
 	return runs, rows.Err()
 }
 
-// GetResultsForBenchmarkInRuns fetches all results for a specific benchmark name across multiple runs.
+// GetResultsForBenchmarkInRuns fetches the results of the benchmark
+// category/name across multiple runs.
 // Returns a map of runID -> Result.
-func (db *DB) GetResultsForBenchmarkInRuns(benchmarkName string, runIDs []int64) (map[int64]Result, error) {
+func (db *DB) GetResultsForBenchmarkInRuns(category, benchmarkName string, runIDs []int64) (map[int64]Result, error) {
 	if len(runIDs) == 0 {
 		return make(map[int64]Result), nil
 	}
 
-	// Build placeholders for IN clause
-	placeholders := make([]string, len(runIDs))
-	args := make([]interface{}, len(runIDs)+1)
-	args[0] = benchmarkName
-	for i, id := range runIDs {
-		placeholders[i] = "?"
-		args[i+1] = id
-	}
+	placeholders, idArgs := inPlaceholders(runIDs)
+	args := append([]interface{}{category, benchmarkName}, idArgs...)
 
 	query := fmt.Sprintf(`
 		SELECT id, run_id, category, name, min_ns, avg_ns, max_ns,
 		       COALESCE(std_dev_ns, 0), COALESCE(p50_ns, 0), COALESCE(p95_ns, 0), COALESCE(p99_ns, 0),
 		       total_ns, iterations, COALESCE(sample_count, 1)
 		FROM results
-		WHERE name = ? AND run_id IN (%s)`, strings.Join(placeholders, ","))
+		WHERE category = ? AND name = ? AND run_id IN (%s)`, placeholders)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -978,17 +1785,12 @@ func (db *DB) GetDistinctBenchmarkNames(runIDs []int64) ([]string, error) {
 		return []string{}, nil
 	}
 
-	placeholders := make([]string, len(runIDs))
-	args := make([]interface{}, len(runIDs))
-	for i, id := range runIDs {
-		placeholders[i] = "?"
-		args[i] = id
-	}
+	placeholders, args := inPlaceholders(runIDs)
 
 	query := fmt.Sprintf(`
 		SELECT DISTINCT name FROM results
 		WHERE run_id IN (%s)
-		ORDER BY name`, strings.Join(placeholders, ","))
+		ORDER BY name`, placeholders)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
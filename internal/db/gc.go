@@ -0,0 +1,256 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Artifact kinds. CPU profiles are captured by the runner; the SVGs are
+// rendered from them on demand and cached, so they can always be rendered
+// again while the profile exists.
+const (
+	ArtifactCPUProfile    = "cpu.pprof"
+	ArtifactFlamegraphSVG = "cpu.flamegraph.svg"
+	ArtifactCallgraphSVG  = "cpu.callgraph.svg"
+)
+
+// GCItem kinds besides artifact kinds: rows of the legacy flamegraphs table,
+// and files of the blob store no artifact references any more.
+const (
+	GCFlamegraphs = "flamegraphs"
+	GCBlobs       = "blobs"
+)
+
+// GCPolicy selects what CollectGarbage drops. Runs and results are always
+// kept.
+type GCPolicy struct {
+	// KeepProfiles keeps the CPU profiles of the newest KeepProfiles
+	// profiled runs of each branch, in history order. Older runs lose their
+	// profiles and the SVGs rendered from them. Negative keeps all.
+	KeepProfiles int
+	// SVGsBefore drops cached SVGs created before it. Zero keeps all.
+	SVGsBefore time.Time
+}
+
+// GCItem is what a collection drops of one kind of data. The bytes of
+// artifacts count only payloads stored in the database; those in the blob
+// store are counted under GCBlobs once no artifact references them.
+type GCItem struct {
+	Kind  string
+	Count int
+	Bytes int64
+}
+
+// GCReport describes a collection.
+type GCReport struct {
+	Items []GCItem
+	// RunIDs are the runs that lost profiles or SVGs.
+	RunIDs []int64
+}
+
+// Bytes is the total payload size dropped. The file only shrinks by about
+// as much after a Vacuum.
+func (r *GCReport) Bytes() int64 {
+	var total int64
+	for _, item := range r.Items {
+		total += item.Bytes
+	}
+	return total
+}
+
+// CollectGarbage drops the profiles and cached SVGs selected by p in one
+// transaction and reports what it dropped. With dryRun it only reports.
+func (db *DB) CollectGarbage(p GCPolicy, dryRun bool) (*GCReport, error) {
+	prunedRuns, err := db.profiledRunsBeyond(p.KeepProfiles)
+	if err != nil {
+		return nil, fmt.Errorf("select runs: %w", err)
+	}
+
+	var conds []string
+	var args []interface{}
+	if len(prunedRuns) > 0 {
+		placeholders, runArgs := inPlaceholders(prunedRuns)
+		conds = append(conds, fmt.Sprintf("(r.run_id IN (%s) AND a.kind IN (?, ?, ?))", placeholders))
+		args = append(args, runArgs...)
+		args = append(args, ArtifactCPUProfile, ArtifactFlamegraphSVG, ArtifactCallgraphSVG)
+	}
+	if !p.SVGsBefore.IsZero() {
+		conds = append(conds, "(a.kind IN (?, ?) AND julianday(a.created_at) < julianday(?))")
+		args = append(args, ArtifactFlamegraphSVG, ArtifactCallgraphSVG, p.SVGsBefore.UTC().Format(time.RFC3339))
+	}
+
+	report := &GCReport{}
+	items := make(map[string]*GCItem)
+	runs := make(map[int64]bool)
+	add := func(kind string, runID, size int64) {
+		item, ok := items[kind]
+		if !ok {
+			item = &GCItem{Kind: kind}
+			items[kind] = item
+		}
+		item.Count++
+		item.Bytes += size
+		runs[runID] = true
+	}
+
+	var artifactIDs, flamegraphIDs []int64
+	if len(conds) > 0 {
+		rows, err := db.Query(`
+			SELECT a.id, r.run_id, a.kind, length(a.data_blob)
+			FROM artifacts a
+			JOIN results r ON r.id = a.result_id
+			WHERE `+strings.Join(conds, " OR "), args...)
+		if err != nil {
+			return nil, fmt.Errorf("select artifacts: %w", err)
+		}
+		for rows.Next() {
+			var id, runID, size int64
+			var kind string
+			if err := rows.Scan(&id, &runID, &kind, &size); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			artifactIDs = append(artifactIDs, id)
+			add(kind, runID, size)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(prunedRuns) > 0 {
+		placeholders, runArgs := inPlaceholders(prunedRuns)
+		rows, err := db.Query(fmt.Sprintf(`
+			SELECT id, run_id, length(folded_stacks_gz)
+			FROM flamegraphs WHERE run_id IN (%s)`, placeholders), runArgs...)
+		if err != nil {
+			return nil, fmt.Errorf("select flamegraphs: %w", err)
+		}
+		for rows.Next() {
+			var id, runID, size int64
+			if err := rows.Scan(&id, &runID, &size); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			flamegraphIDs = append(flamegraphIDs, id)
+			add(GCFlamegraphs, runID, size)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kind := range []string{ArtifactCPUProfile, GCFlamegraphs, ArtifactFlamegraphSVG, ArtifactCallgraphSVG} {
+		if item, ok := items[kind]; ok {
+			report.Items = append(report.Items, *item)
+		}
+	}
+	for id := range runs {
+		report.RunIDs = append(report.RunIDs, id)
+	}
+	slices.Sort(report.RunIDs)
+
+	if dryRun {
+		dropped := make(map[int64]bool, len(artifactIDs))
+		for _, id := range artifactIDs {
+			dropped[id] = true
+		}
+		orphans, err := unreferencedBlobs(db, db.blobs, dropped)
+		if err != nil {
+			return nil, err
+		}
+		report.addBlobs(orphans)
+		return report, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for table, ids := range map[string][]int64{"artifacts": artifactIDs, "flamegraphs": flamegraphIDs} {
+		for chunk := range slices.Chunk(ids, 500) {
+			placeholders, chunkArgs := inPlaceholders(chunk)
+			if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`, table, placeholders), chunkArgs...); err != nil {
+				return nil, fmt.Errorf("delete %s: %w", table, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// Blob files are only removed once the rows referencing them are gone
+	// for good, so a failed commit cannot leave artifacts without payloads.
+	// They are removed under the write lock, so no concurrent writer can
+	// reference one of them again in between; a failure leaves orphans for
+	// the next collection.
+	var orphans map[string]int64
+	err = db.InTx(func(tx *Tx) error {
+		var err error
+		if orphans, err = unreferencedBlobs(tx.tx, db.blobs, nil); err != nil {
+			return err
+		}
+		for sum := range orphans {
+			if err := db.blobs.Remove(sum); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remove unreferenced blobs: %w", err)
+	}
+	report.addBlobs(orphans)
+	return report, nil
+}
+
+func (r *GCReport) addBlobs(blobs map[string]int64) {
+	if len(blobs) == 0 {
+		return
+	}
+	item := GCItem{Kind: GCBlobs, Count: len(blobs)}
+	for _, size := range blobs {
+		item.Bytes += size
+	}
+	r.Items = append(r.Items, item)
+}
+
+// profiledRunsBeyond returns the runs that have CPU profiles but are not
+// among the newest keep profiled runs of their branch. Negative keep
+// returns none.
+func (db *DB) profiledRunsBeyond(keep int) ([]int64, error) {
+	if keep < 0 {
+		return nil, nil
+	}
+
+	return queryIDs(db, fmt.Sprintf(`
+		WITH profiled AS (
+			SELECT ru.id,
+			       ROW_NUMBER() OVER (PARTITION BY COALESCE(ru.branch, '') ORDER BY %s) AS rank
+			FROM runs ru
+			WHERE EXISTS (
+				SELECT 1 FROM artifacts a JOIN results r ON r.id = a.result_id
+				WHERE r.run_id = ru.id AND a.kind = ?
+			) OR EXISTS (SELECT 1 FROM flamegraphs f WHERE f.run_id = ru.id)
+		)
+		SELECT id FROM profiled WHERE rank > ? ORDER BY id`, historyOrder("ru.")), ArtifactCPUProfile, keep)
+}
+
+// Vacuum rebuilds the database file to return the space of deleted rows to
+// the file system, then truncates the WAL the rebuild went through.
+func (db *DB) Vacuum() error {
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := db.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	database, runIDs := generateDB(t, 4, 1)
+
+	feature, err := database.InsertRun(&Run{CommitHash: "feature", Branch: "feature", RunDate: "2024-01-01T00:00:00Z", CommitSeq: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	featureResult, err := database.InsertResultWithSamples(&Result{RunID: feature, Category: "category 0", Name: "bench 0"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.InsertArtifact(&Artifact{ResultID: featureResult, Kind: ArtifactCPUProfile, DataBlob: make([]byte, 100), CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every main run has a profile and a flamegraph SVG; only the newest
+	// SVG is recent.
+	var resultIDs []int64
+	for i, runID := range runIDs {
+		results, err := database.GetResultsForRun(runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resultID := results[0].ID
+		resultIDs = append(resultIDs, resultID)
+		svgCreated := "2024-01-01T00:00:00+02:00"
+		if i == len(runIDs)-1 {
+			svgCreated = time.Now().Format(time.RFC3339)
+		}
+		for _, a := range []*Artifact{
+			{ResultID: resultID, Kind: ArtifactCPUProfile, DataBlob: make([]byte, 100), CreatedAt: "2024-01-01T00:00:00Z"},
+			{ResultID: resultID, Kind: ArtifactFlamegraphSVG, DataBlob: make([]byte, 10), CreatedAt: svgCreated},
+		} {
+			if _, err := database.InsertArtifact(a); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := database.InsertFlamegraph(&Flamegraph{RunID: runIDs[0], BenchmarkName: "bench 0", FoldedStacks: "main 1", SamplingFreq: 997, CreatedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := GCPolicy{KeepProfiles: 2, SVGsBefore: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	dry, err := database.CollectGarbage(policy, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := database.GetArtifact(resultIDs[0], ArtifactCPUProfile); err != nil {
+		t.Fatalf("dry run deleted a profile: %v", err)
+	}
+
+	report, err := database.CollectGarbage(policy, false)
+	if err != nil {
+		t.Fatalf("CollectGarbage: %v", err)
+	}
+	if !slices.Equal(report.Items, dry.Items) || !slices.Equal(report.RunIDs, dry.RunIDs) {
+		t.Fatalf("report = %+v, dry run reported %+v", report, dry)
+	}
+
+	counts := make(map[string]int)
+	for _, item := range report.Items {
+		counts[item.Kind] = item.Count
+	}
+	want := map[string]int{ArtifactCPUProfile: 2, GCFlamegraphs: 1, ArtifactFlamegraphSVG: 3}
+	for kind, n := range want {
+		if counts[kind] != n {
+			t.Errorf("%s: dropped %d, want %d", kind, counts[kind], n)
+		}
+	}
+	if !slices.Equal(report.RunIDs, runIDs[:3]) {
+		t.Errorf("runs = %v, want %v", report.RunIDs, runIDs[:3])
+	}
+
+	for i, resultID := range resultIDs {
+		_, err := database.GetArtifact(resultID, ArtifactCPUProfile)
+		if kept := err == nil; kept != (i >= 2) {
+			t.Errorf("run %d: profile kept = %v", i, kept)
+		}
+		_, err = database.GetArtifact(resultID, ArtifactFlamegraphSVG)
+		if kept := err == nil; kept != (i == 3) {
+			t.Errorf("run %d: SVG kept = %v", i, kept)
+		}
+	}
+	if _, err := database.GetArtifact(featureResult, ArtifactCPUProfile); err != nil {
+		t.Errorf("feature branch profile dropped: %v", err)
+	}
+	resultCounts, err := database.CountResultsForRuns(runIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range runIDs {
+		if resultCounts[id] != 1 {
+			t.Errorf("run %d: %d results after gc", id, resultCounts[id])
+		}
+	}
+
+	if err := database.Vacuum(); err != nil {
+		t.Fatalf("Vacuum: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+// insertHistory inserts runs in the given order and returns their ids by
+// commit hash.
+func insertHistory(t *testing.T, database *DB, runs []Run) map[string]int64 {
+	t.Helper()
+	ids := make(map[string]int64)
+	err := database.InTx(func(tx *Tx) error {
+		for _, r := range runs {
+			r.CommitHashFull = r.CommitHash
+			r.RunDate = "2024-06-01T00:00:00Z"
+			id, err := tx.InsertRun(&r)
+			if err != nil {
+				return err
+			}
+			ids[r.CommitHash] = id
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func commitsOf(runs []Run) []string {
+	var commits []string
+	for _, r := range runs {
+		commits = append(commits, r.CommitHash)
+	}
+	return commits
+}
+
+func TestHistoryOrderBackfill(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	// c3 and c4 were recorded first; c1 and c2 were backfilled later, so
+	// their ids are higher although they are older.
+	ids := insertHistory(t, database, []Run{
+		{CommitHash: "c3", Branch: "main", CommitSeq: 3, CommitDate: "2024-01-03T00:00:00Z"},
+		{CommitHash: "c4", Branch: "main", CommitSeq: 4, CommitDate: "2024-01-04T00:00:00Z"},
+		{CommitHash: "c1", Branch: "main", CommitSeq: 1, CommitDate: "2024-01-01T00:00:00Z"},
+		{CommitHash: "c2", Branch: "main", CommitSeq: 2, CommitDate: "2024-01-02T00:00:00Z"},
+	})
+
+	runs, err := database.ListRuns(0, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := commitsOf(runs), []string{"c4", "c3", "c2", "c1"}; !slices.Equal(got, want) {
+		t.Fatalf("ListRuns = %v, want %v", got, want)
+	}
+
+	latest, err := database.GetLatestRun("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.CommitHash != "c4" {
+		t.Fatalf("latest run is %s, want c4", latest.CommitHash)
+	}
+
+	window, err := database.GetComparableRunsWindow(ids["c2"], 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := commitsOf(window), []string{"c2", "c1"}; !slices.Equal(got, want) {
+		t.Fatalf("window of c2 = %v, want %v", got, want)
+	}
+}
+
+func TestHistoryOrderWithoutSequence(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	// c2 was imported without a sequence. It sorts by its commit date among
+	// the sequenced runs of its branch instead of below all of them.
+	insertHistory(t, database, []Run{
+		{CommitHash: "c1", Branch: "main", CommitSeq: 1, CommitDate: "2024-01-01T00:00:00Z"},
+		{CommitHash: "c3", Branch: "main", CommitSeq: 3, CommitDate: "2024-01-03T00:00:00Z"},
+		{CommitHash: "c2", Branch: "main", CommitDate: "2024-01-02T00:00:00Z"},
+		{CommitHash: "c0", Branch: "main"},
+	})
+
+	runs, err := database.ListRuns(0, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := commitsOf(runs), []string{"c3", "c2", "c1", "c0"}; !slices.Equal(got, want) {
+		t.Fatalf("ListRuns = %v, want %v", got, want)
+	}
+}
+
+func TestGetLatestRunBranch(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	// The feature branch is longer than main, so its sequence is higher.
+	insertHistory(t, database, []Run{
+		{CommitHash: "main2", Branch: "main", CommitSeq: 2, CommitDate: "2024-01-02T00:00:00Z"},
+		{CommitHash: "feature9", Branch: "feature", CommitSeq: 9, CommitDate: "2024-01-01T00:00:00Z"},
+	})
+
+	for branch, want := range map[string]string{"main": "main2", "feature": "feature9", "": "feature9", "missing": "feature9"} {
+		latest, err := database.GetLatestRun(branch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest.CommitHash != want {
+			t.Errorf("GetLatestRun(%q) = %s, want %s", branch, latest.CommitHash, want)
+		}
+	}
+}
+
+func TestComparableRunsWindowMatchesEnvironment(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	ids := insertHistory(t, database, []Run{
+		{CommitHash: "c1", Branch: "main", CommitSeq: 1},
+		{CommitHash: "c2", Branch: "main", CommitSeq: 2},
+		{CommitHash: "c3", Branch: "main", CommitSeq: 3},
+		{CommitHash: "c4", Branch: "main", CommitSeq: 4},
+	})
+	// c1 predates environment tracking; c2 ran on another machine.
+	for commit, fingerprint := range map[string]string{"c2": "other", "c3": "box", "c4": "box"} {
+		if err := database.InsertRunEnvironment(&RunEnvironment{RunID: ids[commit], Fingerprint: fingerprint}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		match bool
+		want  []string
+	}{
+		{false, []string{"c4", "c3", "c2", "c1"}},
+		{true, []string{"c4", "c3", "c1"}},
+	} {
+		window, err := database.GetComparableRunsWindow(ids["c4"], 10, tc.match)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := commitsOf(window); !slices.Equal(got, tc.want) {
+			t.Errorf("window with matchEnvironment=%v = %v, want %v", tc.match, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func insertJobs(t *testing.T, database *DB, priorities ...int) []int64 {
+	t.Helper()
+	var ids []int64
+	for _, p := range priorities {
+		id, err := database.InsertJob(&Job{Commit: "main", Config: "{}", Priority: p, CreatedAt: "2024-01-01T00:00:00Z"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestClaimJob(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	ids := insertJobs(t, database, 0, 1, 0)
+
+	// Highest priority first, then oldest.
+	for _, want := range []int64{ids[1], ids[0], ids[2]} {
+		job, err := database.ClaimJob("2024-01-01T01:00:00Z")
+		if err != nil {
+			t.Fatalf("ClaimJob: %v", err)
+		}
+		if job.ID != want || job.Status != JobRunning || job.Attempts != 1 {
+			t.Fatalf("claimed job %d (%s, %d attempts), want job %d running with 1 attempt", job.ID, job.Status, job.Attempts, want)
+		}
+	}
+	if _, err := database.ClaimJob("2024-01-01T01:00:00Z"); err != sql.ErrNoRows {
+		t.Fatalf("ClaimJob on an empty queue: %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestJobRetries(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	id := insertJobs(t, database, 0)[0]
+	now := "2024-01-01T01:00:00Z"
+
+	claim := func(wantAttempts int) {
+		t.Helper()
+		job, err := database.ClaimJob(now)
+		if err != nil {
+			t.Fatalf("ClaimJob: %v", err)
+		}
+		if job.ID != id || job.Attempts != wantAttempts {
+			t.Fatalf("claimed job %d with %d attempts, want job %d with %d", job.ID, job.Attempts, id, wantAttempts)
+		}
+	}
+	status := func(want string) {
+		t.Helper()
+		job, err := database.GetJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != want {
+			t.Fatalf("job is %s, want %s", job.Status, want)
+		}
+	}
+
+	claim(1)
+	if err := database.FailJob(id, "build failed", true, now); err != nil {
+		t.Fatal(err)
+	}
+	status(JobQueued)
+
+	// An interrupted attempt does not count.
+	claim(2)
+	if err := database.ReleaseJob(id); err != nil {
+		t.Fatal(err)
+	}
+	status(JobQueued)
+
+	claim(2)
+	if err := database.FailJob(id, "build failed", false, now); err != nil {
+		t.Fatal(err)
+	}
+	status(JobFailed)
+	job, err := database.GetJob(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Error != "build failed" {
+		t.Fatalf("error = %q", job.Error)
+	}
+}
+
+func TestRequeueStaleJobs(t *testing.T) {
+	database, _ := generateDB(t, 0, 0)
+	ids := insertJobs(t, database, 3, 2, 1, 0)
+
+	// Job 0 was claimed twice, job 1 once, both by workers that died; job 2
+	// is a long job whose worker is alive and job 3 just started.
+	for _, claim := range []string{"2024-01-01T00:00:00Z", "2024-01-01T01:00:00Z", "2024-01-01T02:00:00Z", "2024-01-01T05:00:00Z"} {
+		if _, err := database.ClaimJob(claim); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := database.Exec(`UPDATE jobs SET attempts = 2 WHERE id = ?`, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.HeartbeatJob(ids[2], "2024-01-01T05:30:00Z"); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := database.RequeueStaleJobs("2024-01-01T04:00:00Z", 2, "2024-01-01T06:00:00Z")
+	if err != nil {
+		t.Fatalf("RequeueStaleJobs: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("recovered %d jobs, want 2", n)
+	}
+	for i, want := range []string{JobFailed, JobQueued, JobRunning, JobRunning} {
+		job, err := database.GetJob(ids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != want {
+			t.Errorf("job %d is %s, want %s", i, job.Status, want)
+		}
+	}
+}
+
+func TestMergeJobs(t *testing.T) {
+	local, _ := generateDB(t, 0, 0)
+	insertJobs(t, local, 0)
+
+	remotePath := filepath.Join(t.TempDir(), "remote.db")
+	remote, err := Open(remotePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertJobs(t, remote, 0, 5, 3)
+	if err := remote.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := local.MergeJobs(remotePath)
+	if err != nil {
+		t.Fatalf("MergeJobs: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("merged %d jobs, want 2", n)
+	}
+	jobs, err := local.ListJobs(10, JobQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 3 || jobs[0].Priority != 3 || jobs[1].Priority != 5 {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+
+	// Merging again copies nothing.
+	if n, err := local.MergeJobs(remotePath); err != nil || n != 0 {
+		t.Fatalf("second MergeJobs = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,356 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migrations are the files in migrations/, named <version>_<name>.sql with
+// versions numbered from 0001 without gaps. Each one is applied once, in its
+// own transaction, and recorded in schema_migrations. Applied migrations must
+// never be edited; change the schema by adding a new file (and updating
+// schema.sql).
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationHooks are Go steps run before a migration's SQL, in the same
+// transaction, for changes SQL alone cannot express.
+var migrationHooks = map[int]func(tx *sql.Tx) error{
+	1: upgradeUnversioned,
+}
+
+// Migration is one step of the schema history.
+type Migration struct {
+	Version   int
+	Name      string
+	AppliedAt string // empty unless returned by AppliedMigrations
+
+	sql  string
+	hook func(tx *sql.Tx) error
+}
+
+var migrations = loadMigrations()
+
+// ErrSchemaTooNew is returned for a database that was migrated by a newer
+// bench than this one.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+func loadMigrations() []Migration {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	var list []Migration
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version != len(list)+1 {
+			panic(fmt.Sprintf("migration %s: expected %04d_<name>.sql", entry.Name(), len(list)+1))
+		}
+		data, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		list = append(list, Migration{
+			Version: version,
+			Name:    name,
+			sql:     string(data),
+			hook:    migrationHooks[version],
+		})
+	}
+	return list
+}
+
+// LatestSchemaVersion returns the schema version this binary migrates to.
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the version of the last migration applied to the
+// database; 0 for a new database or one created before migrations were
+// versioned.
+func (db *DB) SchemaVersion() (int, error) {
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// AppliedMigrations returns the migrations recorded in the database, oldest
+// first.
+func (db *DB) AppliedMigrations() ([]Migration, error) {
+	version, err := db.SchemaVersion()
+	if err != nil || version == 0 {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT version, name, applied_at FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var applied []Migration
+	for rows.Next() {
+		var m Migration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, rows.Err()
+}
+
+// PendingMigrations returns the migrations Migrate would apply. It fails with
+// ErrSchemaTooNew if the database is ahead of this binary.
+func (db *DB) PendingMigrations() ([]Migration, error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if version > len(migrations) {
+		return nil, fmt.Errorf("%w (database is at version %d, bench supports up to %d)", ErrSchemaTooNew, version, len(migrations))
+	}
+	return migrations[version:], nil
+}
+
+// Migrate applies the pending migrations in order and returns the ones it
+// applied. A failing migration is rolled back and stops the sequence.
+func (db *DB) Migrate() ([]Migration, error) {
+	pending, err := db.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range pending {
+		if err := db.applyMigration(m); err != nil {
+			return pending[:i], fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+func (db *DB) applyMigration(m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if m.hook != nil {
+		if err := m.hook(tx); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(m.sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// upgradeUnversioned brings a database created before schema_migrations
+// existed to the shape the baseline expects. It does nothing on a new
+// database.
+func upgradeUnversioned(tx *sql.Tx) error {
+	if err := migrateFlamegraphs(tx); err != nil {
+		return err
+	}
+	if err := ensureColumns(tx, "runs", [][2]string{
+		{"status", "TEXT NOT NULL DEFAULT 'complete'"},
+		{"failure_reason", "TEXT"},
+		{"commit_seq", "INTEGER"},
+	}); err != nil {
+		return err
+	}
+	return ensureColumns(tx, "mem_stats", [][2]string{
+		{"min_bytes", "INTEGER"},
+		{"max_bytes", "INTEGER"},
+		{"sample_count", "INTEGER NOT NULL DEFAULT 1"},
+	})
+}
+
+// ensureColumns adds columns introduced after a table was first created.
+// Tables that do not exist yet are left to the baseline.
+func ensureColumns(tx *sql.Tx, table string, columns [][2]string) error {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	for _, col := range columns {
+		if existing[col[0]] {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, col[0], col[1])); err != nil {
+			return fmt.Errorf("add %s.%s: %w", table, col[0], err)
+		}
+	}
+	return nil
+}
+
+// migrateFlamegraphs converts the original flamegraphs table, which stored
+// plain folded stacks and rendered SVGs, to gzip-compressed stacks.
+func migrateFlamegraphs(tx *sql.Tx) error {
+	var tableName string
+	err := tx.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name='flamegraphs'`).Scan(&tableName)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	hasOldSchema, err := checkOldFlamegraphSchema(tx)
+	if err != nil {
+		return err
+	}
+	if !hasOldSchema {
+		return nil
+	}
+
+	fmt.Println("Migrating flamegraphs table to compressed format...")
+
+	oldData, err := readOldFlamegraphs(tx)
+	if err != nil {
+		return err
+	}
+
+	if err := performFlamegraphMigration(tx, oldData); err != nil {
+		return err
+	}
+
+	fmt.Printf("Migrated %d flamegraphs to compressed format\n", len(oldData))
+	return nil
+}
+
+func checkOldFlamegraphSchema(tx *sql.Tx) (bool, error) {
+	rows, err := tx.Query(`PRAGMA table_info(flamegraphs)`)
+	if err != nil {
+		return false, err
+	}
+
+	var hasOldSchema bool
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull, pk int
+		var dfltValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			_ = rows.Close()
+			return false, err
+		}
+		if name == "folded_stacks" || name == "svg" {
+			hasOldSchema = true
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	return hasOldSchema, nil
+}
+
+type oldFlamegraph struct {
+	id            int64
+	runID         int64
+	benchmarkName string
+	foldedStacks  string
+	samplingFreq  int
+	createdAt     string
+}
+
+func readOldFlamegraphs(tx *sql.Tx) ([]oldFlamegraph, error) {
+	rows, err := tx.Query(`SELECT id, run_id, benchmark_name, folded_stacks, sampling_freq, created_at FROM flamegraphs`)
+	if err != nil {
+		return nil, fmt.Errorf("read old flamegraphs: %w", err)
+	}
+
+	var oldData []oldFlamegraph
+	for rows.Next() {
+		var fg oldFlamegraph
+		if err := rows.Scan(&fg.id, &fg.runID, &fg.benchmarkName, &fg.foldedStacks, &fg.samplingFreq, &fg.createdAt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan old flamegraph: %w", err)
+		}
+		oldData = append(oldData, fg)
+	}
+	_ = rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read old flamegraphs: %w", err)
+	}
+	return oldData, nil
+}
+
+func performFlamegraphMigration(tx *sql.Tx, oldData []oldFlamegraph) error {
+	if _, err := tx.Exec(`DROP TABLE flamegraphs`); err != nil {
+		return fmt.Errorf("drop old table: %w", err)
+	}
+
+	createSQL := `CREATE TABLE flamegraphs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+		benchmark_name TEXT NOT NULL,
+		folded_stacks_gz BLOB NOT NULL,
+		sampling_freq INTEGER NOT NULL DEFAULT 997,
+		created_at TEXT NOT NULL
+	)`
+	if _, err := tx.Exec(createSQL); err != nil {
+		return fmt.Errorf("create new table: %w", err)
+	}
+
+	if _, err := tx.Exec(`CREATE UNIQUE INDEX idx_flamegraphs_run_benchmark ON flamegraphs(run_id, benchmark_name)`); err != nil {
+		return fmt.Errorf("create index: %w", err)
+	}
+
+	for _, fg := range oldData {
+		compressed, err := gzipCompress([]byte(fg.foldedStacks))
+		if err != nil {
+			return fmt.Errorf("compress flamegraph %s: %w", fg.benchmarkName, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO flamegraphs (run_id, benchmark_name, folded_stacks_gz, sampling_freq, created_at) VALUES (?, ?, ?, ?, ?)`,
+			fg.runID, fg.benchmarkName, compressed, fg.samplingFreq, fg.createdAt); err != nil {
+			return fmt.Errorf("insert flamegraph %s: %w", fg.benchmarkName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMigrateNewDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.db")
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	version, err := database.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Fatalf("version = %d, want %d", version, LatestSchemaVersion())
+	}
+	_ = database.Close()
+
+	database, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	applied, err := database.AppliedMigrations()
+	if err != nil {
+		t.Fatalf("AppliedMigrations: %v", err)
+	}
+	if len(applied) != LatestSchemaVersion() {
+		t.Fatalf("applied %d migrations, want %d", len(applied), LatestSchemaVersion())
+	}
+	pending, err := database.PendingMigrations()
+	if err != nil {
+		t.Fatalf("PendingMigrations: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("pending = %v after reopening", pending)
+	}
+}
+
+func TestMigrateUnversionedDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.db")
+
+	// The layout of a database from before runs had statuses, memory stats
+	// were aggregated and flamegraphs were compressed.
+	legacy, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE runs (id INTEGER PRIMARY KEY AUTOINCREMENT, commit_hash TEXT NOT NULL, commit_hash_full TEXT, commit_message TEXT, commit_date TEXT, branch TEXT, run_date TEXT NOT NULL, machine_id TEXT, notes TEXT, zig_optimize TEXT DEFAULT 'ReleaseFast')`,
+		`CREATE TABLE results (id INTEGER PRIMARY KEY AUTOINCREMENT, run_id INTEGER NOT NULL, category TEXT NOT NULL, name TEXT NOT NULL, min_ns INTEGER NOT NULL, avg_ns INTEGER NOT NULL, max_ns INTEGER NOT NULL, total_ns INTEGER NOT NULL, iterations INTEGER NOT NULL)`,
+		`CREATE TABLE mem_stats (id INTEGER PRIMARY KEY AUTOINCREMENT, result_id INTEGER NOT NULL, stat_name TEXT NOT NULL, bytes INTEGER NOT NULL)`,
+		`CREATE TABLE flamegraphs (id INTEGER PRIMARY KEY AUTOINCREMENT, run_id INTEGER NOT NULL, benchmark_name TEXT NOT NULL, folded_stacks TEXT NOT NULL, svg TEXT, sampling_freq INTEGER NOT NULL DEFAULT 997, created_at TEXT NOT NULL)`,
+		`INSERT INTO runs (commit_hash, run_date) VALUES ('abc1234', '2024-01-01T00:00:00Z')`,
+		`INSERT INTO runs (commit_hash, run_date, zig_optimize) VALUES ('def5678', '2024-01-02T00:00:00Z', 'command')`,
+		`INSERT INTO flamegraphs (run_id, benchmark_name, folded_stacks, created_at) VALUES (1, 'render', 'main;draw 10', '2024-01-01T00:00:00Z')`,
+	} {
+		if _, err := legacy.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = legacy.Close()
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	run, err := database.GetRun(1)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if run.Status != RunComplete {
+		t.Fatalf("status = %q, want %q", run.Status, RunComplete)
+	}
+	if run.Harness != "zig" || run.ZigOptimize != "ReleaseFast" {
+		t.Fatalf("run 1: harness = %q, optimize = %q", run.Harness, run.ZigOptimize)
+	}
+	command, err := database.GetRun(2)
+	if err != nil {
+		t.Fatalf("GetRun: %v", err)
+	}
+	if command.Harness != "command" || command.ZigOptimize != "" {
+		t.Fatalf("run 2: harness = %q, optimize = %q, want the harness moved out of zig_optimize", command.Harness, command.ZigOptimize)
+	}
+	fg, err := database.GetFlamegraph(1, "render")
+	if err != nil {
+		t.Fatalf("GetFlamegraph: %v", err)
+	}
+	if fg.FoldedStacks != "main;draw 10" {
+		t.Fatalf("folded stacks = %q", fg.FoldedStacks)
+	}
+	if err := database.InsertMemStat(&MemStat{ResultID: 1, StatName: "peak_bytes", Bytes: 10, MinBytes: 8, MaxBytes: 12, SampleCount: 2}); err != nil {
+		t.Fatalf("InsertMemStat: %v", err)
+	}
+	if version, _ := database.SchemaVersion(); version != LatestSchemaVersion() {
+		t.Fatalf("version = %d, want %d", version, LatestSchemaVersion())
+	}
+}
+
+func TestOpenRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.db")
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := database.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', '')`, LatestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	_ = database.Close()
+
+	if _, err := Open(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Open error = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+// TestSchemaFileMatchesMigrations keeps the documented schema.sql in sync
+// with what the migrations actually create.
+func TestSchemaFileMatchesMigrations(t *testing.T) {
+	dir := t.TempDir()
+
+	migrated, err := Open(filepath.Join(dir, "migrated.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = migrated.Close() }()
+
+	schema, err := os.ReadFile("../../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented, err := sql.Open("sqlite", filepath.Join(dir, "documented.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = documented.Close() }()
+	if _, err := documented.Exec(string(schema)); err != nil {
+		t.Fatalf("apply schema.sql: %v", err)
+	}
+
+	want := describeSchema(t, migrated.DB)
+	got := describeSchema(t, documented)
+	for _, line := range want {
+		if !slices.Contains(got, line) {
+			t.Errorf("schema.sql lacks %s", line)
+		}
+	}
+	for _, line := range got {
+		if !slices.Contains(want, line) {
+			t.Errorf("schema.sql has %s, which no migration creates", line)
+		}
+	}
+}
+
+// describeSchema lists every table column, index and view of a database as
+// one comparable line each.
+func describeSchema(t *testing.T, database *sql.DB) []string {
+	t.Helper()
+
+	rows, err := database.Query(`SELECT type, name, tbl_name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type object struct{ kind, name, table string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.kind, &o.name, &o.table); err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, o)
+	}
+	_ = rows.Close()
+
+	var lines []string
+	for _, o := range objects {
+		switch o.kind {
+		case "table":
+			cols, err := database.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, o.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for cols.Next() {
+				var cid, notNull, pk int
+				var name, colType string
+				var dflt sql.NullString
+				if err := cols.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+					t.Fatal(err)
+				}
+				lines = append(lines, fmt.Sprintf("column %s.%s %s notnull=%d default=%s pk=%d",
+					o.name, name, strings.ToUpper(colType), notNull, dflt.String, pk))
+			}
+			_ = cols.Close()
+		case "index":
+			cols, err := database.Query(fmt.Sprintf(`PRAGMA index_info(%s)`, o.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for cols.Next() {
+				var seqno, cid int
+				var name string
+				if err := cols.Scan(&seqno, &cid, &name); err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, name)
+			}
+			_ = cols.Close()
+			lines = append(lines, fmt.Sprintf("index %s ON %s(%s)", o.name, o.table, strings.Join(names, ", ")))
+		default:
+			lines = append(lines, fmt.Sprintf("%s %s", o.kind, o.name))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,197 @@
+-- Baseline: the schema as of the first versioned release. Databases created
+-- before schema_migrations existed are brought to it by upgradeUnversioned,
+-- so every statement here must tolerate objects that already exist.
+
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    commit_hash TEXT NOT NULL,
+    commit_hash_full TEXT,
+    commit_message TEXT,
+    commit_date TEXT,
+    branch TEXT,
+    run_date TEXT NOT NULL,
+    machine_id TEXT,
+    notes TEXT,
+    zig_optimize TEXT DEFAULT 'ReleaseFast',
+    status TEXT NOT NULL DEFAULT 'complete',
+    failure_reason TEXT,
+    commit_seq INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_runs_commit ON runs(commit_hash);
+CREATE INDEX IF NOT EXISTS idx_runs_commit_seq ON runs(commit_seq);
+CREATE INDEX IF NOT EXISTS idx_runs_commit_full_status ON runs(commit_hash_full, status);
+CREATE INDEX IF NOT EXISTS idx_runs_date ON runs(run_date);
+CREATE INDEX IF NOT EXISTS idx_runs_branch ON runs(branch);
+
+CREATE TABLE IF NOT EXISTS run_environment (
+    run_id INTEGER PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL,
+    cpu_model TEXT,
+    cpu_cores INTEGER,
+    kernel_version TEXT,
+    cpu_governor TEXT,
+    zig_version TEXT,
+    go_version TEXT,
+    bench_version TEXT,
+    perf_event_paranoid INTEGER,
+    profiling_allowed INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_run_environment_fingerprint ON run_environment(fingerprint);
+
+CREATE TABLE IF NOT EXISTS run_commit (
+    run_id INTEGER PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    author_name TEXT,
+    author_email TEXT,
+    body TEXT,
+    parents TEXT,
+    tags TEXT,
+    diff_base TEXT
+);
+
+CREATE TABLE IF NOT EXISTS run_changed_files (
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    additions INTEGER,
+    deletions INTEGER,
+    PRIMARY KEY (run_id, path)
+);
+
+CREATE TABLE IF NOT EXISTS results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    name TEXT NOT NULL,
+    min_ns INTEGER NOT NULL,
+    avg_ns INTEGER NOT NULL,
+    max_ns INTEGER NOT NULL,
+    std_dev_ns INTEGER NOT NULL DEFAULT 0,
+    p50_ns INTEGER NOT NULL DEFAULT 0,
+    p95_ns INTEGER NOT NULL DEFAULT 0,
+    p99_ns INTEGER NOT NULL DEFAULT 0,
+    total_ns INTEGER NOT NULL,
+    iterations INTEGER NOT NULL,
+    sample_count INTEGER NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS idx_results_run ON results(run_id);
+CREATE INDEX IF NOT EXISTS idx_results_name ON results(name);
+CREATE INDEX IF NOT EXISTS idx_results_category ON results(category);
+
+CREATE TABLE IF NOT EXISTS mem_stats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    stat_name TEXT NOT NULL,
+    bytes INTEGER NOT NULL,
+    min_bytes INTEGER,
+    max_bytes INTEGER,
+    sample_count INTEGER NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS idx_mem_stats_result ON mem_stats(result_id);
+
+CREATE TABLE IF NOT EXISTS samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    sample_index INTEGER NOT NULL,
+    min_ns INTEGER NOT NULL,
+    avg_ns INTEGER NOT NULL,
+    max_ns INTEGER NOT NULL,
+    total_ns INTEGER NOT NULL,
+    iterations INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_samples_result ON samples(result_id);
+
+CREATE TABLE IF NOT EXISTS run_pairs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    base_run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    head_run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    rounds INTEGER NOT NULL,
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_run_pairs_base ON run_pairs(base_run_id);
+CREATE INDEX IF NOT EXISTS idx_run_pairs_head ON run_pairs(head_run_id);
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    commit_ref TEXT NOT NULL,
+    config TEXT NOT NULL DEFAULT '{}',
+    priority INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'queued',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_id INTEGER REFERENCES runs(id) ON DELETE SET NULL,
+    error TEXT,
+    created_at TEXT NOT NULL,
+    started_at TEXT,
+    finished_at TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_status_priority ON jobs(status, priority DESC, id);
+
+CREATE TABLE IF NOT EXISTS flamegraphs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    benchmark_name TEXT NOT NULL,
+    folded_stacks_gz BLOB NOT NULL,
+    sampling_freq INTEGER NOT NULL DEFAULT 997,
+    created_at TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_flamegraphs_run_benchmark ON flamegraphs(run_id, benchmark_name);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    data_blob BLOB NOT NULL,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    created_at TEXT NOT NULL,
+    UNIQUE(result_id, kind)
+);
+CREATE INDEX IF NOT EXISTS idx_artifacts_result_kind ON artifacts(result_id, kind);
+
+CREATE TABLE IF NOT EXISTS run_artifacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    data_gz BLOB NOT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE(run_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS run_metrics (
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    value INTEGER NOT NULL,
+    unit TEXT NOT NULL,
+    PRIMARY KEY (run_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_run_metrics_name ON run_metrics(name);
+
+CREATE VIEW IF NOT EXISTS results_with_run AS
+SELECT
+    r.id as result_id,
+    r.category,
+    r.name,
+    r.min_ns,
+    r.avg_ns,
+    r.max_ns,
+    r.std_dev_ns,
+    r.p50_ns,
+    r.p95_ns,
+    r.p99_ns,
+    r.total_ns,
+    r.iterations,
+    r.sample_count,
+    ru.id as run_id,
+    ru.commit_hash,
+    ru.commit_hash_full,
+    ru.commit_message,
+    ru.commit_date,
+    ru.branch,
+    ru.run_date,
+    ru.machine_id,
+    ru.notes
+FROM results r
+JOIN runs ru ON r.run_id = ru.id;
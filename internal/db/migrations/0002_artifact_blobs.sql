@@ -0,0 +1,5 @@
+-- Artifacts moved to the blob store keep an empty data_blob and reference the
+-- payload by its sha256 and size.
+ALTER TABLE artifacts ADD COLUMN blob_sha256 TEXT;
+ALTER TABLE artifacts ADD COLUMN blob_size INTEGER;
+CREATE INDEX idx_artifacts_blob_sha256 ON artifacts(blob_sha256);
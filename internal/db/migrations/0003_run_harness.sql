@@ -0,0 +1,4 @@
+-- The harness a run was recorded with. Runs of the command and bun harnesses
+-- used to store the harness name in zig_optimize.
+ALTER TABLE runs ADD COLUMN harness TEXT NOT NULL DEFAULT 'zig';
+UPDATE runs SET harness = zig_optimize, zig_optimize = '' WHERE zig_optimize IN ('command', 'bun');
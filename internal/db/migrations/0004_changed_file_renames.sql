@@ -0,0 +1,3 @@
+-- The path a changed file was renamed from. Diffstats used to list a rename
+-- as a deleted and an added file.
+ALTER TABLE run_changed_files ADD COLUMN old_path TEXT;
@@ -0,0 +1,3 @@
+-- When the worker running a job last reported it alive. Jobs are only
+-- requeued once their heartbeat, not their start, is older than the lease.
+ALTER TABLE jobs ADD COLUMN heartbeat_at TEXT;
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// generateDB fills a new database with runs results per run, each result
+// with two memory stats, and returns it with the run IDs oldest first.
+func generateDB(tb testing.TB, runs, results int) (*DB, []int64) {
+	tb.Helper()
+
+	database, err := Open(filepath.Join(tb.TempDir(), "bench.db"))
+	if err != nil {
+		tb.Fatalf("open: %v", err)
+	}
+	tb.Cleanup(func() { _ = database.Close() })
+
+	runIDs := make([]int64, 0, runs)
+	err = database.InTx(func(tx *Tx) error {
+		for i := range runs {
+			runID, err := tx.InsertRun(&Run{
+				CommitHash: fmt.Sprintf("%07x", i),
+				Branch:     "main",
+				RunDate:    fmt.Sprintf("2024-01-01T00:00:%02dZ", i%60),
+				CommitSeq:  int64(i + 1),
+			})
+			if err != nil {
+				return err
+			}
+			runIDs = append(runIDs, runID)
+
+			for j := range results {
+				resultID, err := tx.InsertResultWithSamples(&Result{
+					RunID:       runID,
+					Category:    fmt.Sprintf("category %d", j%5),
+					Name:        fmt.Sprintf("bench %d", j),
+					AvgNs:       int64(1000 + j),
+					SampleCount: 3,
+				}, nil)
+				if err != nil {
+					return err
+				}
+				for _, stat := range []string{"peak_bytes", "alloc_bytes"} {
+					if err := tx.InsertMemStat(&MemStat{ResultID: resultID, StatName: stat, Bytes: int64(j), SampleCount: 3}); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("generate: %v", err)
+	}
+	return database, runIDs
+}
+
+func TestBatchedQueries(t *testing.T) {
+	database, runIDs := generateDB(t, 4, 3)
+
+	empty, err := database.InsertRun(&Run{CommitHash: "empty", RunDate: "2024-01-02T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts, err := database.CountResultsForRuns(append(runIDs, empty))
+	if err != nil {
+		t.Fatalf("CountResultsForRuns: %v", err)
+	}
+	for _, id := range runIDs {
+		if counts[id] != 3 {
+			t.Errorf("run %d: count = %d, want 3", id, counts[id])
+		}
+	}
+	if counts[empty] != 0 {
+		t.Errorf("empty run: count = %d, want 0", counts[empty])
+	}
+
+	results, err := database.GetResultsInRuns(runIDs[1:3])
+	if err != nil {
+		t.Fatalf("GetResultsInRuns: %v", err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want 6", len(results))
+	}
+	for i, r := range results {
+		wantRun := runIDs[1+i/3]
+		if r.RunID != wantRun {
+			t.Errorf("result %d: run = %d, want %d", i, r.RunID, wantRun)
+		}
+		if len(r.MemStats) != 2 || r.MemStats[0].ResultID != r.ID {
+			t.Errorf("result %d: mem stats = %+v", i, r.MemStats)
+		}
+	}
+}
+
+// The benchmarks run against a database the size of a long-lived
+// deployment: thousands of runs with dozens of benchmarks each.
+const (
+	benchRuns       = 2000
+	benchResults    = 30
+	benchListLimit  = 100
+	benchWindowSize = 50
+)
+
+func BenchmarkCountResultsForRuns(b *testing.B) {
+	database, runIDs := generateDB(b, benchRuns, benchResults)
+	listed := runIDs[len(runIDs)-benchListLimit:]
+
+	for b.Loop() {
+		if _, err := database.CountResultsForRuns(listed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetResultsForRun(b *testing.B) {
+	database, runIDs := generateDB(b, benchRuns, benchResults)
+	latest := runIDs[len(runIDs)-1]
+
+	for b.Loop() {
+		if _, err := database.GetResultsForRun(latest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetResultsInRuns(b *testing.B) {
+	database, runIDs := generateDB(b, benchRuns, benchResults)
+	window := runIDs[len(runIDs)-benchWindowSize:]
+
+	for b.Loop() {
+		if _, err := database.GetResultsInRuns(window); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
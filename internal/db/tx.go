@@ -0,0 +1,72 @@
+package db
+
+import "database/sql"
+
+// execer is the part of *sql.DB and *sql.Tx the insert helpers need, so the
+// same statements serve DB and Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Tx is a write transaction with the inserts needed to store a run, so a run
+// and everything recorded with it become visible to readers at once.
+type Tx struct {
+	tx    *sql.Tx
+	blobs *BlobStore
+}
+
+// InTx runs fn in a transaction, which is committed if fn returns nil and
+// rolled back otherwise.
+func (db *DB) InTx(fn func(tx *Tx) error) error {
+	sqlTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sqlTx.Rollback() }()
+
+	if err := fn(&Tx{tx: sqlTx, blobs: db.blobs}); err != nil {
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// InsertRun inserts a run. An empty Status is stored as complete.
+func (tx *Tx) InsertRun(run *Run) (int64, error) {
+	return insertRun(tx.tx, run)
+}
+
+func (tx *Tx) InsertRunEnvironment(env *RunEnvironment) error {
+	return insertRunEnvironment(tx.tx, env)
+}
+
+func (tx *Tx) InsertCommitInfo(info *CommitInfo) error {
+	return insertCommitInfo(tx.tx, info)
+}
+
+func (tx *Tx) InsertResultWithSamples(result *Result, samples []Sample) (int64, error) {
+	return insertResultWithSamples(tx.tx, result, samples)
+}
+
+func (tx *Tx) InsertMemStat(stat *MemStat) error {
+	return insertMemStat(tx.tx, stat)
+}
+
+func (tx *Tx) InsertArtifact(a *Artifact) (int64, error) {
+	return insertArtifact(tx.tx, tx.blobs, a)
+}
+
+func (tx *Tx) PutRunMetric(m *RunMetric) error {
+	return putRunMetric(tx.tx, m)
+}
+
+func (tx *Tx) PutRunArtifact(runID int64, kind string, data []byte, createdAt string) error {
+	return putRunArtifact(tx.tx, runID, kind, data, createdAt)
+}
+
+func (tx *Tx) InsertRunPair(p *RunPair) (int64, error) {
+	return insertRunPair(tx.tx, p)
+}
+
+func (tx *Tx) SetRunStatus(id int64, status, reason string) error {
+	return setRunStatus(tx.tx, id, status, reason)
+}
@@ -0,0 +1,85 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"opentui-bench/internal/db"
+)
+
+// ExportGoBench writes the results of a run in `go test -bench` text format,
+// which benchstat reads directly. Every stored sample becomes one line;
+// results recorded before samples were kept are written as a single line.
+// Run metadata is emitted as "key: value" configuration lines.
+func ExportGoBench(w io.Writer, database *db.DB, runID int64) error {
+	run, err := database.GetRun(runID)
+	if err != nil {
+		return fmt.Errorf("get run: %w", err)
+	}
+	results, err := database.GetResultsForRun(runID)
+	if err != nil {
+		return fmt.Errorf("get results: %w", err)
+	}
+
+	bw := bufio.NewWriter(w)
+	config := [][2]string{
+		{"commit", run.CommitHashFull},
+		{"branch", run.Branch},
+		{"machine", run.MachineID},
+		{"harness", run.Harness},
+		{"optimize", run.ZigOptimize},
+	}
+	if env, err := database.GetRunEnvironment(runID); err == nil {
+		config = append(config, [2]string{"cpu", env.CPUModel})
+	}
+	for _, kv := range config {
+		if kv[1] != "" {
+			_, _ = fmt.Fprintf(bw, "%s: %s\n", kv[0], kv[1])
+		}
+	}
+
+	for _, res := range results {
+		name := goBenchName(res.Category, res.Name)
+		memCols := goBenchMemColumns(res.MemStats)
+
+		samples, err := database.GetSamplesForResult(res.ID)
+		if err != nil {
+			return fmt.Errorf("get samples for %s: %w", res.Name, err)
+		}
+		if len(samples) == 0 {
+			_, _ = fmt.Fprintf(bw, "%s\t%d\t%d ns/op%s\n", name, res.Iterations, res.AvgNs, memCols)
+			continue
+		}
+		for _, smp := range samples {
+			_, _ = fmt.Fprintf(bw, "%s\t%d\t%d ns/op%s\n", name, smp.Iterations, smp.AvgNs, memCols)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// goBenchName builds "BenchmarkCategory/Name". benchstat splits lines on
+// whitespace, so spaces inside names are replaced with underscores.
+func goBenchName(category, name string) string {
+	clean := func(s string) string {
+		return strings.Join(strings.Fields(s), "_")
+	}
+	return "Benchmark" + clean(category) + "/" + clean(name)
+}
+
+// goBenchMemColumns renders mem stats as extra metrics. A stat imported from
+// go test keeps its B/op unit; other stats use "<stat>-B" so benchstat
+// compares each one separately.
+func goBenchMemColumns(memStats []db.MemStat) string {
+	var sb strings.Builder
+	for _, ms := range memStats {
+		unit := GoBenchMemStat
+		if ms.StatName != GoBenchMemStat {
+			unit = strings.Join(strings.Fields(ms.StatName), "_") + "-B"
+		}
+		fmt.Fprintf(&sb, "\t%d %s", ms.Bytes, unit)
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package record
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestExportGoBench(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	input := strings.Join([]string{
+		`{"benchmark":"render","results":[{"name":"frame 80x24","avg_ns":1000,"iterations":10,"mem_stats":[{"name":"peak bytes","bytes":100}]}]}`,
+		`{"benchmark":"render","results":[{"name":"frame 80x24","avg_ns":1200,"iterations":10,"mem_stats":[{"name":"peak bytes","bytes":100}]}]}`,
+	}, "\n")
+	meta := RunMetadata{CommitHash: "abc1234", CommitHashFull: "abc1234def", Branch: "main", MachineID: "bench-1"}
+	runID, _, err := Record(database, strings.NewReader(input), meta)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := ExportGoBench(&out, database, runID); err != nil {
+		t.Fatalf("ExportGoBench: %v", err)
+	}
+	want := `commit: abc1234def
+branch: main
+machine: bench-1
+harness: zig
+optimize: ReleaseFast
+Benchmarkrender/frame_80x24	10	1000 ns/op	100 peak_bytes-B
+Benchmarkrender/frame_80x24	10	1200 ns/op	100 peak_bytes-B
+`
+	if out.String() != want {
+		t.Fatalf("export =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	// The export reads back as the same samples.
+	converted, _, err := Convert(out.Bytes(), FormatAuto)
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	set, err := ParseSamples(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	res := set.Aggregate(BenchmarkKey{Category: "render", Name: "frame_80x24"})
+	if res.SampleCount != 2 || res.AvgNs != 1100 || res.Iterations != 20 {
+		t.Fatalf("round trip = %+v", res)
+	}
+}
@@ -0,0 +1,265 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Format identifies a benchmark output format accepted by Convert.
+type Format string
+
+const (
+	FormatAuto      Format = "auto"
+	FormatJSONLines Format = "jsonl"
+	FormatHyperfine Format = "hyperfine"
+	FormatGoBench   Format = "gobench"
+)
+
+// GoBenchMemStat is the mem stat name B/op values from `go test -bench` are
+// stored under.
+const GoBenchMemStat = "B/op"
+
+// goBenchLine matches a result line of `go test -bench` output, e.g.
+// "BenchmarkRender/small-8   1000   1234 ns/op   64 B/op   2 allocs/op".
+var goBenchLine = regexp.MustCompile(`^(Benchmark\S+)\s+(\d+)\s+([\d.]+) ns/op(.*)$`)
+
+// goMaxProcsSuffix matches the "-8" GOMAXPROCS suffix go test appends to
+// names when GOMAXPROCS is not 1.
+var goMaxProcsSuffix = regexp.MustCompile(`-\d+$`)
+
+// ParseFormat validates a user supplied format name.
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(name); f {
+	case "", FormatAuto:
+		return FormatAuto, nil
+	case FormatJSONLines, FormatHyperfine, FormatGoBench:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format: %s", name)
+	}
+}
+
+// DetectFormat guesses the format of data. Anything unrecognised is treated
+// as native JSON lines.
+func DetectFormat(data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var export hyperfineExport
+		if json.Unmarshal(trimmed, &export) == nil && len(export.Results) > 0 && export.Results[0].Command != "" {
+			return FormatHyperfine
+		}
+		return FormatJSONLines
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	for scanner.Scan() {
+		if goBenchLine.MatchString(strings.TrimSpace(scanner.Text())) {
+			return FormatGoBench
+		}
+	}
+	return FormatJSONLines
+}
+
+// Convert rewrites data in the given format as native benchmark JSON lines,
+// one line per sample, so it can be passed to Record. FormatAuto detects the
+// format first; the format actually used is returned.
+func Convert(data []byte, format Format) ([]byte, Format, error) {
+	if format == "" || format == FormatAuto {
+		format = DetectFormat(data)
+	}
+
+	var out []byte
+	var err error
+	switch format {
+	case FormatJSONLines:
+		return data, format, nil
+	case FormatHyperfine:
+		out, err = convertHyperfine(data)
+	case FormatGoBench:
+		out, err = convertGoBench(data)
+	default:
+		return nil, format, fmt.Errorf("unknown format: %s", format)
+	}
+	if err != nil {
+		return nil, format, fmt.Errorf("parse %s output: %w", format, err)
+	}
+	return out, format, nil
+}
+
+type hyperfineExport struct {
+	Results []hyperfineResult `json:"results"`
+}
+
+// hyperfineResult is one command of `hyperfine --export-json`. All times are
+// in seconds.
+type hyperfineResult struct {
+	Command string    `json:"command"`
+	Mean    float64   `json:"mean"`
+	Stddev  float64   `json:"stddev"`
+	Min     float64   `json:"min"`
+	Max     float64   `json:"max"`
+	Times   []float64 `json:"times"`
+}
+
+// convertHyperfine emits one sample per timed hyperfine run, so stddev and
+// percentiles are computed from the individual runs. Exports without times
+// fall back to a single sample of the summary values, including stddev.
+func convertHyperfine(data []byte) ([]byte, error) {
+	var export hyperfineExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, res := range export.Results {
+		if len(res.Times) == 0 {
+			if err := writeSample(&buf, "hyperfine", ResultJSON{
+				Name:       res.Command,
+				MinNs:      secondsToNs(res.Min),
+				AvgNs:      secondsToNs(res.Mean),
+				MaxNs:      secondsToNs(res.Max),
+				StdDevNs:   secondsToNs(res.Stddev),
+				TotalNs:    secondsToNs(res.Mean),
+				Iterations: 1,
+			}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, t := range res.Times {
+			ns := secondsToNs(t)
+			if err := writeSample(&buf, "hyperfine", ResultJSON{
+				Name:       res.Command,
+				MinNs:      ns,
+				AvgNs:      ns,
+				MaxNs:      ns,
+				TotalNs:    ns,
+				Iterations: 1,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// convertGoBench maps each `go test -bench` result line to one sample.
+// Repeated lines for the same benchmark (-count=N) become extra samples.
+// "BenchmarkCategory/Name" is split into category and name; benchmarks
+// without a sub-benchmark use the package name as category. Results have
+// nanosecond resolution, so sub-nanosecond ns/op values are stored as 1ns.
+func convertGoBench(data []byte) ([]byte, error) {
+	type resultLine struct {
+		pkg  string
+		line string
+		m    []string
+	}
+	var lines []resultLine
+	var names []string
+	pkg := "go"
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "pkg:"); ok {
+			pkg = strings.TrimSpace(rest)
+			continue
+		}
+		if m := goBenchLine.FindStringSubmatch(line); m != nil {
+			lines = append(lines, resultLine{pkg: pkg, line: line, m: m})
+			names = append(names, m[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	procsSuffix := goMaxProcsSuffixOf(names)
+	var buf bytes.Buffer
+	for _, l := range lines {
+		line, m := l.line, l.m
+		fullName := strings.TrimSuffix(strings.TrimPrefix(m[1], "Benchmark"), procsSuffix)
+		category, name, ok := strings.Cut(fullName, "/")
+		if !ok {
+			category, name = l.pkg, fullName
+		}
+
+		iterations, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("iterations in %q: %w", line, err)
+		}
+		nsPerOp, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("ns/op in %q: %w", line, err)
+		}
+		ns := max(int64(math.Round(nsPerOp)), 1)
+
+		res := ResultJSON{
+			Name:       name,
+			MinNs:      ns,
+			AvgNs:      ns,
+			MaxNs:      ns,
+			TotalNs:    int64(math.Round(nsPerOp * float64(iterations))),
+			Iterations: iterations,
+		}
+		if bytesPerOp, ok := goBenchMetric(m[4], "B/op"); ok {
+			res.MemStats = []MemStatJSON{{Name: GoBenchMemStat, Bytes: int64(math.Round(bytesPerOp))}}
+		}
+
+		if err := writeSample(&buf, category, res); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// goMaxProcsSuffixOf returns the GOMAXPROCS suffix go test appended to the
+// benchmark names, or "" if there is none. go test appends the same suffix
+// to every name of a run, so a suffix is only stripped when all names share
+// it; otherwise a "-2" is part of the name, e.g. with GOMAXPROCS=1.
+func goMaxProcsSuffixOf(names []string) string {
+	var suffix string
+	for i, name := range names {
+		s := goMaxProcsSuffix.FindString(name)
+		if s == "" || (i > 0 && s != suffix) {
+			return ""
+		}
+		suffix = s
+	}
+	return suffix
+}
+
+// goBenchMetric finds "<value> <unit>" among the extra metrics of a result line.
+func goBenchMetric(metrics, unit string) (float64, bool) {
+	fields := strings.Fields(metrics)
+	for i := 1; i < len(fields); i++ {
+		if fields[i] != unit {
+			continue
+		}
+		v, err := strconv.ParseFloat(fields[i-1], 64)
+		return v, err == nil
+	}
+	return 0, false
+}
+
+func writeSample(buf *bytes.Buffer, category string, res ResultJSON) error {
+	line, err := json.Marshal(BenchmarkJSON{Benchmark: category, Results: []ResultJSON{res}})
+	if err != nil {
+		return err
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+	return nil
+}
+
+func secondsToNs(s float64) int64 {
+	return int64(math.Round(s * 1e9))
+}
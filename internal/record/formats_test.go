@@ -0,0 +1,159 @@
+package record
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+const hyperfineSample = `{
+  "results": [
+    {
+      "command": "opentui render",
+      "mean": 0.002,
+      "stddev": 0.001,
+      "min": 0.001,
+      "max": 0.003,
+      "times": [0.001, 0.002, 0.003]
+    }
+  ]
+}`
+
+const goBenchSample = `goos: linux
+goarch: amd64
+pkg: example.com/tui
+BenchmarkRender/small-8   	    1000	      1500 ns/op	      64 B/op	       2 allocs/op
+BenchmarkRender/small-8   	    1000	      2500 ns/op	      64 B/op	       2 allocs/op
+BenchmarkParse-8          	  500000	         0.25 ns/op
+PASS
+`
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want Format
+	}{
+		{"hyperfine", hyperfineSample, FormatHyperfine},
+		{"gobench", goBenchSample, FormatGoBench},
+		{"jsonl", `{"benchmark":"a","results":[{"name":"b","avg_ns":1}]}`, FormatJSONLines},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectFormat([]byte(tc.data)); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Run("hyperfine times become samples", func(t *testing.T) {
+		out, _, err := Convert([]byte(hyperfineSample), FormatAuto)
+		if err != nil {
+			t.Fatalf("convert: %v", err)
+		}
+		set, err := ParseSamples(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		key := BenchmarkKey{Category: "hyperfine", Name: "opentui render"}
+		res := set.Aggregate(key)
+		if res.SampleCount != 3 || res.MinNs != 1_000_000 || res.AvgNs != 2_000_000 || res.MaxNs != 3_000_000 || res.StdDevNs != 1_000_000 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+	})
+
+	t.Run("gobench splits category and repeats", func(t *testing.T) {
+		out, _, err := Convert([]byte(goBenchSample), FormatAuto)
+		if err != nil {
+			t.Fatalf("convert: %v", err)
+		}
+		set, err := ParseSamples(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		if len(set.Keys) != 2 {
+			t.Fatalf("expected 2 benchmarks, got %v", set.Keys)
+		}
+
+		render := set.Aggregate(BenchmarkKey{Category: "Render", Name: "small"})
+		if render.SampleCount != 2 || render.AvgNs != 2000 || render.Iterations != 2000 {
+			t.Fatalf("unexpected render result: %+v", render)
+		}
+		if ms := set.samples[BenchmarkKey{Category: "Render", Name: "small"}][0].memStats; len(ms) != 1 || ms[0].Bytes != 64 {
+			t.Fatalf("expected B/op mem stat, got %+v", ms)
+		}
+
+		parse := set.Aggregate(BenchmarkKey{Category: "example.com/tui", Name: "Parse"})
+		if parse.SampleCount != 1 || parse.Iterations != 500000 {
+			t.Fatalf("unexpected parse result: %+v", parse)
+		}
+	})
+	t.Run("hyperfine summary keeps stddev", func(t *testing.T) {
+		summary := `{"results": [{"command": "opentui render", "mean": 0.002, "stddev": 0.0005, "min": 0.001, "max": 0.003}]}`
+		out, _, err := Convert([]byte(summary), FormatHyperfine)
+		if err != nil {
+			t.Fatalf("convert: %v", err)
+		}
+		set, err := ParseSamples(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		res := set.Aggregate(BenchmarkKey{Category: "hyperfine", Name: "opentui render"})
+		if res.SampleCount != 1 || res.AvgNs != 2_000_000 || res.StdDevNs != 500_000 {
+			t.Fatalf("unexpected result: %+v", res)
+		}
+	})
+
+	t.Run("gobench sub-nanosecond results", func(t *testing.T) {
+		out, _, err := Convert([]byte(goBenchSample), FormatGoBench)
+		if err != nil {
+			t.Fatalf("convert: %v", err)
+		}
+		set, err := ParseSamples(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		parse := set.Aggregate(BenchmarkKey{Category: "example.com/tui", Name: "Parse"})
+		if parse.AvgNs != 1 || parse.MinNs != 1 || parse.TotalNs != 125000 {
+			t.Fatalf("unexpected parse result: %+v", parse)
+		}
+	})
+}
+
+func TestConvertGoBenchNames(t *testing.T) {
+	cases := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{"shared suffix", []string{"BenchmarkParse-8", "BenchmarkRender/small-8"}, []string{"Parse", "small"}},
+		{"name ending in a number", []string{"BenchmarkParse-2-8", "BenchmarkRender/small-8"}, []string{"Parse-2", "small"}},
+		{"GOMAXPROCS=1", []string{"BenchmarkParse-2", "BenchmarkRender/small"}, []string{"Parse-2", "small"}},
+		{"different suffixes", []string{"BenchmarkParse-2", "BenchmarkParse-4"}, []string{"Parse-2", "Parse-4"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var input bytes.Buffer
+			for _, name := range tc.lines {
+				input.WriteString(name + "   1000   1500 ns/op\n")
+			}
+			out, _, err := Convert(input.Bytes(), FormatGoBench)
+			if err != nil {
+				t.Fatalf("convert: %v", err)
+			}
+			set, err := ParseSamples(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			var names []string
+			for _, key := range set.Keys {
+				names = append(names, key.Name)
+			}
+			if !slices.Equal(names, tc.want) {
+				t.Fatalf("names = %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
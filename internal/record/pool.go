@@ -0,0 +1,199 @@
+package record
+
+import (
+	"math"
+
+	"opentui-bench/internal/db"
+	"opentui-bench/internal/stats"
+)
+
+// PooledResult is a benchmark result pooled across several runs of one
+// commit, together with the per-run results it was pooled from. The pooled
+// SampleCount is the effective number of independent samples: the SEM and
+// CIs derived from StdDevNs and SampleCount match the pooled SEM of
+// stats.PoolRuns, which grows when the runs disagree. The per-run results
+// keep their actual counts.
+type PooledResult struct {
+	db.Result
+	Runs []db.Result
+}
+
+// PoolResults pools benchmark results across runs of one commit, given as one
+// result list per run. Benchmarks are matched by category and name and keep
+// the order and IDs of the first run that has them. Mean and standard
+// deviation are pooled with stats.PoolRuns and min/max span all runs;
+// percentiles are sample-weighted averages of the per-run percentiles and
+// therefore only approximate.
+func PoolResults(runs [][]db.Result) []PooledResult {
+	type key struct{ category, name string }
+	index := make(map[key]int)
+
+	var pooled []PooledResult
+	for _, results := range runs {
+		for _, res := range results {
+			k := key{res.Category, res.Name}
+			i, ok := index[k]
+			if !ok {
+				i = len(pooled)
+				index[k] = i
+				pooled = append(pooled, PooledResult{})
+			}
+			pooled[i].Runs = append(pooled[i].Runs, res)
+		}
+	}
+
+	for i := range pooled {
+		pooled[i].Result = poolResult(pooled[i].Runs)
+	}
+	return pooled
+}
+
+func poolResult(results []db.Result) db.Result {
+	pooled := results[0]
+	if len(results) == 1 {
+		return pooled
+	}
+
+	runStats := make([]stats.RunStat, len(results))
+	var weight, p50, p95, p99 float64
+	for i, r := range results {
+		runStats[i] = ResultStat(r)
+		w := math.Max(float64(r.SampleCount), 1)
+		weight += w
+		p50 += w * float64(r.P50Ns)
+		p95 += w * float64(r.P95Ns)
+		p99 += w * float64(r.P99Ns)
+		if i == 0 {
+			continue
+		}
+		pooled.MinNs = min(pooled.MinNs, r.MinNs)
+		pooled.MaxNs = max(pooled.MaxNs, r.MaxNs)
+		pooled.TotalNs += r.TotalNs
+		pooled.Iterations += r.Iterations
+	}
+
+	ps := stats.PoolRuns(runStats)
+	pooled.AvgNs = int64(math.Round(ps.Mean))
+	pooled.StdDevNs = int64(math.Round(ps.StdDev))
+	pooled.SampleCount = effectiveSampleCount(ps)
+	pooled.P50Ns = int64(math.Round(p50 / weight))
+	pooled.P95Ns = int64(math.Round(p95 / weight))
+	pooled.P99Ns = int64(math.Round(p99 / weight))
+	pooled.MemStats = poolMemStats(results)
+	return pooled
+}
+
+// effectiveSampleCount is the sample count n for which StdDev/sqrt(n) is the
+// pooled SEM, at least 2 so a CI can still be computed and at most the
+// number of samples.
+func effectiveSampleCount(ps stats.RunStat) int64 {
+	if ps.Sem <= 0 || ps.SampleCount < 2 {
+		return ps.SampleCount
+	}
+	n := int64(math.Round(math.Pow(ps.StdDev/ps.Sem, 2)))
+	return min(max(n, 2), ps.SampleCount)
+}
+
+// poolMemStats combines the memory stats of results by name: averages are
+// weighted by sample count and min/max span all runs.
+func poolMemStats(results []db.Result) []db.MemStat {
+	var pooled []db.MemStat
+	index := make(map[string]int)
+	sums := make(map[string]float64)
+	for _, r := range results {
+		for _, ms := range r.MemStats {
+			n := max(ms.SampleCount, 1)
+			i, ok := index[ms.StatName]
+			if !ok {
+				index[ms.StatName] = len(pooled)
+				pooled = append(pooled, db.MemStat{
+					ResultID: ms.ResultID,
+					StatName: ms.StatName,
+					MinBytes: ms.MinBytes,
+					MaxBytes: ms.MaxBytes,
+				})
+				i = len(pooled) - 1
+			}
+			pooled[i].MinBytes = min(pooled[i].MinBytes, ms.MinBytes)
+			pooled[i].MaxBytes = max(pooled[i].MaxBytes, ms.MaxBytes)
+			pooled[i].SampleCount += n
+			sums[ms.StatName] += float64(n) * float64(ms.Bytes)
+		}
+	}
+	for i := range pooled {
+		pooled[i].Bytes = int64(math.Round(sums[pooled[i].StatName] / float64(pooled[i].SampleCount)))
+	}
+	return pooled
+}
+
+// ResultStat summarizes a stored result for the stats package.
+func ResultStat(r db.Result) stats.RunStat {
+	sem := float64(0)
+	if r.SampleCount >= 2 {
+		sem = float64(r.StdDevNs) / math.Sqrt(float64(r.SampleCount))
+	}
+	return stats.RunStat{
+		RunID:       r.RunID,
+		Mean:        float64(r.AvgNs),
+		Sem:         sem,
+		SampleCount: r.SampleCount,
+		StdDev:      float64(r.StdDevNs),
+	}
+}
+
+// LoadPooledResults returns the results of run. With pool, results are
+// pooled across every complete run of the run's commit on the same machine
+// and optimize mode, and the runs used are returned alongside.
+func LoadPooledResults(database *db.DB, run *db.Run, pool bool) ([]PooledResult, []db.Run, error) {
+	runs := []db.Run{*run}
+	if pool {
+		poolable, err := database.GetPoolableRuns(run)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(poolable) > 0 {
+			runs = poolable
+		}
+	}
+
+	perRun := make([][]db.Result, len(runs))
+	for i, r := range runs {
+		results, err := database.GetResultsForRun(r.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		perRun[i] = results
+	}
+	return PoolResults(perRun), runs, nil
+}
+
+// PooledTrendPoint is a trend point whose result may be pooled from several
+// runs. Run is the newest of them.
+type PooledTrendPoint struct {
+	Run    db.Run
+	Result PooledResult
+}
+
+// PoolTrend merges trend points of runs that share a db.Run.PoolKey into
+// one point per commit, keeping history order.
+func PoolTrend(points []db.TrendPoint) []PooledTrendPoint {
+	type key struct{ pool, category, name string }
+	index := make(map[key]int)
+
+	var pooled []PooledTrendPoint
+	for _, p := range points {
+		k := key{p.Run.PoolKey(), p.Result.Category, p.Result.Name}
+		i, ok := index[k]
+		if !ok {
+			i = len(pooled)
+			index[k] = i
+			pooled = append(pooled, PooledTrendPoint{Run: p.Run})
+		}
+		pooled[i].Result.Runs = append(pooled[i].Result.Runs, p.Result)
+	}
+
+	for i := range pooled {
+		pooled[i].Result.Result = poolResult(pooled[i].Result.Runs)
+	}
+	return pooled
+}
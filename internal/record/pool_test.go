@@ -0,0 +1,60 @@
+package record
+
+import (
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestPoolResults(t *testing.T) {
+	runA := []db.Result{
+		{ID: 10, RunID: 2, Category: "render", Name: "frame", MinNs: 90, AvgNs: 100, MaxNs: 110, SampleCount: 2, P50Ns: 100},
+		{ID: 11, RunID: 2, Category: "render", Name: "only-a", AvgNs: 5, SampleCount: 1},
+	}
+	runB := []db.Result{
+		{ID: 20, RunID: 1, Category: "render", Name: "frame", MinNs: 180, AvgNs: 200, MaxNs: 220, SampleCount: 2, P50Ns: 200},
+	}
+
+	got := PoolResults([][]db.Result{runA, runB})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 pooled results, got %d", len(got))
+	}
+
+	frame := got[0]
+	if frame.ID != 10 || frame.RunID != 2 || len(frame.Runs) != 2 {
+		t.Fatalf("expected frame to keep the first run's IDs and both runs, got %+v", frame)
+	}
+	// The runs disagree completely, so their four samples count as two.
+	if frame.AvgNs != 150 || frame.SampleCount != 2 || frame.P50Ns != 150 {
+		t.Fatalf("unexpected pooled values: %+v", frame.Result)
+	}
+	if frame.MinNs != 90 || frame.MaxNs != 220 {
+		t.Fatalf("expected min/max to span both runs, got %d/%d", frame.MinNs, frame.MaxNs)
+	}
+
+	if onlyA := got[1]; onlyA.ID != 11 || onlyA.AvgNs != 5 || len(onlyA.Runs) != 1 {
+		t.Fatalf("expected unpooled result to be unchanged, got %+v", onlyA)
+	}
+}
+
+func TestPoolResultsEffectiveSampleCount(t *testing.T) {
+	// Runs that agree pool to all their samples; the SEM shrinks as usual.
+	agree := PoolResults([][]db.Result{
+		{{Category: "render", Name: "frame", AvgNs: 100, StdDevNs: 10, SampleCount: 50}},
+		{{Category: "render", Name: "frame", AvgNs: 100, StdDevNs: 10, SampleCount: 50}},
+	})[0]
+	if agree.SampleCount != 100 {
+		t.Fatalf("agreeing runs: sample count %d, want 100", agree.SampleCount)
+	}
+
+	// Runs of 100 tight samples that differ by 10 ns: the SEM derived from
+	// the pooled result must reflect the disagreement, not 200 samples.
+	disagree := PoolResults([][]db.Result{
+		{{Category: "render", Name: "frame", AvgNs: 100, StdDevNs: 1, SampleCount: 100}},
+		{{Category: "render", Name: "frame", AvgNs: 110, StdDevNs: 1, SampleCount: 100}},
+	})[0]
+	sem := ResultStat(disagree.Result).Sem
+	if sem < 3 {
+		t.Fatalf("disagreeing runs: sem %.2f ns from %d samples, want about 5 ns", sem, disagree.SampleCount)
+	}
+}
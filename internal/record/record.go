@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -19,10 +20,13 @@ type BenchmarkJSON struct {
 }
 
 type ResultJSON struct {
-	Name       string        `json:"name"`
-	MinNs      int64         `json:"min_ns"`
-	AvgNs      int64         `json:"avg_ns"`
-	MaxNs      int64         `json:"max_ns"`
+	Name  string `json:"name"`
+	MinNs int64  `json:"min_ns"`
+	AvgNs int64  `json:"avg_ns"`
+	MaxNs int64  `json:"max_ns"`
+	// StdDevNs is the spread within this sample, for tools that report only
+	// a summary. It is used when a benchmark has a single sample.
+	StdDevNs   int64         `json:"std_dev_ns,omitempty"`
 	TotalNs    int64         `json:"total_ns"`
 	Iterations int64         `json:"iterations"`
 	MemStats   []MemStatJSON `json:"mem_stats,omitempty"`
@@ -38,54 +42,54 @@ type RunMetadata struct {
 	CommitHashFull string
 	CommitMessage  string
 	CommitDate     string
+	CommitSeq      int64
 	Branch         string
 	MachineID      string
 	Notes          string
 	ZigOptimize    string
+	Harness        string
 	SampleCount    int
+	Environment    *db.RunEnvironment
+	Commit         *db.CommitInfo
 }
 
 type sample struct {
 	minNs      int64
 	avgNs      int64
 	maxNs      int64
+	stdDevNs   int64
 	totalNs    int64
 	iterations int64
 	memStats   []MemStatJSON
 }
 
-type benchmarkKey struct {
-	category string
-	name     string
+// BenchmarkKey identifies a benchmark within a run.
+type BenchmarkKey struct {
+	Category string
+	Name     string
 }
 
-func Record(database *db.DB, reader io.Reader, meta RunMetadata) (int64, int, error) {
-	run := &db.Run{
-		CommitHash:     meta.CommitHash,
-		CommitHashFull: meta.CommitHashFull,
-		CommitMessage:  meta.CommitMessage,
-		CommitDate:     meta.CommitDate,
-		Branch:         meta.Branch,
-		RunDate:        time.Now().Format(time.RFC3339),
-		MachineID:      meta.MachineID,
-		Notes:          meta.Notes,
-		ZigOptimize:    meta.ZigOptimize,
-	}
+// SampleSet holds parsed benchmark samples grouped per benchmark.
+type SampleSet struct {
+	// Keys lists benchmarks in the order they first appeared in the input.
+	Keys    []BenchmarkKey
+	samples map[BenchmarkKey][]sample
+}
 
-	if run.ZigOptimize == "" {
-		run.ZigOptimize = "ReleaseFast"
-	}
+// Count returns how many samples were parsed for key.
+func (s *SampleSet) Count(key BenchmarkKey) int {
+	return len(s.samples[key])
+}
 
-	runID, err := database.InsertRun(run)
-	if err != nil {
-		return 0, 0, fmt.Errorf("insert run: %w", err)
-	}
-	cleanup := func() {
-		_ = database.DeleteRun(runID)
-	}
+// Aggregate folds the samples of key into a single result.
+func (s *SampleSet) Aggregate(key BenchmarkKey) *db.Result {
+	return aggregateSamples(key.Category, key.Name, s.samples[key])
+}
 
-	samples := make(map[benchmarkKey][]sample)
-	keyOrder := []benchmarkKey{}
+// ParseSamples reads benchmark JSON lines from reader. Every line is one
+// sample of the benchmarks it contains; non-JSON lines are skipped.
+func ParseSamples(reader io.Reader) (*SampleSet, error) {
+	set := &SampleSet{samples: make(map[BenchmarkKey][]sample)}
 
 	scanner := bufio.NewScanner(reader)
 	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
@@ -104,19 +108,19 @@ func Record(database *db.DB, reader io.Reader, meta RunMetadata) (int64, int, er
 
 		var bench BenchmarkJSON
 		if err := json.Unmarshal([]byte(trimmed), &bench); err != nil {
-			cleanup()
-			return 0, 0, fmt.Errorf("parse benchmark JSON on line %d: %w", lineNum, err)
+			return nil, fmt.Errorf("parse benchmark JSON on line %d: %w", lineNum, err)
 		}
 
 		for _, r := range bench.Results {
-			key := benchmarkKey{category: bench.Benchmark, name: r.Name}
-			if _, exists := samples[key]; !exists {
-				keyOrder = append(keyOrder, key)
+			key := BenchmarkKey{Category: bench.Benchmark, Name: r.Name}
+			if _, exists := set.samples[key]; !exists {
+				set.Keys = append(set.Keys, key)
 			}
-			samples[key] = append(samples[key], sample{
+			set.samples[key] = append(set.samples[key], sample{
 				minNs:      r.MinNs,
 				avgNs:      r.AvgNs,
 				maxNs:      r.MaxNs,
+				stdDevNs:   r.StdDevNs,
 				totalNs:    r.TotalNs,
 				iterations: r.Iterations,
 				memStats:   r.MemStats,
@@ -125,41 +129,137 @@ func Record(database *db.DB, reader io.Reader, meta RunMetadata) (int64, int, er
 	}
 
 	if err := scanner.Err(); err != nil {
-		cleanup()
-		return 0, 0, fmt.Errorf("scan input: %w", err)
+		return nil, fmt.Errorf("scan input: %w", err)
+	}
+
+	return set, nil
+}
+
+// CreateRun inserts a run for meta with the given status, dated now, together
+// with its commit metadata.
+func CreateRun(database *db.DB, meta RunMetadata, status string) (int64, error) {
+	var runID int64
+	err := database.InTx(func(tx *db.Tx) error {
+		var err error
+		runID, err = createRun(tx, meta, status)
+		return err
+	})
+	return runID, err
+}
+
+func createRun(tx *db.Tx, meta RunMetadata, status string) (int64, error) {
+	run := &db.Run{
+		CommitHash:     meta.CommitHash,
+		CommitHashFull: meta.CommitHashFull,
+		CommitMessage:  meta.CommitMessage,
+		CommitDate:     meta.CommitDate,
+		CommitSeq:      meta.CommitSeq,
+		Branch:         meta.Branch,
+		RunDate:        time.Now().Format(time.RFC3339),
+		MachineID:      meta.MachineID,
+		Notes:          meta.Notes,
+		ZigOptimize:    meta.ZigOptimize,
+		Harness:        meta.Harness,
+		Status:         status,
+	}
+
+	// Only zig runs have an optimize mode.
+	if run.ZigOptimize == "" && (run.Harness == "" || run.Harness == "zig") {
+		run.ZigOptimize = "ReleaseFast"
+	}
+
+	runID, err := tx.InsertRun(run)
+	if err != nil {
+		return 0, fmt.Errorf("insert run: %w", err)
+	}
+	if meta.Commit != nil {
+		info := *meta.Commit
+		info.RunID = runID
+		if err := tx.InsertCommitInfo(&info); err != nil {
+			return 0, fmt.Errorf("insert commit info: %w", err)
+		}
+	}
+	return runID, nil
+}
+
+// Record parses benchmark output and stores it as a new, complete run in a
+// single transaction.
+func Record(database *db.DB, reader io.Reader, meta RunMetadata) (int64, int, error) {
+	set, err := ParseSamples(reader)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	totalResults := 0
+	var runID int64
+	err = database.InTx(func(tx *db.Tx) error {
+		runID, err = StoreRun(tx, set, meta)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return runID, len(set.Keys), nil
+}
+
+// StoreRun inserts a complete run with its parsed results in tx, for callers
+// that store several runs in one transaction.
+func StoreRun(tx *db.Tx, set *SampleSet, meta RunMetadata) (int64, error) {
+	runID, err := createRun(tx, meta, db.RunComplete)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := StoreResults(tx, runID, set, meta.Environment); err != nil {
+		return 0, err
+	}
+	return runID, nil
+}
 
-	for _, key := range keyOrder {
-		sampleList := samples[key]
-		result := aggregateSamples(key.category, key.name, sampleList)
+// StoreResults stores parsed results, with their samples and memory stats,
+// for a run inserted earlier, e.g. by the runner, which tracks the run's
+// status itself. It returns the ID of each stored result.
+func StoreResults(tx *db.Tx, runID int64, set *SampleSet, env *db.RunEnvironment) (map[BenchmarkKey]int64, error) {
+	if env != nil {
+		runEnv := *env
+		runEnv.RunID = runID
+		if err := tx.InsertRunEnvironment(&runEnv); err != nil {
+			return nil, fmt.Errorf("insert run environment: %w", err)
+		}
+	}
+
+	ids := make(map[BenchmarkKey]int64, len(set.Keys))
+	for _, key := range set.Keys {
+		sampleList := set.samples[key]
+		result := aggregateSamples(key.Category, key.Name, sampleList)
 		result.RunID = runID
 
-		resultID, err := database.InsertResult(result)
+		rawSamples := make([]db.Sample, len(sampleList))
+		for i, s := range sampleList {
+			rawSamples[i] = db.Sample{
+				SampleIndex: i,
+				MinNs:       s.minNs,
+				AvgNs:       s.avgNs,
+				MaxNs:       s.maxNs,
+				TotalNs:     s.totalNs,
+				Iterations:  s.iterations,
+			}
+		}
+
+		resultID, err := tx.InsertResultWithSamples(result, rawSamples)
 		if err != nil {
-			cleanup()
-			return 0, 0, fmt.Errorf("insert result: %w", err)
+			return nil, fmt.Errorf("insert result: %w", err)
 		}
 
-		if len(sampleList) > 0 && len(sampleList[0].memStats) > 0 {
-			for _, ms := range sampleList[0].memStats {
-				stat := &db.MemStat{
-					ResultID: resultID,
-					StatName: ms.Name,
-					Bytes:    ms.Bytes,
-				}
-				if err := database.InsertMemStat(stat); err != nil {
-					cleanup()
-					return 0, 0, fmt.Errorf("insert mem stat: %w", err)
-				}
+		for _, stat := range result.MemStats {
+			stat.ResultID = resultID
+			if err := tx.InsertMemStat(&stat); err != nil {
+				return nil, fmt.Errorf("insert mem stat: %w", err)
 			}
 		}
 
-		totalResults++
+		ids[key] = resultID
 	}
 
-	return runID, totalResults, nil
+	return ids, nil
 }
 
 func aggregateSamples(category, name string, sampleList []sample) *db.Result {
@@ -180,13 +280,14 @@ func aggregateSamples(category, name string, sampleList []sample) *db.Result {
 			MinNs:       s.minNs,
 			AvgNs:       s.avgNs,
 			MaxNs:       s.maxNs,
-			StdDevNs:    0,
+			StdDevNs:    s.stdDevNs,
 			P50Ns:       s.avgNs,
 			P95Ns:       s.avgNs,
 			P99Ns:       s.avgNs,
 			TotalNs:     s.totalNs,
 			Iterations:  s.iterations,
 			SampleCount: 1,
+			MemStats:    aggregateMemStats(sampleList),
 		}
 	}
 
@@ -227,7 +328,59 @@ func aggregateSamples(category, name string, sampleList []sample) *db.Result {
 		TotalNs:     totalNs,
 		Iterations:  totalIter,
 		SampleCount: int64(n),
+		MemStats:    aggregateMemStats(sampleList),
+	}
+}
+
+// aggregateMemStats folds the memory stats of all samples into min/avg/max
+// per stat name, in the order names first appear. A stat missing from some
+// samples is aggregated over the samples that report it.
+func aggregateMemStats(sampleList []sample) []db.MemStat {
+	var names []string
+	values := make(map[string][]int64)
+	for _, s := range sampleList {
+		for _, ms := range s.memStats {
+			if _, ok := values[ms.Name]; !ok {
+				names = append(names, ms.Name)
+			}
+			values[ms.Name] = append(values[ms.Name], ms.Bytes)
+		}
+	}
+
+	stats := make([]db.MemStat, 0, len(names))
+	for _, name := range names {
+		v := values[name]
+		stats = append(stats, db.MemStat{
+			StatName:    name,
+			Bytes:       mean(v),
+			MinBytes:    slices.Min(v),
+			MaxBytes:    slices.Max(v),
+			SampleCount: int64(len(v)),
+		})
+	}
+	return stats
+}
+
+// MemStatPair is a memory statistic present on both sides of a comparison.
+type MemStatPair struct {
+	Baseline db.MemStat
+	Current  db.MemStat
+}
+
+// PairMemStats matches the memory stats of two results by name, in baseline
+// order. Stats missing on either side are skipped.
+func PairMemStats(baseline, current []db.MemStat) []MemStatPair {
+	byName := make(map[string]db.MemStat, len(current))
+	for _, ms := range current {
+		byName[ms.StatName] = ms
+	}
+	var pairs []MemStatPair
+	for _, ms := range baseline {
+		if cur, ok := byName[ms.StatName]; ok {
+			pairs = append(pairs, MemStatPair{Baseline: ms, Current: cur})
+		}
 	}
+	return pairs
 }
 
 func mean(values []int64) int64 {
@@ -0,0 +1,103 @@
+package record
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestAggregateMemStats(t *testing.T) {
+	input := strings.Join([]string{
+		`{"benchmark":"render","results":[{"name":"frame","avg_ns":10,"mem_stats":[{"name":"peak","bytes":100},{"name":"allocs","bytes":8}]}]}`,
+		`{"benchmark":"render","results":[{"name":"frame","avg_ns":12,"mem_stats":[{"name":"peak","bytes":300}]}]}`,
+		`{"benchmark":"render","results":[{"name":"frame","avg_ns":11,"mem_stats":[{"name":"peak","bytes":200}]}]}`,
+	}, "\n")
+
+	set, err := ParseSamples(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	res := set.Aggregate(BenchmarkKey{Category: "render", Name: "frame"})
+	if len(res.MemStats) != 2 {
+		t.Fatalf("expected 2 mem stats, got %+v", res.MemStats)
+	}
+
+	peak := res.MemStats[0]
+	if peak.StatName != "peak" || peak.Bytes != 200 || peak.MinBytes != 100 || peak.MaxBytes != 300 || peak.SampleCount != 3 {
+		t.Fatalf("unexpected peak stat: %+v", peak)
+	}
+	allocs := res.MemStats[1]
+	if allocs.StatName != "allocs" || allocs.Bytes != 8 || allocs.MinBytes != 8 || allocs.MaxBytes != 8 || allocs.SampleCount != 1 {
+		t.Fatalf("unexpected allocs stat: %+v", allocs)
+	}
+}
+
+func TestRecordIsAtomic(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	// Fail the last insert of the run, after its run and result rows.
+	if _, err := database.Exec(`CREATE TRIGGER fail_mem_stats BEFORE INSERT ON mem_stats BEGIN SELECT RAISE(ABORT, 'injected'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"benchmark":"render","results":[{"name":"frame","avg_ns":10,"mem_stats":[{"name":"peak","bytes":100}]}]}`
+	if _, _, err := Record(database, strings.NewReader(input), RunMetadata{CommitHash: "abc1234"}); err == nil {
+		t.Fatal("Record succeeded despite failing insert")
+	}
+
+	var runs, results int
+	if err := database.QueryRow(`SELECT (SELECT COUNT(*) FROM runs), (SELECT COUNT(*) FROM results)`).Scan(&runs, &results); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 0 || results != 0 {
+		t.Fatalf("left %d runs and %d results behind", runs, results)
+	}
+}
+
+func TestRecordStoresSamples(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	input := strings.Join([]string{
+		`{"benchmark":"render","results":[{"name":"frame","min_ns":8,"avg_ns":10,"max_ns":12,"total_ns":100,"iterations":10}]}`,
+		`{"benchmark":"render","results":[{"name":"frame","min_ns":18,"avg_ns":20,"max_ns":22,"total_ns":200,"iterations":10}]}`,
+	}, "\n")
+	runID, _, err := Record(database, strings.NewReader(input), RunMetadata{CommitHash: "abc1234"})
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	results, err := database.GetResultsForRun(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SampleCount != 2 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	samples, err := database.GetSamplesForResult(results[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("expected 2 samples, got %+v", samples)
+	}
+	for i, want := range []db.Sample{
+		{SampleIndex: 0, MinNs: 8, AvgNs: 10, MaxNs: 12, TotalNs: 100, Iterations: 10},
+		{SampleIndex: 1, MinNs: 18, AvgNs: 20, MaxNs: 22, TotalNs: 200, Iterations: 10},
+	} {
+		got := samples[i]
+		got.ID, got.ResultID = 0, 0
+		if got != want {
+			t.Errorf("sample %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"opentui-bench/internal/db"
+	"opentui-bench/internal/record"
+)
+
+// ABResult identifies the runs recorded by RunAB.
+type ABResult struct {
+	PairID    int64
+	BaseRunID int64
+	HeadRunID int64
+}
+
+// abSide is one commit of an A/B comparison, built in its own worktree.
+type abSide struct {
+	meta     record.RunMetadata
+	wt       *Worktree
+	benchBin string
+	buf      bytes.Buffer
+}
+
+// RunAB builds base and head in separate worktrees and then alternates
+// samples between the two binaries for cfg.Samples rounds, so slow machine
+// drift affects both sides equally. Both runs are recorded and linked as a
+// pair in one transaction.
+func RunAB(ctx context.Context, database *db.DB, cfg RunConfig, base, head string) (*ABResult, error) {
+	if cfg.Samples < 2 {
+		return nil, fmt.Errorf("rounds must be >= 2")
+	}
+
+	harness, err := NewHarness(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	runner := OSRunner{}
+	args := harness.FilterArgs(cfg.Filter, cfg.FilterBenchmark)
+
+	var sides [2]*abSide
+	defer func() {
+		for _, side := range sides {
+			if side == nil {
+				continue
+			}
+			if err := side.wt.Close(context.Background()); err != nil {
+				fmt.Printf("Warning: failed to remove worktree %s: %v\n", side.wt.Path, err)
+			}
+		}
+	}()
+
+	for i, commit := range []string{base, head} {
+		meta, err := ReadGitMeta(ctx, cfg.RepoPath, commit, runner)
+		if err != nil {
+			return nil, fmt.Errorf("read git meta for %s: %w", commit, err)
+		}
+		if err := ReadChangedFiles(ctx, database, cfg.RepoPath, &meta, runner); err != nil {
+			fmt.Printf("Warning: failed to read changed files for %s: %v\n", commit, err)
+		}
+
+		wt, err := PrepareWorktree(ctx, cfg.RepoPath, "", meta.CommitHashFull, runner)
+		if err != nil {
+			return nil, fmt.Errorf("prepare worktree for %s: %w", commit, err)
+		}
+		side := &abSide{meta: meta, wt: wt}
+		sides[i] = side
+
+		if err := harness.Build(ctx, wt.Path, runner); err != nil {
+			return nil, fmt.Errorf("build %s: %w", meta.CommitHash, err)
+		}
+		side.benchBin, err = harness.Locate(wt.Path)
+		if err != nil {
+			return nil, fmt.Errorf("find benchmark binary for %s: %w", meta.CommitHash, err)
+		}
+
+		side.meta.Notes = cfg.Notes
+		if side.meta.Notes == "" {
+			side.meta.Notes = fmt.Sprintf("ab %s..%s", base, head)
+		}
+		if cfg.MachineID != "" {
+			side.meta.MachineID = cfg.MachineID
+		}
+		setHarness(&side.meta, cfg, harness)
+		side.meta.SampleCount = cfg.Samples
+		side.meta.Environment = ReadEnvironment(ctx, harnessZigDir(harness, wt.Path), runner)
+	}
+
+	if err := runABRounds(ctx, runner, harness, sides[:], cfg.Samples, args); err != nil {
+		return nil, err
+	}
+	return recordAB(database, sides[0], sides[1], cfg.Samples)
+}
+
+// runABRounds takes one sample of each side per round, alternating which
+// side goes first (ABBA...), so neither side always runs on a machine warmed
+// up by the other.
+func runABRounds(ctx context.Context, r CmdRunner, h Harness, sides []*abSide, rounds int, args []string) error {
+	for round := 0; round < rounds; round++ {
+		fmt.Printf("  Round %d/%d\n", round+1, rounds)
+		order := sides
+		if round%2 == 1 {
+			order = []*abSide{sides[1], sides[0]}
+		}
+		for _, side := range order {
+			if err := runSample(ctx, r, h, side.wt.Path, side.benchBin, args, &side.buf); err != nil {
+				return fmt.Errorf("round %d (%s) failed: %w", round+1, side.meta.CommitHash, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recordAB stores both runs and the pair linking them in one transaction, so
+// a failure never leaves one side behind in history.
+func recordAB(database *db.DB, base, head *abSide, rounds int) (*ABResult, error) {
+	var sets [2]*record.SampleSet
+	for i, side := range []*abSide{base, head} {
+		set, err := record.ParseSamples(bytes.NewReader(side.buf.Bytes()))
+		if err != nil {
+			return nil, fmt.Errorf("parse results for %s: %w", side.meta.CommitHash, err)
+		}
+		sets[i] = set
+	}
+
+	var result ABResult
+	err := database.InTx(func(tx *db.Tx) error {
+		var err error
+		if result.BaseRunID, err = record.StoreRun(tx, sets[0], base.meta); err != nil {
+			return fmt.Errorf("record results for %s: %w", base.meta.CommitHash, err)
+		}
+		if result.HeadRunID, err = record.StoreRun(tx, sets[1], head.meta); err != nil {
+			return fmt.Errorf("record results for %s: %w", head.meta.CommitHash, err)
+		}
+		result.PairID, err = tx.InsertRunPair(&db.RunPair{
+			BaseRunID: result.BaseRunID,
+			HeadRunID: result.HeadRunID,
+			Rounds:    rounds,
+			CreatedAt: time.Now().Format(time.RFC3339),
+		})
+		if err != nil {
+			return fmt.Errorf("link runs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
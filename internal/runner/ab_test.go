@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+
+	"opentui-bench/internal/record"
+)
+
+func TestRunABRoundsAlternatesOrder(t *testing.T) {
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		return benchLine(t, "render", "frame", 100), nil
+	}}
+	sides := []*abSide{
+		{meta: record.RunMetadata{CommitHash: "base"}, wt: &Worktree{Path: t.TempDir()}, benchBin: "base-bin"},
+		{meta: record.RunMetadata{CommitHash: "head"}, wt: &Worktree{Path: t.TempDir()}, benchBin: "head-bin"},
+	}
+
+	if err := runABRounds(context.Background(), r, zigHarness{}, sides, 4, nil); err != nil {
+		t.Fatalf("runABRounds: %v", err)
+	}
+
+	var order []string
+	for _, call := range r.calls {
+		order = append(order, strings.Fields(call)[0])
+	}
+	want := []string{"base-bin", "head-bin", "head-bin", "base-bin", "base-bin", "head-bin", "head-bin", "base-bin"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for _, side := range sides {
+		set, err := record.ParseSamples(strings.NewReader(side.buf.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := set.Count(record.BenchmarkKey{Category: "render", Name: "frame"}); n != 4 {
+			t.Errorf("%s: %d samples, want 4", side.meta.CommitHash, n)
+		}
+	}
+}
+
+func newABSide(t *testing.T, commit string, ns int64) *abSide {
+	t.Helper()
+
+	side := &abSide{meta: record.RunMetadata{CommitHash: commit, CommitHashFull: commit, Branch: "main"}}
+	side.buf.Write(benchLine(t, "render", "frame", ns))
+	side.buf.Write(benchLine(t, "render", "frame", ns))
+	return side
+}
+
+func TestRecordABLinksRuns(t *testing.T) {
+	database := openTestDB(t)
+
+	result, err := recordAB(database, newABSide(t, "base", 100), newABSide(t, "head", 90), 2)
+	if err != nil {
+		t.Fatalf("recordAB: %v", err)
+	}
+	pair, err := database.GetRunPair(result.PairID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.BaseRunID != result.BaseRunID || pair.HeadRunID != result.HeadRunID || pair.Rounds != 2 {
+		t.Fatalf("pair = %+v, result = %+v", pair, result)
+	}
+	for runID, want := range map[int64]int64{result.BaseRunID: 100, result.HeadRunID: 90} {
+		results, err := database.GetResultsForRun(runID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].AvgNs != want {
+			t.Errorf("run %d: results = %+v, want avg %d", runID, results, want)
+		}
+	}
+}
+
+func TestRecordABIsAtomic(t *testing.T) {
+	database := openTestDB(t)
+	if _, err := database.Exec(`CREATE TRIGGER fail_pairs BEFORE INSERT ON run_pairs BEGIN SELECT RAISE(ABORT, 'no pairs'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := recordAB(database, newABSide(t, "base", 100), newABSide(t, "head", 90), 2); err == nil {
+		t.Fatal("recordAB succeeded")
+	}
+	runs, err := database.ListRuns(0, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 0 {
+		t.Fatalf("%d runs left behind", len(runs))
+	}
+}
+
+func TestABRunsStayOutOfHistory(t *testing.T) {
+	database := openTestDB(t)
+
+	result, err := recordAB(database, newABSide(t, "base", 100), newABSide(t, "head", 90), 2)
+	if err != nil {
+		t.Fatalf("recordAB: %v", err)
+	}
+
+	if ok, err := database.HasCommit("head"); err != nil || ok {
+		t.Fatalf("HasCommit(head) = %v, %v; A/B runs must not count as benchmarked", ok, err)
+	}
+	if _, err := database.GetLatestRun("main"); err != sql.ErrNoRows {
+		t.Fatalf("GetLatestRun: %v, want sql.ErrNoRows", err)
+	}
+	trend, err := database.GetTrend("frame", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trend) != 0 {
+		t.Fatalf("trend has %d A/B points", len(trend))
+	}
+	// The window of an A/B run still holds the run itself.
+	window, err := database.GetComparableRunsWindow(result.HeadRunID, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(window) != 1 || window[0].ID != result.HeadRunID {
+		t.Fatalf("window = %+v, want only run %d", window, result.HeadRunID)
+	}
+}
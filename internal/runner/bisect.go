@@ -0,0 +1,225 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"opentui-bench/internal/db"
+	"opentui-bench/internal/stats"
+)
+
+// bisectAlpha matches the significance level used by /api/regressions.
+const bisectAlpha = 0.01
+
+// Bisect verdicts of a tested commit.
+const (
+	BisectGood = "good"
+	BisectBad  = "bad"
+	// BisectSkip marks a commit that could not be measured, e.g. because
+	// it does not build; the search continues with a neighbour, like
+	// `git bisect skip`.
+	BisectSkip = "skip"
+)
+
+// BisectStep is one commit benchmarked during a bisect.
+type BisectStep struct {
+	Commit        string
+	RunID         int64
+	Status        string   // BisectGood, BisectBad or BisectSkip
+	ChangePercent *float64 // set when the commit regressed
+	Reason        string   // why a skipped commit could not be measured
+}
+
+// BisectResult reports the first commit that regressed bench.
+type BisectResult struct {
+	FirstBad string
+	// Candidates is set when skipped commits keep the first bad commit
+	// from being pinpointed: it is one of these, in history order, ending
+	// with FirstBad.
+	Candidates []string
+	Steps      []BisectStep
+}
+
+// Bisect binary-searches the first-parent history between good and bad for
+// the commit that regressed the benchmark category/bench. Every tested
+// commit is recorded as a normal run and judged with stats.DetectRegression
+// against a baseline computed from the good commit's run. Commits that fail
+// to build or benchmark are skipped.
+func Bisect(ctx context.Context, database *db.DB, cfg RunConfig, good, bad, category, bench string) (*BisectResult, error) {
+	if cfg.Samples < 2 {
+		return nil, fmt.Errorf("samples must be >= 2 to detect regressions")
+	}
+
+	runner := OSRunner{}
+	goodHash, err := runGitIn(ctx, runner, cfg.RepoPath, "rev-parse", "--verify", good+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("resolve good commit: %w", err)
+	}
+	badHash, err := runGitIn(ctx, runner, cfg.RepoPath, "rev-parse", "--verify", bad+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("resolve bad commit: %w", err)
+	}
+
+	out, err := runGitIn(ctx, runner, cfg.RepoPath, "rev-list", "--first-parent", "--reverse", goodHash+".."+badHash)
+	if err != nil {
+		return nil, fmt.Errorf("list commits: %w", err)
+	}
+	commits := strings.Fields(out)
+	if len(commits) == 0 || commits[len(commits)-1] != badHash {
+		return nil, fmt.Errorf("%s is not a first-parent ancestor of %s", good, bad)
+	}
+
+	if cfg.Notes == "" {
+		cfg.Notes = fmt.Sprintf("bisect %s..%s", shortRev(goodHash), shortRev(badHash))
+	}
+	if cfg.Harness == "" || cfg.Harness == HarnessZig {
+		cfg.Filter = category
+		cfg.FilterBenchmark = bench
+	}
+
+	measure := func(commit string) (int64, stats.RunStat, error) {
+		runCfg := cfg
+		runCfg.Commit = commit
+		runID, err := Run(ctx, database, runCfg)
+		if err != nil {
+			return runID, stats.RunStat{}, err
+		}
+		results, err := database.GetResultsForBenchmarkInRuns(category, bench, []int64{runID})
+		if err != nil {
+			return runID, stats.RunStat{}, err
+		}
+		res, ok := results[runID]
+		if !ok {
+			return runID, stats.RunStat{}, fmt.Errorf("benchmark %s/%s not found in run #%d", category, bench, runID)
+		}
+		sem := float64(0)
+		if res.SampleCount >= 2 {
+			sem = float64(res.StdDevNs) / math.Sqrt(float64(res.SampleCount))
+		}
+		return runID, stats.RunStat{
+			RunID:       runID,
+			Mean:        float64(res.AvgNs),
+			Sem:         sem,
+			SampleCount: res.SampleCount,
+			StdDev:      float64(res.StdDevNs),
+		}, nil
+	}
+
+	fmt.Printf("Bisecting %d commits for %s/%s\n", len(commits), category, bench)
+
+	fmt.Printf("  Good %s\n", shortRev(goodHash))
+	_, goodStat, err := measure(goodHash)
+	if err != nil {
+		return nil, fmt.Errorf("benchmark good commit: %w", err)
+	}
+	baseline, err := stats.ComputeBaseline([]stats.RunStat{goodStat}, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("good commit has no usable baseline (needs variance across samples): %w", err)
+	}
+
+	result := &BisectResult{}
+	check := func(commit string) (string, error) {
+		fmt.Printf("  Testing %s\n", shortRev(commit))
+		step := BisectStep{Commit: commit, Status: BisectGood}
+		runID, stat, err := measure(commit)
+		step.RunID = runID
+		if err == nil {
+			det := stats.DetectRegression(stat, baseline, bisectAlpha)
+			switch det.Status {
+			case "insufficient":
+				err = fmt.Errorf("run #%d has insufficient samples", runID)
+			case "regressed":
+				step.Status = BisectBad
+				step.ChangePercent = det.ChangePercent
+			}
+		}
+		if err != nil {
+			// Cancellation ends the bisect; anything else only rules out
+			// this commit.
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			step.Status = BisectSkip
+			step.Reason = err.Error()
+		}
+		result.Steps = append(result.Steps, step)
+		fmt.Printf("    %s (run #%d)\n", step.Status, runID)
+		return step.Status, nil
+	}
+
+	// Confirm the range actually contains a regression before searching it.
+	status, err := check(badHash)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case BisectSkip:
+		return result, fmt.Errorf("benchmark bad commit %s: %s", shortRev(badHash), result.Steps[0].Reason)
+	case BisectGood:
+		return result, fmt.Errorf("%s does not regress %s relative to %s", shortRev(badHash), bench, shortRev(goodHash))
+	}
+
+	first, err := bisectSearch(commits, check)
+	if err != nil {
+		return result, err
+	}
+	result.FirstBad = first[len(first)-1]
+	if len(first) > 1 {
+		result.Candidates = first
+	}
+	return result, nil
+}
+
+// bisectSearch finds the first bad commit of commits, whose last commit is
+// bad and whose predecessor is good, testing commits with check. Skipped
+// commits are replaced by the closest untested neighbour. It returns the
+// first bad commit, or, when only skipped commits are left before it, those
+// and the first bad commit.
+func bisectSearch(commits []string, check func(commit string) (string, error)) ([]string, error) {
+	// Invariant: commits[lo] is good (lo == -1 is the good commit itself) and
+	// commits[hi] is bad.
+	lo, hi := -1, len(commits)-1
+	skipped := make(map[int]bool)
+	for {
+		mid, ok := bisectNext(lo, hi, skipped)
+		if !ok {
+			break
+		}
+		status, err := check(commits[mid])
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case BisectBad:
+			hi = mid
+		case BisectGood:
+			lo = mid
+		default:
+			skipped[mid] = true
+		}
+	}
+	return commits[lo+1 : hi+1], nil
+}
+
+// bisectNext returns the untested index closest to the midpoint of lo and
+// hi, or false when only skipped commits are left between them.
+func bisectNext(lo, hi int, skipped map[int]bool) (int, bool) {
+	mid := lo + (hi-lo)/2
+	for d := 0; mid-d > lo || mid+d < hi; d++ {
+		for _, i := range []int{mid - d, mid + d} {
+			if i > lo && i < hi && !skipped[i] {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func shortRev(hash string) string {
+	if len(hash) <= 7 {
+		return hash
+	}
+	return hash[:7]
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestBisectSearch(t *testing.T) {
+	commits := make([]string, 10)
+	for i := range commits {
+		commits[i] = fmt.Sprintf("c%d", i)
+	}
+
+	cases := []struct {
+		name     string
+		firstBad int
+		skip     []int
+		want     []string
+		tested   []string
+	}{
+		{"midpoints", 6, nil, []string{"c6"}, []string{"c4", "c6", "c5"}},
+		{"first commit", 0, nil, []string{"c0"}, []string{"c4", "c1", "c0"}},
+		{"skipped midpoint", 6, []int{4}, []string{"c6"}, []string{"c4", "c3", "c6", "c5"}},
+		{"skip next to first bad", 6, []int{5}, []string{"c5", "c6"}, []string{"c4", "c6", "c5"}},
+		{"all skipped", 9, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, commits, []string{"c4", "c3", "c5", "c2", "c6", "c1", "c7", "c0", "c8"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var tested []string
+			check := func(commit string) (string, error) {
+				tested = append(tested, commit)
+				i := slices.Index(commits, commit)
+				switch {
+				case slices.Contains(tc.skip, i):
+					return BisectSkip, nil
+				case i >= tc.firstBad:
+					return BisectBad, nil
+				default:
+					return BisectGood, nil
+				}
+			}
+
+			got, err := bisectSearch(commits, check)
+			if err != nil {
+				t.Fatalf("bisectSearch: %v", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("first bad = %v, want %v", got, tc.want)
+			}
+			if !slices.Equal(tested, tc.tested) {
+				t.Errorf("tested %v, want %v", tested, tc.tested)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"opentui-bench/internal/db"
+)
+
+// harnessZigDir is the zigDir ReadEnvironment needs for h: the worktree's zig
+// directory for the zig harness and "" for the others.
+func harnessZigDir(h Harness, root string) string {
+	if h.Name() != HarnessZig {
+		return ""
+	}
+	return ZigDir(root)
+}
+
+// ReadEnvironment fingerprints the machine and toolchain a run executes on.
+// Every probe is best effort: values that cannot be read are left empty so a
+// missing /proc or sysfs entry never fails a run. zig version is read from
+// zigDir because anyzig resolves the toolchain per project; an empty zigDir
+// skips it for suites not built with zig.
+func ReadEnvironment(ctx context.Context, zigDir string, r CmdRunner) *db.RunEnvironment {
+	env := &db.RunEnvironment{
+		CPUModel:  readCPUModel(),
+		CPUCores:  runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+
+	if out, err := r.CombinedOutput(ctx, exec.CommandContext(ctx, "uname", "-r")); err == nil {
+		env.KernelVersion = strings.TrimSpace(string(out))
+	}
+
+	if data, err := os.ReadFile("/sys/devices/system/cpu/cpu0/cpufreq/scaling_governor"); err == nil {
+		env.CPUGovernor = strings.TrimSpace(string(data))
+	}
+
+	if zigDir != "" {
+		zigCmd := exec.CommandContext(ctx, "zig", "version")
+		zigCmd.Dir = zigDir
+		if out, err := r.CombinedOutput(ctx, zigCmd); err == nil {
+			env.ZigVersion = strings.TrimSpace(string(out))
+		}
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		env.BenchVersion = info.Main.Version
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				env.BenchVersion = setting.Value
+			}
+		}
+	}
+
+	if data, err := os.ReadFile("/proc/sys/kernel/perf_event_paranoid"); err == nil {
+		if n, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			env.PerfEventParanoid = &n
+			// Level 2 still permits user-space sampling of our own process,
+			// which is all `perf record` needs for the bench binary.
+			env.ProfilingAllowed = n <= 2 || os.Geteuid() == 0
+		}
+	}
+
+	env.Fingerprint = Fingerprint(env)
+	return env
+}
+
+// Fingerprint hashes the parts of the environment that affect timings.
+// Runs with different fingerprints should not share a regression baseline.
+func Fingerprint(env *db.RunEnvironment) string {
+	h := sha256.New()
+	for _, part := range []string{
+		env.CPUModel,
+		strconv.Itoa(env.CPUCores),
+		env.KernelVersion,
+		env.CPUGovernor,
+		env.ZigVersion,
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func readCPUModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return runtime.GOARCH
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "model name" || key == "Model" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return runtime.GOARCH
+}
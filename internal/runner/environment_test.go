@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestReadEnvironmentSkipsZigForOtherHarnesses(t *testing.T) {
+	for _, h := range []Harness{zigHarness{}, commandHarness{command: "true"}, bunHarness{script: "bench.ts"}} {
+		r := &fakeRunner{}
+		ReadEnvironment(context.Background(), harnessZigDir(h, t.TempDir()), r)
+
+		asked := strings.Contains(strings.Join(r.calls, "\n"), "zig version")
+		if asked != (h.Name() == HarnessZig) {
+			t.Errorf("%s harness: asked for zig version = %v", h.Name(), asked)
+		}
+	}
+}
+
+func TestReadEnvironmentFingerprint(t *testing.T) {
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		switch cmd.Args[0] {
+		case "uname":
+			return []byte("6.1.0\n"), nil
+		case "zig":
+			return []byte("0.14.0\n"), nil
+		}
+		return nil, nil
+	}}
+	env := ReadEnvironment(context.Background(), t.TempDir(), r)
+	if env.KernelVersion != "6.1.0" || env.ZigVersion != "0.14.0" {
+		t.Fatalf("kernel %q, zig %q", env.KernelVersion, env.ZigVersion)
+	}
+	if env.Fingerprint == "" || env.Fingerprint != Fingerprint(env) {
+		t.Fatalf("fingerprint %q, want %q", env.Fingerprint, Fingerprint(env))
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := db.RunEnvironment{CPUModel: "cpu", CPUCores: 8, KernelVersion: "6.1.0", CPUGovernor: "performance", ZigVersion: "0.14.0"}
+
+	// Fields that do not affect timings leave the fingerprint alone.
+	same := base
+	same.GoVersion = "go1.25.0"
+	same.BenchVersion = "abc1234"
+	if Fingerprint(&same) != Fingerprint(&base) {
+		t.Fatal("fingerprint changed with the go or bench version")
+	}
+
+	for name, change := range map[string]func(*db.RunEnvironment){
+		"cpu model": func(e *db.RunEnvironment) { e.CPUModel = "other" },
+		"cpu cores": func(e *db.RunEnvironment) { e.CPUCores = 4 },
+		"kernel":    func(e *db.RunEnvironment) { e.KernelVersion = "6.2.0" },
+		"governor":  func(e *db.RunEnvironment) { e.CPUGovernor = "powersave" },
+		"zig":       func(e *db.RunEnvironment) { e.ZigVersion = "0.15.0" },
+	} {
+		env := base
+		change(&env)
+		if Fingerprint(&env) == Fingerprint(&base) {
+			t.Errorf("fingerprint did not change with the %s", name)
+		}
+	}
+}
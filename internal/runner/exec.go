@@ -1,8 +1,12 @@
 package runner
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os/exec"
+	"strings"
+	"time"
 )
 
 // CmdRunner abstracts executing commands to allow for testing.
@@ -17,3 +21,52 @@ type OSRunner struct{}
 func (OSRunner) CombinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
 	return cmd.CombinedOutput()
 }
+
+// OutputError is a failed command together with its combined output, so the
+// output can be stored alongside the failed run.
+type OutputError struct {
+	Err    error
+	Output []byte
+}
+
+func (e *OutputError) Error() string {
+	return fmt.Sprintf("%v\n%s", e.Err, strings.TrimSpace(string(e.Output)))
+}
+
+func (e *OutputError) Unwrap() error {
+	return e.Err
+}
+
+// LogRunner wraps a CmdRunner and keeps a transcript of every command it runs:
+// the command line, its combined output and how it ended. Output is captured
+// whether or not the command succeeds. With MaxOutput set, each command's
+// output beyond MaxOutput bytes is cut from the middle, keeping its start and
+// end; benchmark samples print their whole JSON result stream, which the
+// results already hold.
+type LogRunner struct {
+	CmdRunner
+	MaxOutput int
+	Log       bytes.Buffer
+}
+
+func (l *LogRunner) CombinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	out, err := l.CmdRunner.CombinedOutput(ctx, cmd)
+
+	fmt.Fprintf(&l.Log, "$ %s\n", strings.Join(cmd.Args, " "))
+	logged := out
+	if l.MaxOutput > 0 && len(out) > l.MaxOutput {
+		head, tail := out[:l.MaxOutput/2], out[len(out)-l.MaxOutput/2:]
+		logged = fmt.Appendf(bytes.Clone(head), "\n[... %d bytes omitted ...]\n%s", len(out)-len(head)-len(tail), tail)
+	}
+	l.Log.Write(logged)
+	if len(logged) > 0 && logged[len(logged)-1] != '\n' {
+		l.Log.WriteByte('\n')
+	}
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	fmt.Fprintf(&l.Log, "[%s after %s]\n\n", status, time.Since(start).Round(time.Millisecond))
+	return out, err
+}
@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLogRunnerCutsLongOutput(t *testing.T) {
+	out := []byte(strings.Repeat("a", 10) + strings.Repeat("b", 100) + strings.Repeat("c", 10))
+	l := &LogRunner{
+		CmdRunner: &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) { return out, nil }},
+		MaxOutput: 20,
+	}
+
+	got, err := l.CombinedOutput(context.Background(), exec.Command("bench", "--json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, out) {
+		t.Fatal("LogRunner changed the output returned to the caller")
+	}
+	log := l.Log.String()
+	if !strings.HasPrefix(log, "$ bench --json\naaaaaaaaaa\n[... 100 bytes omitted ...]\ncccccccccc\n[ok after ") {
+		t.Fatalf("log = %q", log)
+	}
+}
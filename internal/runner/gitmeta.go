@@ -4,52 +4,93 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 
+	"opentui-bench/internal/db"
 	"opentui-bench/internal/record"
 )
 
-// ReadGitMeta gathers git metadata and hostname for the run.
-func ReadGitMeta(ctx context.Context, repoPath string, r CmdRunner) (record.RunMetadata, error) {
+// ReadGitMeta gathers git metadata for rev and hostname for the run.
+// The branch is recorded when rev names one (see RefBranch) or is the repo's
+// checked-out HEAD commit; callers iterating a branch by hash set it
+// themselves.
+func ReadGitMeta(ctx context.Context, repoPath string, rev string, r CmdRunner) (record.RunMetadata, error) {
 	var meta record.RunMetadata
 	var err error
 
+	if rev == "" {
+		rev = "HEAD"
+	}
+
 	// Helper to run git command in repo dir
 	runGit := func(args ...string) (string, error) {
-		cmd := exec.CommandContext(ctx, "git", args...)
-		cmd.Dir = repoPath
-		out, err := r.CombinedOutput(ctx, cmd)
-		if err != nil {
-			return "", fmt.Errorf("git %v: %w (%s)", args, err, strings.TrimSpace(string(out)))
-		}
-		return strings.TrimSpace(string(out)), nil
+		return runGitIn(ctx, r, repoPath, args...)
+	}
+
+	meta.CommitHash, err = runGit("rev-parse", "--short", rev)
+	if err != nil {
+		return meta, err
 	}
 
-	meta.CommitHash, err = runGit("rev-parse", "--short", "HEAD")
+	meta.CommitHashFull, err = runGit("rev-parse", rev)
 	if err != nil {
 		return meta, err
 	}
 
-	meta.CommitHashFull, err = runGit("rev-parse", "HEAD")
+	meta.CommitMessage, err = runGit("log", "-1", "--format=%s", rev)
 	if err != nil {
 		return meta, err
 	}
 
-	meta.CommitMessage, err = runGit("log", "-1", "--format=%s")
+	meta.CommitDate, err = runGit("log", "-1", "--format=%cI", rev)
 	if err != nil {
 		return meta, err
 	}
 
-	meta.CommitDate, err = runGit("log", "-1", "--format=%cI")
+	meta.CommitSeq, err = CommitSeq(ctx, repoPath, meta.CommitHashFull, r)
 	if err != nil {
 		return meta, err
 	}
 
-	meta.Branch, err = runGit("branch", "--show-current")
+	// Fields are NUL-separated; the body comes last since it may span lines.
+	details, err := runGit("log", "-1", "--format=%an%x00%ae%x00%P%x00%b", rev)
 	if err != nil {
 		return meta, err
 	}
+	fields := strings.SplitN(details, "\x00", 4)
+	for len(fields) < 4 {
+		fields = append(fields, "")
+	}
+	meta.Commit = &db.CommitInfo{
+		AuthorName:  fields[0],
+		AuthorEmail: fields[1],
+		Parents:     strings.Fields(fields[2]),
+		Body:        strings.TrimSpace(fields[3]),
+	}
+
+	tags, err := runGit("tag", "--points-at", meta.CommitHashFull)
+	if err != nil {
+		return meta, err
+	}
+	meta.Commit.Tags = strings.Fields(tags)
+
+	meta.Branch, err = RefBranch(ctx, repoPath, rev, r)
+	if err != nil {
+		return meta, err
+	}
+	if meta.Branch == "" {
+		head, err := runGit("rev-parse", "HEAD")
+		if err != nil {
+			return meta, err
+		}
+		if head == meta.CommitHashFull {
+			meta.Branch, err = runGit("branch", "--show-current")
+			if err != nil {
+				return meta, err
+			}
+		}
+	}
 
 	meta.MachineID, err = os.Hostname()
 	if err != nil {
@@ -59,3 +100,160 @@ func ReadGitMeta(ctx context.Context, repoPath string, r CmdRunner) (record.RunM
 
 	return meta, nil
 }
+
+// RefBranch returns the branch rev names: the checked-out branch for HEAD,
+// and the branch of a local or remote-tracking branch ref ("origin/main" is
+// "main"). It returns "" for commit hashes, tags and a detached HEAD.
+func RefBranch(ctx context.Context, repoPath, rev string, r CmdRunner) (string, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	ref, err := runGitIn(ctx, r, repoPath, "rev-parse", "--symbolic-full-name", rev)
+	if err != nil {
+		return "", err
+	}
+	return branchFromRef(ref), nil
+}
+
+// branchFromRef returns the branch of a full ref name, or "" when it is not
+// a branch.
+func branchFromRef(ref string) string {
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		return branch
+	}
+	if remoteBranch, ok := strings.CutPrefix(ref, "refs/remotes/"); ok {
+		if _, branch, ok := strings.Cut(remoteBranch, "/"); ok && branch != "HEAD" {
+			return branch
+		}
+	}
+	return ""
+}
+
+// CommitSeq returns the position of rev in its first-parent history, which
+// orders the commits of a branch even when they were recorded out of order.
+func CommitSeq(ctx context.Context, repoPath, rev string, r CmdRunner) (int64, error) {
+	out, err := runGitIn(ctx, r, repoPath, "rev-list", "--first-parent", "--count", rev)
+	if err != nil {
+		return 0, err
+	}
+	seq, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse commit count %q: %w", out, err)
+	}
+	return seq, nil
+}
+
+// SequenceRuns stores the first-parent sequence of the runs recorded without
+// one whose commit is in the repository, and returns how many of the
+// unsequenced commits it sequenced. Commits missing from the repository are
+// skipped.
+func SequenceRuns(ctx context.Context, database *db.DB, repoPath string, r CmdRunner) (int, int, error) {
+	commits, err := database.ListUnsequencedCommits()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	updated := 0
+	for _, commit := range commits {
+		seq, err := CommitSeq(ctx, repoPath, commit, r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return updated, len(commits), ctx.Err()
+			}
+			continue
+		}
+		if err := database.SetCommitSeq(commit, seq); err != nil {
+			return updated, len(commits), err
+		}
+		updated++
+	}
+	return updated, len(commits), nil
+}
+
+// changedFilesDepth bounds the search for a previously recorded ancestor.
+const changedFilesDepth = 100
+
+// ReadChangedFiles fills meta.Commit with the diffstat of the commit against
+// the closest first-parent ancestor that has a complete run, falling back to
+// the first parent. Root commits get no diffstat.
+func ReadChangedFiles(ctx context.Context, database *db.DB, repoPath string, meta *record.RunMetadata, r CmdRunner) error {
+	if meta.Commit == nil {
+		return nil
+	}
+
+	out, err := runGitIn(ctx, r, repoPath, "rev-list", "--first-parent",
+		fmt.Sprintf("--max-count=%d", changedFilesDepth+1), meta.CommitHashFull)
+	if err != nil {
+		return err
+	}
+	history := strings.Fields(out)
+	if len(history) < 2 {
+		return nil
+	}
+
+	base := history[1]
+	for _, hash := range history[1:] {
+		recorded, err := database.HasCommit(hash)
+		if err != nil {
+			return fmt.Errorf("check commit %s: %w", shortRev(hash), err)
+		}
+		if recorded {
+			base = hash
+			break
+		}
+	}
+
+	numstat, err := runGitIn(ctx, r, repoPath, "diff", "--numstat", "-z", "-M", base, meta.CommitHashFull)
+	if err != nil {
+		return err
+	}
+	files, err := parseNumstat(numstat)
+	if err != nil {
+		return err
+	}
+
+	meta.Commit.DiffBase = base
+	meta.Commit.ChangedFiles = files
+	return nil
+}
+
+// parseNumstat parses `git diff --numstat -z` output. Each file is
+// "added\tdeleted\tpath\0", or for a rename "added\tdeleted\t\0old\0new\0";
+// paths are not quoted. Binary files are listed with "-" instead of line
+// counts.
+func parseNumstat(out string) ([]db.ChangedFile, error) {
+	var files []db.ChangedFile
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		record := fields[i]
+		if record == "" {
+			continue
+		}
+		parts := strings.SplitN(record, "\t", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unexpected numstat record: %q", record)
+		}
+
+		f := db.ChangedFile{Path: parts[2]}
+		if f.Path == "" {
+			if i+2 >= len(fields) || fields[i+2] == "" {
+				return nil, fmt.Errorf("truncated numstat rename: %q", record)
+			}
+			f.OldPath, f.Path = fields[i+1], fields[i+2]
+			i += 2
+		}
+		if parts[0] == "-" && parts[1] == "-" {
+			f.Binary = true
+		} else {
+			var err error
+			if f.Additions, err = strconv.Atoi(parts[0]); err != nil {
+				return nil, fmt.Errorf("unexpected numstat record: %q", record)
+			}
+			if f.Deletions, err = strconv.Atoi(parts[1]); err != nil {
+				return nil, fmt.Errorf("unexpected numstat record: %q", record)
+			}
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
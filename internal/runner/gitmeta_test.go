@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"slices"
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestBranchFromRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"refs/heads/main", "main"},
+		{"refs/heads/feature/x", "feature/x"},
+		{"refs/remotes/origin/main", "main"},
+		{"refs/remotes/origin/HEAD", ""},
+		{"refs/tags/v1.0", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := branchFromRef(tt.ref); got != tt.want {
+			t.Errorf("branchFromRef(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want []db.ChangedFile
+	}{
+		{"empty", "", nil},
+		{
+			"lines and binary",
+			"3\t1\tsrc/a.zig\x00-\t-\tassets/logo.png\x00",
+			[]db.ChangedFile{
+				{Path: "src/a.zig", Additions: 3, Deletions: 1},
+				{Path: "assets/logo.png", Binary: true},
+			},
+		},
+		{
+			"special characters are not quoted",
+			"1\t0\tdocs/naïve \"quoted\"\ttab.md\x00",
+			[]db.ChangedFile{{Path: "docs/naïve \"quoted\"\ttab.md", Additions: 1}},
+		},
+		{
+			"rename",
+			"2\t2\t\x00src/old.zig\x00src/new.zig\x000\t4\tsrc/b.zig\x00",
+			[]db.ChangedFile{
+				{Path: "src/new.zig", OldPath: "src/old.zig", Additions: 2, Deletions: 2},
+				{Path: "src/b.zig", Deletions: 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNumstat(tt.out)
+			if err != nil {
+				t.Fatalf("parseNumstat: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"3\t1\x00", "x\t1\ta.zig\x00", "1\t1\t\x00old.zig\x00"} {
+		if _, err := parseNumstat(bad); err == nil {
+			t.Errorf("parseNumstat(%q) succeeded, want an error", bad)
+		}
+	}
+}
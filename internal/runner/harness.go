@@ -0,0 +1,227 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"opentui-bench/internal/record"
+)
+
+// Harness names accepted by NewHarness.
+const (
+	HarnessZig     = "zig"
+	HarnessCommand = "command"
+	HarnessBun     = "bun"
+)
+
+// Harness adapts a benchmark suite to the runner. All paths are relative to
+// the root of the worktree the commit was checked out in.
+type Harness interface {
+	// Name identifies the harness in run metadata.
+	Name() string
+	// Build compiles the suite.
+	Build(ctx context.Context, root string, r CmdRunner) error
+	// Locate returns the executable RunSample should invoke.
+	Locate(root string) (string, error)
+	// RunSample executes the suite once with the given filter arguments.
+	RunSample(ctx context.Context, root, bin string, args []string, r CmdRunner) (SampleOutput, error)
+	// Parse converts the output of one sample to benchmark JSON lines.
+	Parse(out SampleOutput) ([]byte, error)
+	// FilterArgs returns arguments restricting a sample to one category
+	// and/or benchmark, or nil when the suite cannot be filtered.
+	FilterArgs(category, benchmark string) []string
+}
+
+// SampleOutput is the raw result of a single harness invocation.
+type SampleOutput struct {
+	Output  []byte
+	Elapsed time.Duration
+}
+
+// NewHarness returns the harness selected by cfg.Harness (zig by default).
+func NewHarness(cfg RunConfig) (Harness, error) {
+	switch cfg.Harness {
+	case "", HarnessZig:
+		return zigHarness{optimize: cfg.ZigOptimize}, nil
+	case HarnessCommand:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("command harness requires a command")
+		}
+		return commandHarness{command: cfg.Command, dir: cfg.HarnessDir}, nil
+	case HarnessBun:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("bun harness requires a benchmark script")
+		}
+		dir := cfg.HarnessDir
+		if dir == "" {
+			dir = filepath.Join("packages", "core")
+		}
+		return bunHarness{script: cfg.Command, dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown harness: %s", cfg.Harness)
+	}
+}
+
+// zigHarness drives the opentui-bench binary built by `zig build`.
+type zigHarness struct {
+	optimize string
+}
+
+func (zigHarness) Name() string { return HarnessZig }
+
+func (h zigHarness) Build(ctx context.Context, root string, r CmdRunner) error {
+	return BuildZigBench(ctx, ZigDir(root), h.optimize, r)
+}
+
+func (zigHarness) Locate(root string) (string, error) {
+	return FindBenchmarkBinary(ZigDir(root))
+}
+
+func (zigHarness) RunSample(ctx context.Context, root, bin string, args []string, r CmdRunner) (SampleOutput, error) {
+	cmdArgs := []string{"--json", "--mem"}
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.CommandContext(ctx, bin, cmdArgs...)
+	cmd.Dir = ZigDir(root)
+	return timedOutput(ctx, cmd, r)
+}
+
+func (zigHarness) Parse(out SampleOutput) ([]byte, error) {
+	return out.Output, nil
+}
+
+func (zigHarness) FilterArgs(category, benchmark string) []string {
+	var args []string
+	if category != "" {
+		args = append(args, "--filter", category)
+	}
+	if benchmark != "" {
+		args = append(args, "--bench", benchmark)
+	}
+	return args
+}
+
+// commandHarness runs an arbitrary shell command. If the command prints
+// benchmark JSON lines those are used as-is; otherwise its wall-clock time is
+// recorded as a single "command" benchmark.
+type commandHarness struct {
+	command string
+	dir     string
+}
+
+func (commandHarness) Name() string { return HarnessCommand }
+
+func (commandHarness) Build(ctx context.Context, root string, r CmdRunner) error {
+	return nil
+}
+
+func (commandHarness) Locate(root string) (string, error) {
+	return exec.LookPath("sh")
+}
+
+func (h commandHarness) RunSample(ctx context.Context, root, bin string, args []string, r CmdRunner) (SampleOutput, error) {
+	cmd := exec.CommandContext(ctx, bin, "-c", h.command)
+	cmd.Dir = filepath.Join(root, h.dir)
+	return timedOutput(ctx, cmd, r)
+}
+
+func (h commandHarness) Parse(out SampleOutput) ([]byte, error) {
+	if hasBenchmarkJSON(out.Output) {
+		return out.Output, nil
+	}
+
+	ns := out.Elapsed.Nanoseconds()
+	line, err := json.Marshal(record.BenchmarkJSON{
+		Benchmark: HarnessCommand,
+		Results: []record.ResultJSON{{
+			Name:       h.command,
+			MinNs:      ns,
+			AvgNs:      ns,
+			MaxNs:      ns,
+			TotalNs:    ns,
+			Iterations: 1,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func (commandHarness) FilterArgs(category, benchmark string) []string {
+	return nil
+}
+
+// bunHarness runs a TypeScript benchmark script with bun. The script must
+// print benchmark JSON lines (the same format as the Zig bench binary) and
+// accept --filter/--bench arguments.
+type bunHarness struct {
+	script string
+	dir    string
+}
+
+func (bunHarness) Name() string { return HarnessBun }
+
+func (bunHarness) Build(ctx context.Context, root string, r CmdRunner) error {
+	cmd := exec.CommandContext(ctx, "bun", "install", "--frozen-lockfile")
+	cmd.Dir = root
+
+	out, err := r.CombinedOutput(ctx, cmd)
+	if err != nil {
+		return &OutputError{Err: fmt.Errorf("bun install failed: %w", err), Output: out}
+	}
+	return nil
+}
+
+func (bunHarness) Locate(root string) (string, error) {
+	return exec.LookPath("bun")
+}
+
+func (h bunHarness) RunSample(ctx context.Context, root, bin string, args []string, r CmdRunner) (SampleOutput, error) {
+	cmdArgs := []string{h.script, "--json"}
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.CommandContext(ctx, bin, cmdArgs...)
+	cmd.Dir = filepath.Join(root, h.dir)
+	return timedOutput(ctx, cmd, r)
+}
+
+func (bunHarness) Parse(out SampleOutput) ([]byte, error) {
+	if !hasBenchmarkJSON(out.Output) {
+		return nil, fmt.Errorf("bun script printed no benchmark JSON lines")
+	}
+	return out.Output, nil
+}
+
+func (h bunHarness) FilterArgs(category, benchmark string) []string {
+	return zigHarness{}.FilterArgs(category, benchmark)
+}
+
+func timedOutput(ctx context.Context, cmd *exec.Cmd, r CmdRunner) (SampleOutput, error) {
+	start := time.Now()
+	out, err := r.CombinedOutput(ctx, cmd)
+	elapsed := time.Since(start)
+	if err != nil {
+		return SampleOutput{}, &OutputError{Err: err, Output: out}
+	}
+	return SampleOutput{Output: out, Elapsed: elapsed}, nil
+}
+
+func hasBenchmarkJSON(out []byte) bool {
+	for line := range strings.SplitSeq(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var bench record.BenchmarkJSON
+		if json.Unmarshal([]byte(line), &bench) == nil && bench.Benchmark != "" {
+			return true
+		}
+	}
+	return false
+}
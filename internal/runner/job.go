@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JobConfig is the set of run options a queued job may override. It is
+// deliberately narrower than RunConfig: jobs can be submitted over HTTP, so
+// nothing that selects a shell command, repository or path is accepted.
+type JobConfig struct {
+	Samples         int     `json:"samples,omitempty"`
+	Profile         string  `json:"profile,omitempty"`
+	PerfFreq        int     `json:"perf_freq,omitempty"`
+	ZigOptimize     string  `json:"optimize,omitempty"`
+	Filter          string  `json:"filter,omitempty"`
+	FilterBenchmark string  `json:"filter_bench,omitempty"`
+	TargetCI        float64 `json:"target_ci,omitempty"`
+	MaxSamples      int     `json:"max_samples,omitempty"`
+	Notes           string  `json:"notes,omitempty"`
+}
+
+var zigOptimizeModes = map[string]bool{
+	"Debug":        true,
+	"ReleaseSafe":  true,
+	"ReleaseFast":  true,
+	"ReleaseSmall": true,
+}
+
+// ParseJobConfig decodes and validates a job's JSON config. Unknown fields
+// are rejected so typos do not silently fall back to defaults.
+func ParseJobConfig(data string) (JobConfig, error) {
+	var c JobConfig
+	if strings.TrimSpace(data) != "" {
+		dec := json.NewDecoder(strings.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&c); err != nil {
+			return c, fmt.Errorf("invalid job config: %w", err)
+		}
+	}
+	return c, c.Validate()
+}
+
+func (c JobConfig) Validate() error {
+	if c.Samples < 0 || c.Samples > 100 {
+		return fmt.Errorf("samples must be between 0 and 100 (0: default)")
+	}
+	if c.MaxSamples < 0 || c.MaxSamples > 100 {
+		return fmt.Errorf("max_samples must be between 0 and 100 (0: default)")
+	}
+	switch ProfileMode(c.Profile) {
+	case "", ProfileNone, ProfileCPU:
+	default:
+		return fmt.Errorf("invalid profile mode: %s", c.Profile)
+	}
+	if c.ZigOptimize != "" && !zigOptimizeModes[c.ZigOptimize] {
+		return fmt.Errorf("invalid optimize mode: %s", c.ZigOptimize)
+	}
+	if c.TargetCI < 0 {
+		return fmt.Errorf("target_ci must not be negative")
+	}
+	return nil
+}
+
+// Apply overrides the non-zero fields of c on top of base.
+func (c JobConfig) Apply(base RunConfig) RunConfig {
+	if c.Samples > 0 {
+		base.Samples = c.Samples
+	}
+	if c.Profile != "" {
+		base.Profile = ProfileMode(c.Profile)
+	}
+	if c.PerfFreq > 0 {
+		base.PerfFreq = c.PerfFreq
+	}
+	if c.ZigOptimize != "" {
+		base.ZigOptimize = c.ZigOptimize
+	}
+	if c.Filter != "" {
+		base.Filter = c.Filter
+	}
+	if c.FilterBenchmark != "" {
+		base.FilterBenchmark = c.FilterBenchmark
+	}
+	if c.TargetCI > 0 {
+		base.TargetCI = c.TargetCI
+	}
+	if c.MaxSamples > 0 {
+		base.MaxSamples = c.MaxSamples
+	}
+	if c.Notes != "" {
+		base.Notes = c.Notes
+	}
+	return base
+}
+
+// ValidCommitRef reports whether ref is safe to hand to git as a revision.
+// It rejects option-like and whitespace-containing values.
+func ValidCommitRef(ref string) bool {
+	if ref == "" || strings.HasPrefix(ref, "-") || len(ref) > 255 {
+		return false
+	}
+	return !strings.ContainsAny(ref, " \t\r\n\x00")
+}
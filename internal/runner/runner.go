@@ -3,12 +3,15 @@ package runner
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"strings"
 	"time"
 
 	"opentui-bench/internal/db"
 	"opentui-bench/internal/record"
+	"opentui-bench/internal/stats"
 )
 
 type ProfileMode string
@@ -19,17 +22,41 @@ const (
 )
 
 type RunConfig struct {
-	RepoPath        string
+	RepoPath string
+	Commit   string
+	// Harness selects the benchmark suite adapter (zig, command or bun).
+	// Command is the shell command (command harness) or script (bun harness)
+	// and HarnessDir the worktree-relative directory it runs in.
+	Harness         string
+	Command         string
+	HarnessDir      string
 	ZigOptimize     string
 	Filter          string
 	FilterBenchmark string
 	Benchmarks      []string
 	Samples         int
-	Profile         ProfileMode
-	PerfFreq        int
-	Notes           string
-	MachineID       string
-	WorkDir         string
+	// TargetCI enables adaptive sampling: after Samples initial samples, more
+	// are taken per benchmark until the 95% CI half-width is within TargetCI
+	// percent of the mean, or the benchmark has MaxSamples samples.
+	TargetCI float64
+	// TargetFilter limits which benchmarks must reach TargetCI (substring of
+	// the benchmark name). Empty means all benchmarks.
+	TargetFilter string
+	MaxSamples   int
+	Profile      ProfileMode
+	PerfFreq     int
+	Notes        string
+	MachineID    string
+	// Branch is recorded as the run's branch instead of the one ReadGitMeta
+	// derives from Commit, for callers that iterate a branch by commit hash.
+	Branch string
+	// WorkDir is a pooled worktree directory reused across runs. When empty,
+	// each run builds in a temporary worktree that is removed afterwards.
+	WorkDir string
+	// TimeBuild adds a zig build with an empty cache before the real build
+	// and records its duration as build_time. The real build keeps using the
+	// worktree's cache, whose state makes its own duration meaningless.
+	TimeBuild bool
 }
 
 func Run(ctx context.Context, database *db.DB, cfg RunConfig) (int64, error) {
@@ -39,10 +66,27 @@ func Run(ctx context.Context, database *db.DB, cfg RunConfig) (int64, error) {
 	if cfg.Profile == ProfileCPU && cfg.PerfFreq <= 0 {
 		cfg.PerfFreq = 997
 	}
+	if cfg.TargetCI > 0 {
+		// A confidence interval needs at least two samples.
+		if cfg.Samples < 2 {
+			cfg.Samples = 2
+		}
+		if cfg.MaxSamples < cfg.Samples {
+			cfg.MaxSamples = cfg.Samples
+		}
+	}
+
+	harness, err := NewHarness(cfg)
+	if err != nil {
+		return 0, err
+	}
+	if cfg.Profile == ProfileCPU && harness.Name() != HarnessZig {
+		return 0, fmt.Errorf("cpu profiling is only supported with the zig harness")
+	}
 
 	runner := OSRunner{}
 
-	meta, err := ReadGitMeta(ctx, cfg.RepoPath, runner)
+	meta, err := ReadGitMeta(ctx, cfg.RepoPath, cfg.Commit, runner)
 	if err != nil {
 		return 0, fmt.Errorf("read git meta: %w", err)
 	}
@@ -53,88 +97,432 @@ func Run(ctx context.Context, database *db.DB, cfg RunConfig) (int64, error) {
 	if cfg.MachineID != "" {
 		meta.MachineID = cfg.MachineID
 	}
-	meta.ZigOptimize = cfg.ZigOptimize
+	if cfg.Branch != "" {
+		meta.Branch = cfg.Branch
+	}
+	setHarness(&meta, cfg, harness)
 	meta.SampleCount = cfg.Samples
 
-	zigDir := ZigDir(cfg.RepoPath)
-	var args []string
-	if cfg.Filter != "" {
-		args = append(args, "--filter", cfg.Filter)
+	if err := ReadChangedFiles(ctx, database, cfg.RepoPath, &meta, runner); err != nil {
+		fmt.Printf("Warning: failed to read changed files: %v\n", err)
 	}
-	if cfg.FilterBenchmark != "" {
-		args = append(args, "--bench", cfg.FilterBenchmark)
+
+	// The run row is created up front so a failed build or benchmark still
+	// leaves a record of the attempt.
+	runID, err := record.CreateRun(database, meta, db.RunPending)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := execute(ctx, database, runID, cfg, harness, meta, runner); err != nil {
+		return runID, err
 	}
+	return runID, nil
+}
 
-	err = BuildZigBench(ctx, zigDir, cfg.ZigOptimize, runner)
+// execute builds and benchmarks the commit of an inserted run, moving the run
+// through its statuses. Results, profiles, build metrics and command logs are
+// committed in one transaction together with the complete status, so readers
+// never see a partially recorded run. On failure the run is marked failed
+// and the logs and the output of the failing command are stored in the same
+// transaction; when ctx is cancelled it is marked cancelled instead. A failed
+// profiling step leaves the run complete, with the reason noted, and is not
+// an error.
+func execute(ctx context.Context, database *db.DB, runID int64, cfg RunConfig, harness Harness, meta record.RunMetadata, runner CmdRunner) error {
+	stage := db.RunBuilding
+	setStatus := func(status string) {
+		stage = status
+		if err := database.SetRunStatus(runID, status, ""); err != nil {
+			fmt.Printf("Warning: failed to update run status: %v\n", err)
+		}
+	}
+	buildLog := &LogRunner{CmdRunner: runner, MaxOutput: maxLogOutput}
+	sampleLog := &LogRunner{CmdRunner: runner, MaxOutput: maxLogOutput}
+	perfLog := &LogRunner{CmdRunner: runner, MaxOutput: maxLogOutput}
+	logs := map[string]*LogRunner{
+		ArtifactBuildLog:  buildLog,
+		ArtifactSampleLog: sampleLog,
+		ArtifactPerfLog:   perfLog,
+	}
+
+	fail := func(err error) error {
+		status, reason := db.RunFailed, failureReason(stage, err)
+		// An interrupted run says nothing about the commit, so it is not
+		// stored as a failure that counts toward giving up on the commit.
+		cancelled := ctx.Err() != nil || errors.Is(err, context.Canceled)
+		if cancelled {
+			status, reason = db.RunCancelled, stage+": cancelled"
+		}
+		serr := database.InTx(func(tx *db.Tx) error {
+			if err := storeLogs(tx, runID, logs); err != nil {
+				return err
+			}
+			if !cancelled {
+				if err := storeFailure(tx, runID, stage, err); err != nil {
+					return err
+				}
+			}
+			return tx.SetRunStatus(runID, status, reason)
+		})
+		if serr != nil {
+			fmt.Printf("Warning: failed to store the output of the %s run: %v\n", status, serr)
+			if serr := database.SetRunStatus(runID, status, reason); serr != nil {
+				fmt.Printf("Warning: failed to update run status: %v\n", serr)
+			}
+		}
+		return err
+	}
+
+	setStatus(db.RunBuilding)
+
+	wt, err := PrepareWorktree(ctx, cfg.RepoPath, cfg.WorkDir, meta.CommitHashFull, runner)
 	if err != nil {
-		return 0, fmt.Errorf("build failed: %w", err)
+		return fail(fmt.Errorf("prepare worktree: %w", err))
+	}
+	defer func() {
+		// Use a fresh context so cleanup still runs after cancellation.
+		if err := wt.Close(context.Background()); err != nil {
+			fmt.Printf("Warning: failed to remove worktree %s: %v\n", wt.Path, err)
+		}
+	}()
+
+	zigDir := ZigDir(wt.Path)
+	args := harness.FilterArgs(cfg.Filter, cfg.FilterBenchmark)
+
+	var buildTime time.Duration
+	if cfg.TimeBuild && harness.Name() == HarnessZig {
+		buildStart := time.Now()
+		if err := BuildZigBenchClean(ctx, zigDir, cfg.ZigOptimize, buildLog); err != nil {
+			return fail(fmt.Errorf("build failed: %w", err))
+		}
+		buildTime = time.Since(buildStart)
+	}
+
+	if err := harness.Build(ctx, wt.Path, buildLog); err != nil {
+		return fail(fmt.Errorf("build failed: %w", err))
 	}
 
-	benchBin, err := FindBenchmarkBinary(zigDir)
+	benchBin, err := harness.Locate(wt.Path)
 	if err != nil {
-		return 0, fmt.Errorf("find benchmark binary: %w", err)
+		return fail(fmt.Errorf("find benchmark binary: %w", err))
+	}
+
+	var metrics []db.RunMetric
+	if harness.Name() == HarnessZig {
+		metrics = buildMetrics(runID, zigDir, buildTime)
+	}
+
+	setStatus(db.RunRunning)
+
+	env := ReadEnvironment(ctx, harnessZigDir(harness, wt.Path), runner)
+	if cfg.Profile == ProfileCPU && !env.ProfilingAllowed {
+		fmt.Println("Warning: perf_event_paranoid may prevent CPU profiling")
 	}
 
 	var buf bytes.Buffer
 	for i := 0; i < cfg.Samples; i++ {
-		cmdArgs := []string{"--json", "--mem"}
-		cmdArgs = append(cmdArgs, args...)
-
-		cmd := exec.CommandContext(ctx, benchBin, cmdArgs...)
-		cmd.Dir = zigDir
-		out, err := runner.CombinedOutput(ctx, cmd)
-		if err != nil {
-			return 0, fmt.Errorf("sample %d failed: %w", i+1, err)
+		if err := runSample(ctx, sampleLog, harness, wt.Path, benchBin, args, &buf); err != nil {
+			return fail(fmt.Errorf("sample %d failed: %w", i+1, err))
 		}
+	}
 
-		buf.Write(out)
-		if len(out) > 0 && out[len(out)-1] != '\n' {
-			buf.WriteByte('\n')
+	if cfg.TargetCI > 0 {
+		if err := sampleUntilTargetCI(ctx, sampleLog, harness, wt.Path, benchBin, cfg, &buf); err != nil {
+			return fail(err)
 		}
 	}
 
-	runID, count, err := record.Record(database, bytes.NewReader(buf.Bytes()), meta)
+	set, err := record.ParseSamples(bytes.NewReader(buf.Bytes()))
 	if err != nil {
-		return 0, fmt.Errorf("record results: %w", err)
+		return fail(fmt.Errorf("record results: %w", err))
 	}
 
-	_ = count
-
+	var profiles []capturedProfile
+	var profileErr error
 	if cfg.Profile == ProfileCPU {
-		if cfg.ZigOptimize != "ReleaseSafe" {
-			err = BuildZigBench(ctx, zigDir, "ReleaseSafe", runner)
-			if err != nil {
-				return runID, fmt.Errorf("profiling build failed: %w", err)
+		setStatus(db.RunProfiling)
+		profiles, profileErr = captureProfiles(ctx, zigDir, benchBin, set.Keys, cfg, buildLog, perfLog)
+	}
+
+	// A failed profiling step keeps the run usable: the results are still
+	// recorded, with a note why the profiles are missing.
+	reason := ""
+	if profileErr != nil {
+		reason = failureReason(db.RunProfiling, profileErr)
+	}
+	err = database.InTx(func(tx *db.Tx) error {
+		ids, err := record.StoreResults(tx, runID, set, env)
+		if err != nil {
+			return fmt.Errorf("record results: %w", err)
+		}
+		for _, p := range profiles {
+			if _, err := tx.InsertArtifact(&db.Artifact{
+				ResultID:  ids[p.key],
+				Kind:      p.kind,
+				DataBlob:  p.data,
+				Metadata:  fmt.Sprintf(`{"perf_freq":%d}`, cfg.PerfFreq),
+				CreatedAt: time.Now().Format(time.RFC3339),
+			}); err != nil {
+				return fmt.Errorf("store profile of %s: %w", p.key.Name, err)
 			}
-			benchBin, err = FindBenchmarkBinary(zigDir)
-			if err != nil {
-				return runID, fmt.Errorf("find benchmark binary (safe): %w", err)
+		}
+		for _, m := range metrics {
+			if err := tx.PutRunMetric(&m); err != nil {
+				return fmt.Errorf("store %s: %w", m.Name, err)
+			}
+		}
+		if err := storeLogs(tx, runID, logs); err != nil {
+			return err
+		}
+		if profileErr != nil {
+			if err := storeFailure(tx, runID, db.RunProfiling, profileErr); err != nil {
+				return err
 			}
 		}
+		return tx.SetRunStatus(runID, db.RunComplete, reason)
+	})
+	if err != nil {
+		return fail(err)
+	}
+	if profileErr != nil {
+		fmt.Printf("Warning: recorded without profiles: %v\n", profileErr)
+	}
+	return nil
+}
+
+// capturedProfile is the CPU profile of one benchmark, kept in memory until
+// the run's results are committed.
+type capturedProfile struct {
+	key  record.BenchmarkKey
+	kind string
+	data []byte
+}
 
-		results, err := database.GetResultsForRun(runID)
+// captureProfiles profiles every benchmark with perf, rebuilding with
+// ReleaseSafe first if the run used another mode.
+func captureProfiles(ctx context.Context, zigDir, benchBin string, keys []record.BenchmarkKey, cfg RunConfig, buildLog, perfLog CmdRunner) ([]capturedProfile, error) {
+	if cfg.ZigOptimize != "ReleaseSafe" {
+		if err := BuildZigBench(ctx, zigDir, "ReleaseSafe", buildLog); err != nil {
+			return nil, fmt.Errorf("profiling build failed: %w", err)
+		}
+		var err error
+		benchBin, err = FindBenchmarkBinary(zigDir)
 		if err != nil {
-			return runID, fmt.Errorf("get results for profiling: %w", err)
+			return nil, fmt.Errorf("find benchmark binary (safe): %w", err)
 		}
+	}
 
-		for _, res := range results {
-			pbGz, kind, err := CaptureCPUProfile(ctx, runner, benchBin, res.Name, cfg.PerfFreq)
-			if err != nil {
-				return runID, fmt.Errorf("profile %s: %w", res.Name, err)
+	var profiles []capturedProfile
+	for _, key := range keys {
+		pbGz, kind, err := CaptureCPUProfile(ctx, perfLog, benchBin, key.Name, cfg.PerfFreq)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", key.Name, err)
+		}
+		profiles = append(profiles, capturedProfile{key: key, kind: kind, data: pbGz})
+	}
+	return profiles, nil
+}
+
+// failureReason is the one-line summary stored on a failed run; the full
+// command output goes into a run artifact instead.
+func failureReason(stage string, err error) string {
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return stage + ": " + msg
+}
+
+// Run artifact kinds. The logs hold the transcript of every build, sample and
+// perf command of a run; the stderr artifacts only the output of the command
+// that failed it.
+const (
+	ArtifactBuildLog      = "build.log"
+	ArtifactSampleLog     = "sample.log"
+	ArtifactPerfLog       = "perf.log"
+	ArtifactBuildStderr   = "build.stderr"
+	ArtifactBenchStderr   = "bench.stderr"
+	ArtifactProfileStderr = "profile.stderr"
+)
+
+// maxLogOutput caps the output of each command in the logs. The output of a
+// failed command is stored in full in its stderr artifact.
+const maxLogOutput = 4 << 10
+
+func storeLogs(tx *db.Tx, runID int64, logs map[string]*LogRunner) error {
+	now := time.Now().Format(time.RFC3339)
+	for kind, l := range logs {
+		if l.Log.Len() == 0 {
+			continue
+		}
+		if err := tx.PutRunArtifact(runID, kind, l.Log.Bytes(), now); err != nil {
+			return fmt.Errorf("store %s: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// buildMetrics measures the artifact sizes of a zig build, together with
+// its cold build time unless buildTime is 0. A failed measurement only
+// warns; it should not fail the run.
+func buildMetrics(runID int64, zigDir string, buildTime time.Duration) []db.RunMetric {
+	var metrics []db.RunMetric
+	if buildTime > 0 {
+		metrics = append(metrics, db.RunMetric{RunID: runID, Name: MetricBuildTime, Value: buildTime.Nanoseconds(), Unit: db.MetricUnitNs})
+	}
+
+	sizes, err := BuildArtifactSizes(zigDir)
+	if err != nil {
+		fmt.Printf("Warning: failed to measure build artifacts: %v\n", err)
+	}
+	for name, size := range sizes {
+		metrics = append(metrics, db.RunMetric{RunID: runID, Name: MetricSizePrefix + name, Value: size, Unit: db.MetricUnitBytes})
+	}
+	return metrics
+}
+
+// storeFailure stores the output of the command that failed stage, if err
+// carries any.
+func storeFailure(tx *db.Tx, runID int64, stage string, err error) error {
+	var outErr *OutputError
+	if !errors.As(err, &outErr) || len(outErr.Output) == 0 {
+		return nil
+	}
+	kind := ArtifactBuildStderr
+	switch stage {
+	case db.RunRunning:
+		kind = ArtifactBenchStderr
+	case db.RunProfiling:
+		kind = ArtifactProfileStderr
+	}
+	if perr := tx.PutRunArtifact(runID, kind, outErr.Output, time.Now().Format(time.RFC3339)); perr != nil {
+		return fmt.Errorf("store %s output: %w", stage, perr)
+	}
+	return nil
+}
+
+// setHarness records the harness of a run, and the optimize mode for zig
+// runs only; other suites are not built with zig.
+func setHarness(meta *record.RunMetadata, cfg RunConfig, h Harness) {
+	meta.Harness = h.Name()
+	meta.ZigOptimize = ""
+	if h.Name() == HarnessZig {
+		meta.ZigOptimize = cfg.ZigOptimize
+	}
+}
+
+// runSample executes the suite once and appends its JSON lines to buf.
+func runSample(ctx context.Context, r CmdRunner, h Harness, root, bin string, args []string, buf *bytes.Buffer) error {
+	raw, err := h.RunSample(ctx, root, bin, args, r)
+	if err != nil {
+		return err
+	}
+	out, err := h.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(out)
+	if len(out) > 0 && out[len(out)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	return nil
+}
+
+// sampleUntilTargetCI keeps sampling benchmarks whose confidence interval is
+// still wider than cfg.TargetCI. Each extra sample runs only the unconverged
+// benchmark, so stable benchmarks stop early and noisy ones get more samples.
+// Harnesses that cannot filter re-run the whole suite once per round instead.
+func sampleUntilTargetCI(ctx context.Context, r CmdRunner, h Harness, root, bin string, cfg RunConfig, buf *bytes.Buffer) error {
+	// Benchmarks whose targeted run produced no new sample are given up on,
+	// otherwise a name the binary cannot filter by would loop forever.
+	lastCount := make(map[record.BenchmarkKey]int)
+	stalled := make(map[record.BenchmarkKey]bool)
+
+	for {
+		set, err := record.ParseSamples(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return fmt.Errorf("parse samples: %w", err)
+		}
+
+		var pending []record.BenchmarkKey
+		for _, key := range set.Keys {
+			if cfg.TargetFilter != "" && !strings.Contains(strings.ToLower(key.Name), strings.ToLower(cfg.TargetFilter)) {
+				continue
 			}
+			if set.Count(key) >= cfg.MaxSamples || stalled[key] {
+				continue
+			}
+			if prev, ok := lastCount[key]; ok && set.Count(key) <= prev {
+				stalled[key] = true
+				fmt.Printf("  Warning: no new samples for %s, giving up on target CI\n", key.Name)
+				continue
+			}
+			res := set.Aggregate(key)
+			if stats.RelativeCIHalfWidth(res.AvgNs, res.StdDevNs, res.SampleCount) > cfg.TargetCI {
+				pending = append(pending, key)
+			}
+		}
 
-			_, err = database.InsertArtifact(&db.Artifact{
-				ResultID:  res.ID,
-				Kind:      kind,
-				DataBlob:  pbGz,
-				Metadata:  fmt.Sprintf(`{"perf_freq":%d}`, cfg.PerfFreq),
-				CreatedAt: time.Now().Format(time.RFC3339),
-			})
+		if len(pending) == 0 {
+			return nil
+		}
+
+		fmt.Printf("  %d benchmarks above ±%.1f%% CI, sampling again\n", len(pending), cfg.TargetCI)
+		for _, key := range pending {
+			lastCount[key] = set.Count(key)
+		}
+
+		if h.FilterArgs("", pending[0].Name) == nil {
+			if err := runSample(ctx, r, h, root, bin, h.FilterArgs(cfg.Filter, cfg.FilterBenchmark), buf); err != nil {
+				return fmt.Errorf("extra sample failed: %w", err)
+			}
+			continue
+		}
+		for _, key := range pending {
+			var out bytes.Buffer
+			if err := runSample(ctx, r, h, root, bin, h.FilterArgs(key.Category, key.Name), &out); err != nil {
+				return fmt.Errorf("extra sample for %s failed: %w", key.Name, err)
+			}
+			lines, err := keepBenchmark(out.Bytes(), key)
 			if err != nil {
-				fmt.Printf("Warning: failed to store artifact for %s: %v\n", res.Name, err)
+				return fmt.Errorf("extra sample for %s: %w", key.Name, err)
 			}
+			buf.Write(lines)
 		}
 	}
+}
 
-	return runID, nil
+// keepBenchmark reduces the benchmark JSON lines in out to the results of
+// key. Filters match names by substring, so a targeted sample can include
+// other benchmarks, which must not get extra samples from it.
+func keepBenchmark(out []byte, key record.BenchmarkKey) ([]byte, error) {
+	var kept bytes.Buffer
+	for line := range bytes.SplitSeq(out, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var bench record.BenchmarkJSON
+		if err := json.Unmarshal(line, &bench); err != nil {
+			return nil, fmt.Errorf("parse benchmark JSON: %w", err)
+		}
+		if bench.Benchmark != key.Category {
+			continue
+		}
+		results := bench.Results[:0]
+		for _, res := range bench.Results {
+			if res.Name == key.Name {
+				results = append(results, res)
+			}
+		}
+		if len(results) == 0 {
+			continue
+		}
+		bench.Results = results
+		filtered, err := json.Marshal(bench)
+		if err != nil {
+			return nil, err
+		}
+		kept.Write(filtered)
+		kept.WriteByte('\n')
+	}
+	return kept.Bytes(), nil
 }
@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opentui-bench/internal/db"
+	"opentui-bench/internal/record"
+)
+
+// fakeRunner records the commands it is asked to run and answers them with
+// run, or with no output when run is nil.
+type fakeRunner struct {
+	calls []string
+	run   func(cmd *exec.Cmd) ([]byte, error)
+}
+
+func (f *fakeRunner) CombinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	f.calls = append(f.calls, strings.Join(cmd.Args, " "))
+	if f.run == nil {
+		return nil, nil
+	}
+	return f.run(cmd)
+}
+
+func openTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	database, err := db.Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	return database
+}
+
+// benchLine returns one benchmark JSON line with a single result.
+func benchLine(t *testing.T, category, name string, ns int64) []byte {
+	t.Helper()
+
+	line, err := json.Marshal(record.BenchmarkJSON{
+		Benchmark: category,
+		Results: []record.ResultJSON{{
+			Name:       name,
+			MinNs:      ns,
+			AvgNs:      ns,
+			MaxNs:      ns,
+			TotalNs:    ns,
+			Iterations: 1,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(line, '\n')
+}
+
+func TestSampleUntilTargetCIFiltersExactBenchmark(t *testing.T) {
+	// Every targeted sample also prints benchmarks that merely contain the
+	// name, or share it in another category, like a substring filter would.
+	n := int64(0)
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		n++
+		var out []byte
+		out = append(out, benchLine(t, "render", "frame", 100+n%2*100)...)
+		out = append(out, benchLine(t, "render", "frame large", 500)...)
+		out = append(out, benchLine(t, "text", "frame", 300)...)
+		return out, nil
+	}}
+
+	var buf bytes.Buffer
+	buf.Write(benchLine(t, "render", "frame", 100))
+	buf.Write(benchLine(t, "render", "frame", 200))
+	buf.Write(benchLine(t, "text", "frame", 300))
+	buf.Write(benchLine(t, "text", "frame", 300))
+
+	cfg := RunConfig{TargetCI: 1, MaxSamples: 5}
+	if err := sampleUntilTargetCI(context.Background(), r, zigHarness{}, t.TempDir(), "bench", cfg, &buf); err != nil {
+		t.Fatalf("sampleUntilTargetCI: %v", err)
+	}
+
+	if len(r.calls) != 3 {
+		t.Fatalf("ran %d extra samples, want 3: %v", len(r.calls), r.calls)
+	}
+	for _, call := range r.calls {
+		if !strings.HasSuffix(call, "--filter render --bench frame") {
+			t.Errorf("extra sample ran %q, want it filtered to render/frame", call)
+		}
+	}
+
+	set, err := record.ParseSamples(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[record.BenchmarkKey]int{
+		{Category: "render", Name: "frame"}:       5,
+		{Category: "render", Name: "frame large"}: 0,
+		{Category: "text", Name: "frame"}:         2,
+	} {
+		if got := set.Count(key); got != want {
+			t.Errorf("%s/%s: %d samples, want %d", key.Category, key.Name, got, want)
+		}
+	}
+}
+
+// fakeHarness runs "build" and "bench" through the CmdRunner and uses the
+// bench output as benchmark JSON as is.
+type fakeHarness struct{}
+
+func (fakeHarness) Name() string { return "fake" }
+
+func (fakeHarness) Build(ctx context.Context, root string, r CmdRunner) error {
+	if out, err := r.CombinedOutput(ctx, exec.CommandContext(ctx, "build")); err != nil {
+		return &OutputError{Err: err, Output: out}
+	}
+	return nil
+}
+
+func (fakeHarness) Locate(root string) (string, error) { return "bench", nil }
+
+func (fakeHarness) RunSample(ctx context.Context, root, bin string, args []string, r CmdRunner) (SampleOutput, error) {
+	out, err := r.CombinedOutput(ctx, exec.CommandContext(ctx, bin, args...))
+	if err != nil {
+		return SampleOutput{}, &OutputError{Err: err, Output: out}
+	}
+	return SampleOutput{Output: out}, nil
+}
+
+func (fakeHarness) Parse(out SampleOutput) ([]byte, error) { return out.Output, nil }
+
+func (fakeHarness) FilterArgs(category, benchmark string) []string { return nil }
+
+// executeRun inserts a pending run and executes it with fakeHarness and r.
+func executeRun(t *testing.T, ctx context.Context, database *db.DB, r CmdRunner) (*db.Run, error) {
+	t.Helper()
+
+	meta := record.RunMetadata{CommitHash: "abc1234", CommitHashFull: "abc1234", Harness: "fake"}
+	runID, err := record.CreateRun(database, meta, db.RunPending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := RunConfig{Samples: 2, WorkDir: t.TempDir()}
+	execErr := execute(ctx, database, runID, cfg, fakeHarness{}, meta, r)
+
+	run, err := database.GetRun(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return run, execErr
+}
+
+func TestExecuteRecordsRun(t *testing.T) {
+	database := openTestDB(t)
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		if cmd.Args[0] == "bench" {
+			return benchLine(t, "render", "frame", 100), nil
+		}
+		return nil, nil
+	}}
+
+	run, err := executeRun(t, context.Background(), database, r)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if run.Status != db.RunComplete || run.FailureReason != "" {
+		t.Fatalf("run is %s (%q), want complete", run.Status, run.FailureReason)
+	}
+	results, err := database.GetResultsForRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].SampleCount != 2 {
+		t.Fatalf("results = %+v, want one result of 2 samples", results)
+	}
+	for _, kind := range []string{ArtifactBuildLog, ArtifactSampleLog} {
+		log, err := database.GetRunArtifact(run.ID, kind)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if !bytes.Contains(log.Data, []byte("[ok after ")) {
+			t.Errorf("%s = %q", kind, log.Data)
+		}
+	}
+}
+
+func TestExecuteFailure(t *testing.T) {
+	database := openTestDB(t)
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		if cmd.Args[0] == "build" {
+			return []byte("error: undefined symbol"), errors.New("exit status 1")
+		}
+		return nil, nil
+	}}
+
+	run, err := executeRun(t, context.Background(), database, r)
+	if err == nil {
+		t.Fatal("execute succeeded, want the build error")
+	}
+	if run.Status != db.RunFailed || !strings.HasPrefix(run.FailureReason, "building: build failed") {
+		t.Fatalf("run is %s (%q), want failed while building", run.Status, run.FailureReason)
+	}
+	stderr, err := database.GetRunArtifact(run.ID, ArtifactBuildStderr)
+	if err != nil {
+		t.Fatalf("build.stderr: %v", err)
+	}
+	if string(stderr.Data) != "error: undefined symbol" {
+		t.Fatalf("build.stderr = %q", stderr.Data)
+	}
+	if _, err := database.GetRunArtifact(run.ID, ArtifactBuildLog); err != nil {
+		t.Fatalf("build.log: %v", err)
+	}
+	if failed, err := database.CountFailedRuns("abc1234"); err != nil || failed != 1 {
+		t.Fatalf("CountFailedRuns = %d, %v, want 1", failed, err)
+	}
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	database := openTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		if cmd.Args[0] == "bench" {
+			// The process is killed by the cancelled context.
+			cancel()
+			return []byte("partial output"), errors.New("signal: killed")
+		}
+		return nil, nil
+	}}
+
+	run, err := executeRun(t, ctx, database, r)
+	if err == nil {
+		t.Fatal("execute succeeded, want an error")
+	}
+	if run.Status != db.RunCancelled {
+		t.Fatalf("run is %s (%q), want cancelled", run.Status, run.FailureReason)
+	}
+	if _, err := database.GetRunArtifact(run.ID, ArtifactBenchStderr); err == nil {
+		t.Fatal("bench.stderr stored for a cancelled run")
+	}
+	if failed, err := database.CountFailedRuns("abc1234"); err != nil || failed != 0 {
+		t.Fatalf("CountFailedRuns = %d, %v, want 0", failed, err)
+	}
+}
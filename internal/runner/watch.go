@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"opentui-bench/internal/db"
+)
+
+// ErrLocked is returned by AcquireLock when another process holds the lock.
+var ErrLocked = errors.New("lock is held by another process")
+
+// FileLock is an exclusive advisory lock on a file. The kernel drops it when
+// the process exits, so a crashed watcher never leaves a stale lock behind.
+type FileLock struct {
+	f *os.File
+}
+
+// AcquireLock takes a non-blocking exclusive flock on path, creating the file
+// if needed, and writes the current pid into it for diagnostics.
+func AcquireLock(path string) (*FileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("%s: %w", path, ErrLocked)
+		}
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Release unlocks and closes the lock file.
+func (l *FileLock) Release() error {
+	if err := syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN); err != nil {
+		_ = l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// FetchRef fetches branch from remote and returns the full hash it points to.
+func FetchRef(ctx context.Context, repoPath, remote, branch string, r CmdRunner) (string, error) {
+	if _, err := runGitIn(ctx, r, repoPath, "fetch", remote, branch); err != nil {
+		return "", err
+	}
+	return runGitIn(ctx, r, repoPath, "rev-parse", "--verify", remote+"/"+branch+"^{commit}")
+}
+
+// PendingCommits walks the first-parent history of ref back to the newest
+// recorded commit and returns the unrecorded commits after it, oldest first.
+// The database is the queue: after a restart the same commits are found
+// again. If no recorded commit is found within maxDepth commits only ref
+// itself is returned, so a fresh database does not trigger a full backfill.
+// Commits that already failed maxFailures times are skipped as known-broken.
+func PendingCommits(ctx context.Context, database *db.DB, repoPath, ref string, maxDepth, maxFailures int, r CmdRunner) ([]string, error) {
+	out, err := runGitIn(ctx, r, repoPath, "rev-list", "--first-parent", fmt.Sprintf("--max-count=%d", maxDepth), ref)
+	if err != nil {
+		return nil, err
+	}
+	history := strings.Fields(out)
+	if len(history) == 0 {
+		return nil, nil
+	}
+
+	var pending []string
+	for _, hash := range history {
+		recorded, err := database.HasCommit(hash)
+		if err != nil {
+			return nil, fmt.Errorf("check commit %s: %w", shortRev(hash), err)
+		}
+		if recorded {
+			// history is newest first; reverse into chronological order.
+			for i, j := 0, len(pending)-1; i < j; i, j = i+1, j-1 {
+				pending[i], pending[j] = pending[j], pending[i]
+			}
+			return pending, nil
+		}
+		broken, err := IsKnownBroken(database, hash, maxFailures)
+		if err != nil {
+			return nil, err
+		}
+		if !broken {
+			pending = append(pending, hash)
+		}
+	}
+
+	if broken, err := IsKnownBroken(database, history[0], maxFailures); err != nil || broken {
+		return nil, err
+	}
+	return history[:1], nil
+}
+
+// IsKnownBroken reports whether commit has failed at least maxFailures times.
+// A maxFailures of zero never gives up on a commit.
+func IsKnownBroken(database *db.DB, commit string, maxFailures int) (bool, error) {
+	if maxFailures <= 0 {
+		return false, nil
+	}
+	failed, err := database.CountFailedRuns(commit)
+	if err != nil {
+		return false, fmt.Errorf("check failures of %s: %w", shortRev(commit), err)
+	}
+	return failed >= maxFailures, nil
+}
@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestPendingCommits(t *testing.T) {
+	// First-parent history of the watched ref, newest first.
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		return []byte("h6\nh5\nh4\nh3\nh2\nh1\n"), nil
+	}}
+
+	database := openTestDB(t)
+	pending, err := PendingCommits(context.Background(), database, "", "main", 100, 2, r)
+	if err != nil {
+		t.Fatalf("PendingCommits: %v", err)
+	}
+	if !slices.Equal(pending, []string{"h6"}) {
+		t.Fatalf("fresh database: pending = %v, want only the tip", pending)
+	}
+
+	for _, run := range []db.Run{
+		{CommitHashFull: "h2", Status: db.RunComplete},
+		{CommitHashFull: "h4", Status: db.RunFailed},
+		{CommitHashFull: "h4", Status: db.RunFailed},
+		{CommitHashFull: "h5", Status: db.RunFailed},
+	} {
+		run.CommitHash = run.CommitHashFull
+		run.RunDate = "2024-01-01T00:00:00Z"
+		if _, err := database.InsertRun(&run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err = PendingCommits(context.Background(), database, "", "main", 100, 2, r)
+	if err != nil {
+		t.Fatalf("PendingCommits: %v", err)
+	}
+	if want := []string{"h3", "h5", "h6"}; !slices.Equal(pending, want) {
+		t.Fatalf("pending = %v, want %v (oldest first, without the known-broken h4)", pending, want)
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.lock")
+
+	lock, err := AcquireLock(path)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if _, err := AcquireLock(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second AcquireLock error = %v, want ErrLocked", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	lock, err = AcquireLock(path)
+	if err != nil {
+		t.Fatalf("AcquireLock after release: %v", err)
+	}
+	_ = lock.Release()
+}
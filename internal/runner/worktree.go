@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Worktree is a detached git worktree of the benchmarked repo checked out at a
+// single commit. Builds happen inside it so the user's checkout is never touched.
+type Worktree struct {
+	Path      string
+	Commit    string
+	repoPath  string
+	temporary bool
+	runner    CmdRunner
+}
+
+// PrepareWorktree checks out commit in a worktree of repoPath.
+//
+// When dir is empty a temporary worktree is created and Close removes it again.
+// Otherwise dir is treated as a pooled worktree: it is created on first use and
+// reused (re-checked-out and cleaned, keeping .zig-cache) on later runs, so
+// incremental zig builds stay warm across commits.
+func PrepareWorktree(ctx context.Context, repoPath, dir, commit string, r CmdRunner) (*Worktree, error) {
+	if commit == "" {
+		commit = "HEAD"
+	}
+
+	full, err := runGitIn(ctx, r, repoPath, "rev-parse", "--verify", commit+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("resolve commit %s: %w", commit, err)
+	}
+
+	wt := &Worktree{
+		Commit:   full,
+		repoPath: repoPath,
+		runner:   r,
+	}
+
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "opentui-bench-wt-")
+		if err != nil {
+			return nil, fmt.Errorf("create worktree dir: %w", err)
+		}
+		wt.Path = tmp
+		wt.temporary = true
+		if _, err := runGitIn(ctx, r, repoPath, "worktree", "add", "--detach", "--force", tmp, full); err != nil {
+			_ = os.RemoveAll(tmp)
+			return nil, err
+		}
+		return wt, nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve worktree dir: %w", err)
+	}
+	wt.Path = abs
+
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+		if _, err := runGitIn(ctx, r, abs, "checkout", "--detach", "--force", full); err != nil {
+			return nil, err
+		}
+		if _, err := runGitIn(ctx, r, abs, "clean", "-ffdx", "-e", ".zig-cache"); err != nil {
+			return nil, err
+		}
+		return wt, nil
+	}
+
+	// Drop stale registrations, e.g. when a pooled dir was deleted by hand.
+	_, _ = runGitIn(ctx, r, repoPath, "worktree", "prune")
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		return nil, fmt.Errorf("create worktree parent: %w", err)
+	}
+	if _, err := runGitIn(ctx, r, repoPath, "worktree", "add", "--detach", "--force", abs, full); err != nil {
+		return nil, err
+	}
+	return wt, nil
+}
+
+// Close removes a temporary worktree. Pooled worktrees are left in place.
+func (w *Worktree) Close(ctx context.Context) error {
+	if w == nil || !w.temporary {
+		return nil
+	}
+	_, err := runGitIn(ctx, w.runner, w.repoPath, "worktree", "remove", "--force", w.Path)
+	if err != nil {
+		// Fall back to deleting the directory and pruning the registration.
+		_ = os.RemoveAll(w.Path)
+		_, _ = runGitIn(ctx, w.runner, w.repoPath, "worktree", "prune")
+	}
+	return err
+}
+
+func runGitIn(ctx context.Context, r CmdRunner, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := r.CombinedOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("git %v: %w (%s)", args, err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
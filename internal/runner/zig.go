@@ -21,11 +21,75 @@ func BuildZigBench(ctx context.Context, zigDir string, optimize string, r CmdRun
 
 	out, err := r.CombinedOutput(ctx, cmd)
 	if err != nil {
-		return fmt.Errorf("zig build failed: %w\n%s", err, strings.TrimSpace(string(out)))
+		return &OutputError{Err: fmt.Errorf("zig build failed: %w", err), Output: out}
 	}
 	return nil
 }
 
+// BuildZigBenchClean builds like BuildZigBench, but with an empty local zig
+// cache that is removed afterwards, so the time it takes is that of a full
+// build whether or not the worktree was built before, and the worktree's
+// cache is left as it was. The global zig cache (the toolchain's own
+// libraries) is used as usual; it is the same for every commit.
+func BuildZigBenchClean(ctx context.Context, zigDir string, optimize string, r CmdRunner) error {
+	cacheDir, err := os.MkdirTemp("", "opentui-bench-zig-cache-")
+	if err != nil {
+		return fmt.Errorf("create zig cache dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(cacheDir) }()
+
+	cmd := exec.CommandContext(ctx, "zig", "build", "-Doptimize="+optimize, "--cache-dir", cacheDir)
+	cmd.Dir = zigDir
+
+	out, err := r.CombinedOutput(ctx, cmd)
+	if err != nil {
+		return &OutputError{Err: fmt.Errorf("zig build failed: %w", err), Output: out}
+	}
+	return nil
+}
+
+// Run metrics recorded for zig builds. Artifact sizes are stored under
+// MetricSizePrefix followed by the artifact's file name.
+const (
+	MetricBuildTime  = "build_time"
+	MetricSizePrefix = "size:"
+)
+
+// BuildArtifactSizes returns the sizes of the opentui-bench binary and the
+// shared libraries installed under zig-out, keyed by file name. Symlinks to
+// versioned libraries are skipped so each library is counted once.
+func BuildArtifactSizes(zigDir string) (map[string]int64, error) {
+	out := filepath.Join(zigDir, "zig-out")
+	sizes := make(map[string]int64)
+
+	info, err := os.Stat(filepath.Join(out, "bin", "opentui-bench"))
+	if err != nil {
+		return nil, err
+	}
+	sizes[info.Name()] = info.Size()
+
+	entries, err := os.ReadDir(filepath.Join(out, "lib"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !isSharedLibrary(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		sizes[e.Name()] = info.Size()
+	}
+	return sizes, nil
+}
+
+func isSharedLibrary(name string) bool {
+	return strings.HasSuffix(name, ".so") || strings.Contains(name, ".so.") ||
+		strings.HasSuffix(name, ".dylib") || strings.HasSuffix(name, ".dll")
+}
+
 func FindBenchmarkBinary(zigDir string) (string, error) {
 	// Try standard install location first (from zig build)
 	binPath := filepath.Join(zigDir, "zig-out", "bin", "opentui-bench")
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"opentui-bench/internal/db"
+)
+
+func TestBuildZigBenchCleanUsesEmptyCache(t *testing.T) {
+	var cacheDir string
+	r := &fakeRunner{run: func(cmd *exec.Cmd) ([]byte, error) {
+		i := slices.Index(cmd.Args, "--cache-dir")
+		if i < 0 || i+1 == len(cmd.Args) {
+			t.Fatalf("zig build without --cache-dir: %v", cmd.Args)
+		}
+		cacheDir = cmd.Args[i+1]
+		entries, err := os.ReadDir(cacheDir)
+		if err != nil || len(entries) != 0 {
+			t.Fatalf("cache dir %s: %d entries, %v; want an empty dir", cacheDir, len(entries), err)
+		}
+		return nil, nil
+	}}
+
+	if err := BuildZigBenchClean(context.Background(), t.TempDir(), "ReleaseFast", r); err != nil {
+		t.Fatalf("BuildZigBenchClean: %v", err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatalf("cache dir %s left behind: %v", cacheDir, err)
+	}
+}
+
+func TestZigBuildKeepsWorktreeCache(t *testing.T) {
+	r := &fakeRunner{}
+	if err := (zigHarness{optimize: "ReleaseFast"}).Build(context.Background(), t.TempDir(), r); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if want := []string{"zig build -Doptimize=ReleaseFast"}; !slices.Equal(r.calls, want) {
+		t.Fatalf("calls = %v, want %v", r.calls, want)
+	}
+}
+
+func TestBuildMetrics(t *testing.T) {
+	zigDir := t.TempDir()
+	for path, size := range map[string]int{
+		"zig-out/bin/opentui-bench":     300,
+		"zig-out/lib/libopentui.so.1.0": 200,
+		"zig-out/lib/libopentui.a":      100,
+	} {
+		path = filepath.Join(zigDir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("libopentui.so.1.0", filepath.Join(zigDir, "zig-out/lib/libopentui.so")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]db.RunMetric)
+	for _, m := range buildMetrics(7, zigDir, 2*time.Second) {
+		got[m.Name] = m
+	}
+	want := map[string]db.RunMetric{
+		MetricBuildTime:                        {RunID: 7, Name: MetricBuildTime, Value: int64(2 * time.Second), Unit: db.MetricUnitNs},
+		MetricSizePrefix + "opentui-bench":     {RunID: 7, Name: MetricSizePrefix + "opentui-bench", Value: 300, Unit: db.MetricUnitBytes},
+		MetricSizePrefix + "libopentui.so.1.0": {RunID: 7, Name: MetricSizePrefix + "libopentui.so.1.0", Value: 200, Unit: db.MetricUnitBytes},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("metrics = %+v, want %+v", got, want)
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("%s = %+v, want %+v", name, got[name], w)
+		}
+	}
+
+	// Without a timed cold build only the sizes are recorded.
+	for _, m := range buildMetrics(7, zigDir, 0) {
+		if m.Name == MetricBuildTime {
+			t.Fatalf("build_time recorded without a timed build: %+v", m)
+		}
+	}
+}
@@ -60,3 +60,17 @@ func MeanCI95(avgNs, stdDevNs, sampleCount int64) (lower, upper, sem int64) {
 
 	return int64(math.Round(lowerF)), int64(math.Round(upperF)), int64(math.Round(semF))
 }
+
+// RelativeCIHalfWidth returns the half-width of the 95% CI of the mean as a
+// percentage of the mean. It returns +Inf when fewer than two samples exist,
+// since the interval is then undefined rather than zero.
+func RelativeCIHalfWidth(avgNs, stdDevNs, sampleCount int64) float64 {
+	if sampleCount < 2 {
+		return math.Inf(1)
+	}
+	if avgNs <= 0 {
+		return 0
+	}
+	_, upper, _ := MeanCI95(avgNs, stdDevNs, sampleCount)
+	return float64(upper-avgNs) / float64(avgNs) * 100
+}
@@ -58,3 +58,15 @@ func TestMeanCI95(t *testing.T) {
 		}
 	})
 }
+
+func TestRelativeCIHalfWidth(t *testing.T) {
+	if w := RelativeCIHalfWidth(100, 10, 1); !math.IsInf(w, 1) {
+		t.Fatalf("expected +Inf for a single sample, got %f", w)
+	}
+
+	_, upper, _ := MeanCI95(1000, 20, 10)
+	want := float64(upper-1000) / 1000 * 100
+	if w := RelativeCIHalfWidth(1000, 20, 10); w != want {
+		t.Fatalf("expected %f, got %f", want, w)
+	}
+}
@@ -0,0 +1,50 @@
+package stats
+
+import "math"
+
+// MeanComparison is the outcome of a two-sample comparison of benchmark means.
+type MeanComparison struct {
+	ChangePercent float64 // (head - base) / base * 100
+	T             float64 // Welch t-statistic, positive when head is slower
+	DF            float64 // Welch-Satterthwaite degrees of freedom
+	Significant   bool    // Two-sided test at 95% confidence
+	Sufficient    bool    // false when either side has fewer than 2 samples or no variance
+}
+
+// CompareMeans runs a two-sided Welch t-test between base and head.
+// Unlike DetectRegression it makes no assumption about run history, which
+// makes it suitable for interleaved A/B runs where both sides were sampled
+// under the same conditions.
+func CompareMeans(base, head RunStat) MeanComparison {
+	var c MeanComparison
+	if base.Mean > 0 {
+		c.ChangePercent = (head.Mean - base.Mean) / base.Mean * 100.0
+	}
+
+	if base.SampleCount < 2 || head.SampleCount < 2 {
+		return c
+	}
+
+	baseVar := base.Sem * base.Sem
+	headVar := head.Sem * head.Sem
+	seDiff2 := baseVar + headVar
+	if seDiff2 == 0 {
+		return c
+	}
+
+	c.Sufficient = true
+	c.T = (head.Mean - base.Mean) / math.Sqrt(seDiff2)
+	c.DF = seDiff2 * seDiff2 / (baseVar*baseVar/float64(base.SampleCount-1) + headVar*headVar/float64(head.SampleCount-1))
+
+	tCrit := 1.96
+	df := int(math.Floor(c.DF))
+	if df < 1 {
+		df = 1
+	}
+	if df < len(tCritical95) {
+		tCrit = tCritical95[df]
+	}
+	c.Significant = math.Abs(c.T) > tCrit
+
+	return c
+}
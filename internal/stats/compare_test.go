@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareMeans(t *testing.T) {
+	t.Run("insufficient with single samples", func(t *testing.T) {
+		c := CompareMeans(
+			RunStat{Mean: 100, SampleCount: 1},
+			RunStat{Mean: 120, SampleCount: 1},
+		)
+		if c.Sufficient || c.Significant {
+			t.Fatalf("expected insufficient comparison, got %+v", c)
+		}
+		if math.Abs(c.ChangePercent-20) > 1e-9 {
+			t.Fatalf("expected change 20%%, got %f", c.ChangePercent)
+		}
+	})
+
+	t.Run("flags clear slowdown", func(t *testing.T) {
+		c := CompareMeans(
+			RunStat{Mean: 1000, Sem: 5, StdDev: 15.8, SampleCount: 10},
+			RunStat{Mean: 1100, Sem: 5, StdDev: 15.8, SampleCount: 10},
+		)
+		if !c.Sufficient || !c.Significant {
+			t.Fatalf("expected significant comparison, got %+v", c)
+		}
+		if c.T <= 0 {
+			t.Fatalf("expected positive t for slowdown, got %f", c.T)
+		}
+		if math.Abs(c.DF-18) > 1e-9 {
+			t.Fatalf("expected df=18 for equal variances, got %f", c.DF)
+		}
+	})
+
+	t.Run("ignores change within noise", func(t *testing.T) {
+		c := CompareMeans(
+			RunStat{Mean: 1000, Sem: 50, StdDev: 158, SampleCount: 10},
+			RunStat{Mean: 1020, Sem: 50, StdDev: 158, SampleCount: 10},
+		)
+		if !c.Sufficient || c.Significant {
+			t.Fatalf("expected non-significant comparison, got %+v", c)
+		}
+	})
+}
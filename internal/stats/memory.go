@@ -0,0 +1,123 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// MemPoint summarizes one memory statistic of a single run.
+type MemPoint struct {
+	RunID int64
+	Mean  float64 // Average across the run's samples
+	Min   float64 // Smallest sample
+}
+
+// MemThreshold is how much a memory statistic must grow over its baseline
+// to count as a regression. An increase must exceed both limits; set one to
+// zero to rely on the other alone.
+type MemThreshold struct {
+	Bytes   float64
+	Percent float64
+}
+
+// MemBaseline is the reference level of a memory statistic.
+type MemBaseline struct {
+	RunID  int64   // ID of the run closest to the median
+	Median float64 // Median of the per-run means
+}
+
+// MemRegressionResult is the outcome of memory regression detection.
+type MemRegressionResult struct {
+	Status        string // "ok", "regressed", "insufficient"
+	ChangeBytes   float64
+	ChangePercent float64
+}
+
+// ComputeMemBaseline returns the median of the run means in history, which
+// must be ordered newest-first. baselineOffset and minPoints behave as in
+// ComputeBaseline. Memory usage is often identical across samples, so unlike
+// timings no variance is required; the median keeps one-off spikes in the
+// window from moving the baseline.
+func ComputeMemBaseline(history []MemPoint, minPoints int, baselineOffset int) (*MemBaseline, error) {
+	if baselineOffset < 0 {
+		baselineOffset = 0
+	}
+	if baselineOffset >= len(history) {
+		return nil, ErrInsufficientData
+	}
+	history = history[baselineOffset:]
+	if len(history) == 0 || len(history) < minPoints {
+		return nil, ErrInsufficientData
+	}
+
+	means := make([]float64, len(history))
+	for i, p := range history {
+		means[i] = p.Mean
+	}
+	sort.Float64s(means)
+	median := means[len(means)/2]
+	if len(means)%2 == 0 {
+		median = (means[len(means)/2-1] + median) / 2
+	}
+
+	baseline := &MemBaseline{Median: median}
+	minDist := math.MaxFloat64
+	for _, p := range history {
+		if dist := math.Abs(p.Mean - median); dist < minDist {
+			minDist = dist
+			baseline.RunID = p.RunID
+		}
+	}
+	return baseline, nil
+}
+
+// DetectMemRegression reports whether latest grew beyond threshold over the
+// baseline. Only sustained growth counts: even the smallest sample of the
+// run has to exceed the threshold. The reported change uses the run mean.
+func DetectMemRegression(latest MemPoint, baseline *MemBaseline, threshold MemThreshold) MemRegressionResult {
+	if baseline == nil {
+		return MemRegressionResult{Status: "insufficient"}
+	}
+
+	result := MemRegressionResult{
+		Status:      "ok",
+		ChangeBytes: latest.Mean - baseline.Median,
+	}
+	if baseline.Median > 0 {
+		result.ChangePercent = result.ChangeBytes / baseline.Median * 100
+	}
+	if exceedsMemThreshold(latest.Min, baseline.Median, threshold) {
+		result.Status = "regressed"
+	}
+	return result
+}
+
+// FindMemIntroducingRun returns the first run of the trailing streak of
+// regressed runs in history, which must be in chronological order (oldest
+// first). Returns nil if the newest run is not regressed.
+func FindMemIntroducingRun(history []MemPoint, baseline *MemBaseline, threshold MemThreshold) *int64 {
+	if baseline == nil {
+		return nil
+	}
+
+	var introducing *int64
+	for i := len(history) - 1; i >= 0; i-- {
+		if !exceedsMemThreshold(history[i].Min, baseline.Median, threshold) {
+			break
+		}
+		id := history[i].RunID
+		introducing = &id
+	}
+	return introducing
+}
+
+func exceedsMemThreshold(value, baseline float64, threshold MemThreshold) bool {
+	increase := value - baseline
+	if increase <= 0 || increase <= threshold.Bytes {
+		return false
+	}
+	if baseline <= 0 {
+		return true
+	}
+	return increase/baseline*100 > threshold.Percent
+}
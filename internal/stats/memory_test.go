@@ -0,0 +1,81 @@
+package stats
+
+import "testing"
+
+func TestComputeMemBaseline(t *testing.T) {
+	history := []MemPoint{
+		{RunID: 6, Mean: 900}, // skipped by the offset
+		{RunID: 5, Mean: 100},
+		{RunID: 4, Mean: 5000}, // spike
+		{RunID: 3, Mean: 110},
+		{RunID: 2, Mean: 105},
+		{RunID: 1, Mean: 108},
+	}
+
+	baseline, err := ComputeMemBaseline(history, 3, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if baseline.Median != 108 || baseline.RunID != 1 {
+		t.Fatalf("unexpected baseline: %+v", baseline)
+	}
+
+	if _, err := ComputeMemBaseline(history, 6, 1); err != ErrInsufficientData {
+		t.Fatalf("expected insufficient data, got %v", err)
+	}
+}
+
+func TestDetectMemRegression(t *testing.T) {
+	baseline := &MemBaseline{RunID: 1, Median: 1000}
+
+	cases := []struct {
+		name      string
+		latest    MemPoint
+		threshold MemThreshold
+		want      string
+	}{
+		{"within percent", MemPoint{Mean: 1040, Min: 1040}, MemThreshold{Percent: 5}, "ok"},
+		{"beyond percent", MemPoint{Mean: 1100, Min: 1100}, MemThreshold{Percent: 5}, "regressed"},
+		{"within bytes", MemPoint{Mean: 1100, Min: 1100}, MemThreshold{Bytes: 200}, "ok"},
+		{"beyond bytes", MemPoint{Mean: 1300, Min: 1300}, MemThreshold{Bytes: 200}, "regressed"},
+		{"needs both limits", MemPoint{Mean: 1100, Min: 1100}, MemThreshold{Bytes: 200, Percent: 5}, "ok"},
+		{"spike within run", MemPoint{Mean: 1500, Min: 1000}, MemThreshold{Percent: 5}, "ok"},
+		{"decrease", MemPoint{Mean: 500, Min: 500}, MemThreshold{}, "ok"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DetectMemRegression(tc.latest, baseline, tc.threshold); got.Status != tc.want {
+				t.Fatalf("expected %s, got %+v", tc.want, got)
+			}
+		})
+	}
+
+	if got := DetectMemRegression(MemPoint{Mean: 1}, nil, MemThreshold{}); got.Status != "insufficient" {
+		t.Fatalf("expected insufficient without baseline, got %s", got.Status)
+	}
+}
+
+func TestFindMemIntroducingRun(t *testing.T) {
+	baseline := &MemBaseline{RunID: 1, Median: 100}
+	threshold := MemThreshold{Percent: 5}
+
+	// Run 2 spikes once; the sustained increase starts at run 4.
+	history := []MemPoint{
+		{RunID: 1, Mean: 100, Min: 100},
+		{RunID: 2, Mean: 150, Min: 150},
+		{RunID: 3, Mean: 100, Min: 100},
+		{RunID: 4, Mean: 130, Min: 130},
+		{RunID: 5, Mean: 131, Min: 131},
+	}
+	got := FindMemIntroducingRun(history, baseline, threshold)
+	if got == nil || *got != 4 {
+		t.Fatalf("expected run 4, got %v", got)
+	}
+
+	if got := FindMemIntroducingRun(history[:4], baseline, threshold); got == nil || *got != 4 {
+		t.Fatalf("expected run 4 for a single regressed run, got %v", got)
+	}
+	if got := FindMemIntroducingRun(history[:3], baseline, threshold); got != nil {
+		t.Fatalf("expected nil when the newest run is fine, got %d", *got)
+	}
+}
@@ -0,0 +1,60 @@
+package stats
+
+import "math"
+
+// PoolRuns combines the summaries of several runs of one commit into a single
+// estimate. The mean and standard deviation are those of all samples taken
+// together: the mean is weighted by sample count and the variance includes
+// the spread between the run means. Samples of different runs are not
+// independent draws (each run has its own machine state), so the SEM is the
+// larger of the samples' SEM and the between-run SEM, the standard
+// deviation of the run means over the square root of the number of runs;
+// disagreeing runs widen the pooled CI however many samples they have. The
+// pooled stat keeps the RunID of the first run.
+func PoolRuns(runs []RunStat) RunStat {
+	if len(runs) == 0 {
+		return RunStat{}
+	}
+	if len(runs) == 1 {
+		return runs[0]
+	}
+
+	weight := func(r RunStat) float64 {
+		return math.Max(float64(r.SampleCount), 1)
+	}
+
+	var n, sum float64
+	for _, r := range runs {
+		n += weight(r)
+		sum += weight(r) * r.Mean
+	}
+	pooledMean := sum / n
+
+	var sumSquares float64
+	for _, r := range runs {
+		k := weight(r)
+		d := r.Mean - pooledMean
+		sumSquares += (k-1)*r.StdDev*r.StdDev + k*d*d
+	}
+
+	pooled := RunStat{
+		RunID:       runs[0].RunID,
+		Mean:        pooledMean,
+		SampleCount: int64(n),
+	}
+	pooled.StdDev = math.Sqrt(sumSquares / (n - 1))
+
+	k := float64(len(runs))
+	var meanOfMeans float64
+	for _, r := range runs {
+		meanOfMeans += r.Mean / k
+	}
+	var spread float64
+	for _, r := range runs {
+		d := r.Mean - meanOfMeans
+		spread += d * d
+	}
+	betweenSem := math.Sqrt(spread/(k-1)) / math.Sqrt(k)
+	pooled.Sem = math.Max(pooled.StdDev/math.Sqrt(n), betweenSem)
+	return pooled
+}
@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPoolRuns(t *testing.T) {
+	t.Run("single run is unchanged", func(t *testing.T) {
+		run := RunStat{RunID: 7, Mean: 100, Sem: 2, StdDev: 6, SampleCount: 9}
+		if got := PoolRuns([]RunStat{run}); got != run {
+			t.Fatalf("expected %+v, got %+v", run, got)
+		}
+	})
+
+	t.Run("matches statistics of all samples", func(t *testing.T) {
+		// Samples {1, 2, 3} and {5, 7}; together mean 3.6, variance 5.8.
+		got := PoolRuns([]RunStat{
+			{RunID: 2, Mean: 2, StdDev: 1, SampleCount: 3},
+			{RunID: 1, Mean: 6, StdDev: math.Sqrt2, SampleCount: 2},
+		})
+		if got.RunID != 2 || got.SampleCount != 5 {
+			t.Fatalf("unexpected pooled run: %+v", got)
+		}
+		if math.Abs(got.Mean-3.6) > 1e-9 {
+			t.Fatalf("expected mean 3.6, got %f", got.Mean)
+		}
+		if math.Abs(got.StdDev-math.Sqrt(5.8)) > 1e-9 {
+			t.Fatalf("expected stddev %f, got %f", math.Sqrt(5.8), got.StdDev)
+		}
+		// The run means 2 and 6 disagree more than the samples' SEM
+		// (sqrt(5.8/5)) suggests: between-run SEM sqrt(8)/sqrt(2).
+		if math.Abs(got.Sem-2) > 1e-9 {
+			t.Fatalf("expected sem 2, got %f", got.Sem)
+		}
+	})
+
+	t.Run("disagreeing runs are not averaged away by sample count", func(t *testing.T) {
+		// Two tight runs of 100 samples whose means differ by 10.
+		got := PoolRuns([]RunStat{
+			{RunID: 1, Mean: 100, StdDev: 1, SampleCount: 100},
+			{RunID: 2, Mean: 110, StdDev: 1, SampleCount: 100},
+		})
+		if math.Abs(got.Sem-5) > 1e-9 {
+			t.Fatalf("expected sem 5 (half the difference), got %f", got.Sem)
+		}
+	})
+
+	t.Run("agreeing runs keep the SEM of all samples", func(t *testing.T) {
+		got := PoolRuns([]RunStat{
+			{RunID: 1, Mean: 100, StdDev: 10, SampleCount: 50},
+			{RunID: 2, Mean: 100, StdDev: 10, SampleCount: 50},
+		})
+		if want := got.StdDev / 10; math.Abs(got.Sem-want) > 1e-9 {
+			t.Fatalf("expected sem %f, got %f", want, got.Sem)
+		}
+	})
+
+	t.Run("single-sample runs contribute their spread", func(t *testing.T) {
+		got := PoolRuns([]RunStat{
+			{RunID: 1, Mean: 10, SampleCount: 1},
+			{RunID: 2, Mean: 20, SampleCount: 1},
+		})
+		if got.SampleCount != 2 || got.Mean != 15 || got.StdDev == 0 {
+			t.Fatalf("unexpected pooled run: %+v", got)
+		}
+	})
+}
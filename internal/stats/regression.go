@@ -98,7 +98,8 @@ func TCriticalOneSided(df int, alpha float64) float64 {
 // Returns nil if there are fewer than minPoints valid runs.
 //
 // baselineOffset skips the most recent N runs in history. history must be ordered
-// newest-first when baselineOffset > 0.
+// newest-first when baselineOffset > 0; run IDs need not follow that order, since
+// commits may be recorded out of history order.
 //
 // The returned BaselineStats contains:
 // - Mean: weighted mean from the random-effects model (used for detection)
@@ -110,9 +111,6 @@ func ComputeBaseline(history []RunStat, minPoints int, baselineOffset int) (*Bas
 	if baselineOffset < 0 {
 		baselineOffset = 0
 	}
-	if baselineOffset > 0 && !isOrderedNewestFirst(history) {
-		return nil, ErrInsufficientData
-	}
 	if baselineOffset >= len(history) {
 		return nil, ErrInsufficientData
 	}
@@ -311,18 +309,6 @@ func FindIntroducingRun(history []RunStat, baseline *BaselineStats, alpha float6
 
 // Helper functions
 
-func isOrderedNewestFirst(history []RunStat) bool {
-	if len(history) < 2 {
-		return true
-	}
-	for i := 1; i < len(history); i++ {
-		if history[i].RunID > history[i-1].RunID {
-			return false
-		}
-	}
-	return true
-}
-
 func mean(values []float64) float64 {
 	if len(values) == 0 {
 		return 0
@@ -0,0 +1,34 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestComputeBaselineOutOfOrderRunIDs(t *testing.T) {
+	// Newest first in history; the older commits were backfilled, so their
+	// run IDs are higher than those of newer ones.
+	history := []RunStat{
+		{RunID: 2, Mean: 200, Sem: 2, SampleCount: 10, StdDev: 6},
+		{RunID: 1, Mean: 101, Sem: 2, SampleCount: 10, StdDev: 6},
+		{RunID: 7, Mean: 99, Sem: 2, SampleCount: 10, StdDev: 6},
+		{RunID: 5, Mean: 100, Sem: 2, SampleCount: 10, StdDev: 6},
+		{RunID: 9, Mean: 102, Sem: 2, SampleCount: 10, StdDev: 6},
+	}
+
+	baseline, err := ComputeBaseline(history, 3, 1)
+	if err != nil {
+		t.Fatalf("ComputeBaseline: %v", err)
+	}
+	// The offset skips the newest run by position, not by ID.
+	if baseline.Mean < 99 || baseline.Mean > 102 {
+		t.Fatalf("baseline mean = %.1f, want the mean of the four older runs", baseline.Mean)
+	}
+	if baseline.RunID == 2 {
+		t.Fatal("baseline picked the skipped newest run")
+	}
+
+	if _, err := ComputeBaseline(history, 5, 1); !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("ComputeBaseline with too few runs after the offset: %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -19,6 +21,8 @@ import (
 	"github.com/google/pprof/profile"
 
 	"opentui-bench/internal/db"
+	"opentui-bench/internal/record"
+	"opentui-bench/internal/runner"
 	"opentui-bench/internal/stats"
 )
 
@@ -46,16 +50,23 @@ func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
 		Branch        string `json:"branch"`
 		RunDate       string `json:"run_date"`
 		Notes         string `json:"notes"`
+		Status        string `json:"status"`
+		FailureReason string `json:"failure_reason,omitempty"`
 		ResultCount   int    `json:"result_count"`
 	}
 
+	runIDs := make([]int64, len(runs))
+	for i, run := range runs {
+		runIDs[i] = run.ID
+	}
+	counts, err := s.db.CountResultsForRuns(runIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	var response []runResponse
 	for _, run := range runs {
-		count, err := s.db.CountResultsForRun(run.ID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
 		response = append(response, runResponse{
 			ID:            run.ID,
 			CommitHash:    run.CommitHash,
@@ -63,7 +74,9 @@ func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
 			Branch:        run.Branch,
 			RunDate:       run.RunDate,
 			Notes:         run.Notes,
-			ResultCount:   count,
+			Status:        run.Status,
+			FailureReason: run.FailureReason,
+			ResultCount:   counts[run.ID],
 		})
 	}
 
@@ -73,6 +86,136 @@ func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+type jobResponse struct {
+	ID         int64           `json:"id"`
+	Commit     string          `json:"commit"`
+	Config     json.RawMessage `json:"config"`
+	Priority   int             `json:"priority"`
+	Status     string          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	RunID      *int64          `json:"run_id"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	StartedAt  string          `json:"started_at,omitempty"`
+	FinishedAt string          `json:"finished_at,omitempty"`
+}
+
+func newJobResponse(j db.Job) jobResponse {
+	resp := jobResponse{
+		ID:         j.ID,
+		Commit:     j.Commit,
+		Config:     json.RawMessage(j.Config),
+		Priority:   j.Priority,
+		Status:     j.Status,
+		Attempts:   j.Attempts,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+	}
+	if j.RunID != 0 {
+		resp.RunID = &j.RunID
+	}
+	return resp
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleListJobs(w, r)
+	case http.MethodPost:
+		s.handleCreateJob(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	jobs, err := s.db.ListJobs(limit, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]jobResponse, 0, len(jobs))
+	for _, j := range jobs {
+		response = append(response, newJobResponse(j))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
+	// Without a token anyone could queue jobs, so submissions are disabled.
+	if s.jobsToken == "" {
+		http.Error(w, "job submission is disabled: JOBS_TOKEN is not set", http.StatusForbidden)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.jobsToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Commit   string          `json:"commit"`
+		Priority int             `json:"priority"`
+		Config   json.RawMessage `json:"config"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !runner.ValidCommitRef(req.Commit) {
+		http.Error(w, "invalid commit", http.StatusBadRequest)
+		return
+	}
+
+	config := "{}"
+	if len(req.Config) > 0 && string(req.Config) != "null" {
+		config = string(req.Config)
+	}
+	if _, err := runner.ParseJobConfig(config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.db.InsertJob(&db.Job{
+		Commit:    req.Commit,
+		Config:    config,
+		Priority:  req.Priority,
+		CreatedAt: time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	job, err := s.db.GetJob(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(newJobResponse(*job)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 	idStr := strings.TrimPrefix(r.URL.Path, "/api/runs/")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -98,8 +241,11 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type memStatResponse struct {
-		Name  string `json:"name"`
-		Bytes int64  `json:"bytes"`
+		Name        string `json:"name"`
+		Bytes       int64  `json:"bytes"`
+		MinBytes    int64  `json:"min_bytes"`
+		MaxBytes    int64  `json:"max_bytes"`
+		SampleCount int64  `json:"sample_count"`
 	}
 
 	type resultResponse struct {
@@ -118,14 +264,33 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		MemStats    []memStatResponse `json:"mem_stats,omitempty"`
 	}
 
+	type environmentResponse struct {
+		Fingerprint       string `json:"fingerprint"`
+		CPUModel          string `json:"cpu_model"`
+		CPUCores          int    `json:"cpu_cores"`
+		KernelVersion     string `json:"kernel_version"`
+		CPUGovernor       string `json:"cpu_governor,omitempty"`
+		ZigVersion        string `json:"zig_version"`
+		GoVersion         string `json:"go_version"`
+		BenchVersion      string `json:"bench_version,omitempty"`
+		PerfEventParanoid *int64 `json:"perf_event_paranoid,omitempty"`
+		ProfilingAllowed  bool   `json:"profiling_allowed"`
+	}
+
 	type runDetailResponse struct {
-		ID            int64            `json:"id"`
-		CommitHash    string           `json:"commit_hash"`
-		CommitMessage string           `json:"commit_message"`
-		Branch        string           `json:"branch"`
-		RunDate       string           `json:"run_date"`
-		Notes         string           `json:"notes"`
-		Results       []resultResponse `json:"results"`
+		ID            int64                `json:"id"`
+		CommitHash    string               `json:"commit_hash"`
+		CommitMessage string               `json:"commit_message"`
+		Branch        string               `json:"branch"`
+		RunDate       string               `json:"run_date"`
+		Notes         string               `json:"notes"`
+		Status        string               `json:"status"`
+		FailureReason string               `json:"failure_reason,omitempty"`
+		Commit        *commitResponse      `json:"commit,omitempty"`
+		Environment   *environmentResponse `json:"environment,omitempty"`
+		Logs          []runLogResponse     `json:"logs"`
+		Metrics       []runMetricResponse  `json:"metrics"`
+		Results       []resultResponse     `json:"results"`
 	}
 
 	var resultResponses []resultResponse
@@ -146,8 +311,11 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		}
 		for _, ms := range res.MemStats {
 			rr.MemStats = append(rr.MemStats, memStatResponse{
-				Name:  ms.StatName,
-				Bytes: ms.Bytes,
+				Name:        ms.StatName,
+				Bytes:       ms.Bytes,
+				MinBytes:    ms.MinBytes,
+				MaxBytes:    ms.MaxBytes,
+				SampleCount: ms.SampleCount,
 			})
 		}
 		resultResponses = append(resultResponses, rr)
@@ -160,9 +328,79 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		Branch:        run.Branch,
 		RunDate:       run.RunDate,
 		Notes:         run.Notes,
+		Status:        run.Status,
+		FailureReason: run.FailureReason,
 		Results:       resultResponses,
 	}
 
+	commit, err := s.db.GetCommitInfo(id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if commit != nil {
+		response.Commit = &commitResponse{
+			AuthorName:   commit.AuthorName,
+			AuthorEmail:  commit.AuthorEmail,
+			Body:         commit.Body,
+			Parents:      commit.Parents,
+			Tags:         commit.Tags,
+			DiffBase:     commit.DiffBase,
+			ChangedFiles: make([]changedFileResponse, 0, len(commit.ChangedFiles)),
+		}
+		for _, f := range commit.ChangedFiles {
+			cf := changedFileResponse{Path: f.Path, OldPath: f.OldPath, Binary: f.Binary}
+			if !f.Binary {
+				cf.Additions, cf.Deletions = &f.Additions, &f.Deletions
+			}
+			response.Commit.ChangedFiles = append(response.Commit.ChangedFiles, cf)
+		}
+	}
+
+	logs, err := s.db.ListRunArtifacts(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	metrics, err := s.db.GetRunMetrics(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.Metrics = make([]runMetricResponse, 0, len(metrics))
+	for _, m := range metrics {
+		response.Metrics = append(response.Metrics, runMetricResponse{Name: m.Name, Value: m.Value, Unit: m.Unit})
+	}
+
+	response.Logs = make([]runLogResponse, 0, len(logs))
+	for _, l := range logs {
+		response.Logs = append(response.Logs, runLogResponse{
+			Kind:      l.Kind,
+			Size:      l.Size,
+			CreatedAt: l.CreatedAt,
+		})
+	}
+
+	env, err := s.db.GetRunEnvironment(id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if env != nil {
+		response.Environment = &environmentResponse{
+			Fingerprint:       env.Fingerprint,
+			CPUModel:          env.CPUModel,
+			CPUCores:          env.CPUCores,
+			KernelVersion:     env.KernelVersion,
+			CPUGovernor:       env.CPUGovernor,
+			ZigVersion:        env.ZigVersion,
+			GoVersion:         env.GoVersion,
+			BenchVersion:      env.BenchVersion,
+			PerfEventParanoid: env.PerfEventParanoid,
+			ProfilingAllowed:  env.ProfilingAllowed,
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -174,9 +412,10 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 	idBStr := r.URL.Query().Get("id_b")
 	commitA := r.URL.Query().Get("a")
 	commitB := r.URL.Query().Get("b")
+	pool, _ := strconv.ParseBool(r.URL.Query().Get("pool"))
 
-	var resultsA, resultsB []db.Result
-	var runAHash, runBHash string
+	var runA, runB *db.Run
+	var err error
 
 	if idAStr != "" && idBStr != "" {
 		idA, errA := strconv.ParseInt(idAStr, 10, 64)
@@ -185,7 +424,7 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid run IDs", http.StatusBadRequest)
 			return
 		}
-		runA, err := s.db.GetRun(idA)
+		runA, err = s.db.GetRun(idA)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "run A not found", http.StatusNotFound)
@@ -194,7 +433,7 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		runB, err := s.db.GetRun(idB)
+		runB, err = s.db.GetRun(idB)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "run B not found", http.StatusNotFound)
@@ -203,19 +442,8 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		runAHash, runBHash = runA.CommitHash, runB.CommitHash
-		resultsA, err = s.db.GetResultsForRun(runA.ID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		resultsB, err = s.db.GetResultsForRun(runB.ID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
 	} else if commitA != "" && commitB != "" {
-		runA, err := s.db.GetRunByCommit(commitA)
+		runA, err = s.db.GetRunByCommit(commitA)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "run A not found", http.StatusNotFound)
@@ -224,7 +452,7 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		runB, err := s.db.GetRunByCommit(commitB)
+		runB, err = s.db.GetRunByCommit(commitB)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				http.Error(w, "run B not found", http.StatusNotFound)
@@ -233,69 +461,100 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		runAHash, runBHash = runA.CommitHash, runB.CommitHash
-		resultsA, err = s.db.GetResultsForRun(runA.ID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		resultsB, err = s.db.GetResultsForRun(runB.ID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
 	} else {
 		http.Error(w, "provide either id_a & id_b or a & b parameters", http.StatusBadRequest)
 		return
 	}
 
+	resultsA, runsA, err := record.LoadPooledResults(s.db, runA, pool)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resultsB, runsB, err := record.LoadPooledResults(s.db, runB, pool)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	type resultKey struct {
 		Category string
 		Name     string
 	}
-	resultsBMap := make(map[resultKey]int64)
+	resultsBMap := make(map[resultKey]record.PooledResult)
 	for _, r := range resultsB {
-		resultsBMap[resultKey{Category: r.Category, Name: r.Name}] = r.AvgNs
+		resultsBMap[resultKey{Category: r.Category, Name: r.Name}] = r
 	}
 
-	type comparison struct {
+	type memComparison struct {
 		Name          string  `json:"name"`
-		Category      string  `json:"category"`
-		BaselineNs    int64   `json:"baseline_ns"`
-		CurrentNs     int64   `json:"current_ns"`
+		BaselineBytes int64   `json:"baseline_bytes"`
+		CurrentBytes  int64   `json:"current_bytes"`
 		ChangePercent float64 `json:"change_percent"`
-		IsRegression  bool    `json:"is_regression"`
+	}
+
+	type comparison struct {
+		Name          string              `json:"name"`
+		Category      string              `json:"category"`
+		BaselineNs    int64               `json:"baseline_ns"`
+		CurrentNs     int64               `json:"current_ns"`
+		ChangePercent float64             `json:"change_percent"`
+		IsRegression  bool                `json:"is_regression"`
+		MemStats      []memComparison     `json:"mem_stats,omitempty"`
+		BaselineRuns  []pooledRunResponse `json:"baseline_runs,omitempty"`
+		CurrentRuns   []pooledRunResponse `json:"current_runs,omitempty"`
 	}
 
 	var comparisons []comparison
 	threshold := 10.0
 
 	for _, rA := range resultsA {
-		if avgB, ok := resultsBMap[resultKey{Category: rA.Category, Name: rA.Name}]; ok {
+		if rB, ok := resultsBMap[resultKey{Category: rA.Category, Name: rA.Name}]; ok {
 			var change float64
 			if rA.AvgNs != 0 {
-				change = float64(avgB-rA.AvgNs) / float64(rA.AvgNs) * 100
+				change = float64(rB.AvgNs-rA.AvgNs) / float64(rA.AvgNs) * 100
 			}
-			comparisons = append(comparisons, comparison{
+			c := comparison{
 				Name:          rA.Name,
 				Category:      rA.Category,
 				BaselineNs:    rA.AvgNs,
-				CurrentNs:     avgB,
+				CurrentNs:     rB.AvgNs,
 				ChangePercent: change,
 				IsRegression:  change > threshold,
-			})
+				BaselineRuns:  newPooledRunResponses(rA.Runs),
+				CurrentRuns:   newPooledRunResponses(rB.Runs),
+			}
+			for _, pair := range record.PairMemStats(rA.MemStats, rB.MemStats) {
+				var memChange float64
+				if pair.Baseline.Bytes != 0 {
+					memChange = float64(pair.Current.Bytes-pair.Baseline.Bytes) / float64(pair.Baseline.Bytes) * 100
+				}
+				c.MemStats = append(c.MemStats, memComparison{
+					Name:          pair.Baseline.StatName,
+					BaselineBytes: pair.Baseline.Bytes,
+					CurrentBytes:  pair.Current.Bytes,
+					ChangePercent: memChange,
+				})
+			}
+			comparisons = append(comparisons, c)
 		}
 	}
 
 	response := struct {
-		Baseline    string       `json:"baseline"`
-		Current     string       `json:"current"`
-		Comparisons []comparison `json:"comparisons"`
+		Baseline       string       `json:"baseline"`
+		Current        string       `json:"current"`
+		BaselineRunIDs []int64      `json:"baseline_run_ids,omitempty"`
+		CurrentRunIDs  []int64      `json:"current_run_ids,omitempty"`
+		Comparisons    []comparison `json:"comparisons"`
 	}{
-		Baseline:    runAHash,
-		Current:     runBHash,
+		Baseline:    runA.CommitHash,
+		Current:     runB.CommitHash,
 		Comparisons: comparisons,
 	}
+	if pool {
+		response.BaselineRunIDs = runIDsOf(runsA)
+		response.CurrentRunIDs = runIDsOf(runsB)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -303,6 +562,42 @@ func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// pooledRunResponse is one run's value behind a pooled result.
+type pooledRunResponse struct {
+	RunID       int64 `json:"run_id"`
+	ResultID    int64 `json:"result_id"`
+	AvgNs       int64 `json:"avg_ns"`
+	StdDevNs    int64 `json:"std_dev_ns"`
+	SampleCount int64 `json:"sample_count"`
+}
+
+// newPooledRunResponses returns the per-run drill-down of a pooled result,
+// or nil when it was not pooled from several runs.
+func newPooledRunResponses(results []db.Result) []pooledRunResponse {
+	if len(results) < 2 {
+		return nil
+	}
+	out := make([]pooledRunResponse, len(results))
+	for i, r := range results {
+		out[i] = pooledRunResponse{
+			RunID:       r.RunID,
+			ResultID:    r.ID,
+			AvgNs:       r.AvgNs,
+			StdDevNs:    r.StdDevNs,
+			SampleCount: r.SampleCount,
+		}
+	}
+	return out
+}
+
+func runIDsOf(runs []db.Run) []int64 {
+	ids := make([]int64, len(runs))
+	for i, run := range runs {
+		ids[i] = run.ID
+	}
+	return ids
+}
+
 func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("name")
 	if name == "" {
@@ -317,12 +612,26 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	trends, err := s.db.GetTrend(name, limit)
+	pool, _ := strconv.ParseBool(r.URL.Query().Get("pool"))
+
+	points, err := s.db.GetTrend(name, limit)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	var trends []record.PooledTrendPoint
+	if pool {
+		trends = record.PoolTrend(points)
+	} else {
+		for _, p := range points {
+			trends = append(trends, record.PooledTrendPoint{
+				Run:    p.Run,
+				Result: record.PooledResult{Result: p.Result, Runs: []db.Result{p.Result}},
+			})
+		}
+	}
+
 	type trendPoint struct {
 		RunID            int64    `json:"run_id"`
 		ResultID         int64    `json:"result_id"`
@@ -339,6 +648,9 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 		RegressionStatus string   `json:"regression_status"`
 		BaselineRunID    *int64   `json:"baseline_run_id,omitempty"`
 		ChangePercent    *float64 `json:"change_percent,omitempty"`
+
+		RunCount int                 `json:"run_count,omitempty"`
+		Runs     []pooledRunResponse `json:"runs,omitempty"`
 	}
 
 	type trendResponse struct {
@@ -352,17 +664,9 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 	// trends are ordered newest-first, so we need to process accordingly
 	var history []stats.RunStat
 	for _, t := range trends {
-		sem := float64(0)
-		if t.Result.SampleCount >= 2 {
-			sem = float64(t.Result.StdDevNs) / math.Sqrt(float64(t.Result.SampleCount))
-		}
-		history = append(history, stats.RunStat{
-			RunID:       t.Run.ID,
-			Mean:        float64(t.Result.AvgNs),
-			Sem:         sem,
-			SampleCount: t.Result.SampleCount,
-			StdDev:      float64(t.Result.StdDevNs),
-		})
+		stat := record.ResultStat(t.Result.Result)
+		stat.RunID = t.Run.ID
+		history = append(history, stat)
 	}
 
 	// Compute baseline from all history except the latest run
@@ -371,7 +675,7 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 		baseline, _ = stats.ComputeBaseline(history[1:], defaultMinPoints, defaultBaselineOffset)
 	}
 
-	var points []trendPoint
+	var trendPoints []trendPoint
 	for i, t := range trends {
 		ciLower, ciUpper, sem := stats.MeanCI95(t.Result.AvgNs, t.Result.StdDevNs, t.Result.SampleCount)
 
@@ -388,6 +692,10 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 			CiLowerNs:   ciLower,
 			CiUpperNs:   ciUpper,
 			SemNs:       sem,
+			Runs:        newPooledRunResponses(t.Result.Runs),
+		}
+		if pool {
+			point.RunCount = len(t.Result.Runs)
 		}
 
 		// Determine regression status
@@ -409,11 +717,11 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 			point.BaselineRunID = &baseline.RunID
 		}
 
-		points = append(points, point)
+		trendPoints = append(trendPoints, point)
 	}
 
 	response := trendResponse{
-		Points: points,
+		Points: trendPoints,
 	}
 
 	if baseline != nil {
@@ -430,6 +738,123 @@ func (s *Server) handleTrend(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleMemTrend(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	stat := r.URL.Query().Get("stat")
+	if name == "" || stat == "" {
+		http.Error(w, "name and stat parameters required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	trends, err := s.db.GetMemTrend(name, stat, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type memTrendPoint struct {
+		RunID       int64  `json:"run_id"`
+		ResultID    int64  `json:"result_id"`
+		CommitHash  string `json:"commit_hash"`
+		RunDate     string `json:"run_date"`
+		Category    string `json:"category"`
+		Name        string `json:"name"`
+		Bytes       int64  `json:"bytes"`
+		MinBytes    int64  `json:"min_bytes"`
+		MaxBytes    int64  `json:"max_bytes"`
+		SampleCount int64  `json:"sample_count"`
+	}
+
+	points := make([]memTrendPoint, 0, len(trends))
+	for _, t := range trends {
+		points = append(points, memTrendPoint{
+			RunID:       t.Run.ID,
+			ResultID:    t.Result.ID,
+			CommitHash:  t.Run.CommitHash,
+			RunDate:     t.Run.RunDate,
+			Category:    t.Result.Category,
+			Name:        t.Result.Name,
+			Bytes:       t.Stat.Bytes,
+			MinBytes:    t.Stat.MinBytes,
+			MaxBytes:    t.Stat.MaxBytes,
+			SampleCount: t.Stat.SampleCount,
+		})
+	}
+
+	response := struct {
+		Stat   string          `json:"stat"`
+		Points []memTrendPoint `json:"points"`
+	}{
+		Stat:   stat,
+		Points: points,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleMetricTrend(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name parameter required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	trends, err := s.db.GetMetricTrend(name, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type metricTrendPoint struct {
+		RunID      int64  `json:"run_id"`
+		CommitHash string `json:"commit_hash"`
+		RunDate    string `json:"run_date"`
+		Value      int64  `json:"value"`
+		Unit       string `json:"unit"`
+	}
+
+	points := make([]metricTrendPoint, 0, len(trends))
+	for _, t := range trends {
+		points = append(points, metricTrendPoint{
+			RunID:      t.Run.ID,
+			CommitHash: t.Run.CommitHash,
+			RunDate:    t.Run.RunDate,
+			Value:      t.Metric.Value,
+			Unit:       t.Metric.Unit,
+		})
+	}
+
+	response := struct {
+		Name   string             `json:"name"`
+		Points []metricTrendPoint `json:"points"`
+	}{
+		Name:   name,
+		Points: points,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (s *Server) handleBenchmarks(w http.ResponseWriter, r *http.Request) {
 	rows, err := s.db.Query(`SELECT DISTINCT name FROM results ORDER BY name`)
 	if err != nil {
@@ -496,9 +921,9 @@ func (s *Server) handleCategories(w http.ResponseWriter, r *http.Request) {
 }
 
 const (
-	flamegraphSVGKind = "cpu.flamegraph.svg"
-	callgraphSVGKind  = "cpu.callgraph.svg"
-	cpuProfileKind    = "cpu.pprof"
+	flamegraphSVGKind = db.ArtifactFlamegraphSVG
+	callgraphSVGKind  = db.ArtifactCallgraphSVG
+	cpuProfileKind    = db.ArtifactCPUProfile
 	maxProfileSize    = 50 << 20
 	maxFlamegraphSize = 20 << 20
 	flamegraphTimeout = 30 * time.Second
@@ -1065,6 +1490,180 @@ func (s *Server) handleArtifactList(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) handleSamples(w http.ResponseWriter, r *http.Request) {
+	// Path: /api/runs/{run_id}/results/{result_id}/samples
+	path := strings.TrimPrefix(r.URL.Path, "/api/runs/")
+	path = strings.TrimSuffix(path, "/samples")
+	parts := strings.Split(path, "/results/")
+	if len(parts) != 2 {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	runID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+
+	resultID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid result id", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.ensureResultBelongsToRun(runID, resultID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "result not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	samples, err := s.db.GetSamplesForResult(resultID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type sampleResponse struct {
+		Index      int   `json:"index"`
+		MinNs      int64 `json:"min_ns"`
+		AvgNs      int64 `json:"avg_ns"`
+		MaxNs      int64 `json:"max_ns"`
+		TotalNs    int64 `json:"total_ns"`
+		Iterations int64 `json:"iterations"`
+	}
+
+	response := make([]sampleResponse, 0, len(samples))
+	for _, smp := range samples {
+		response = append(response, sampleResponse{
+			Index:      smp.SampleIndex,
+			MinNs:      smp.MinNs,
+			AvgNs:      smp.AvgNs,
+			MaxNs:      smp.MaxNs,
+			TotalNs:    smp.TotalNs,
+			Iterations: smp.Iterations,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	// Path: /api/runs/{run_id}/export?format=gobench
+	path := strings.TrimPrefix(r.URL.Path, "/api/runs/")
+	path = strings.TrimSuffix(path, "/export")
+
+	runID, err := strconv.ParseInt(path, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = string(record.FormatGoBench)
+	}
+	if format != string(record.FormatGoBench) {
+		http.Error(w, "unsupported format", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := s.db.GetRun(runID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "run not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := record.ExportGoBench(&buf, s.db, runID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"run-%d.txt\"", runID))
+	_, _ = w.Write(buf.Bytes())
+}
+
+type commitResponse struct {
+	AuthorName   string                `json:"author_name"`
+	AuthorEmail  string                `json:"author_email"`
+	Body         string                `json:"body"`
+	Parents      []string              `json:"parents"`
+	Tags         []string              `json:"tags"`
+	DiffBase     string                `json:"diff_base,omitempty"`
+	ChangedFiles []changedFileResponse `json:"changed_files"`
+}
+
+type changedFileResponse struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Additions *int   `json:"additions,omitempty"`
+	Deletions *int   `json:"deletions,omitempty"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+type runMetricResponse struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+	Unit  string `json:"unit"`
+}
+
+type runLogResponse struct {
+	Kind      string `json:"kind"`
+	Size      int64  `json:"size"`
+	CreatedAt string `json:"created_at"`
+}
+
+func (s *Server) handleRunLogDownload(w http.ResponseWriter, r *http.Request) {
+	// Path: /api/runs/{run_id}/logs/{kind}
+	path := strings.TrimPrefix(r.URL.Path, "/api/runs/")
+	parts := strings.SplitN(path, "/logs/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
+
+	runID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid run id", http.StatusBadRequest)
+		return
+	}
+
+	log, err := s.db.GetRunArtifact(runID, parts[1])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "log not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Logs are arbitrary command output; never let a browser render them as HTML.
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.URL.Query().Get("download") != "" {
+		kind := strings.Map(func(r rune) rune {
+			if r == '"' || r == '/' || r == '\\' || r < ' ' {
+				return '_'
+			}
+			return r
+		}, log.Kind)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"run-%d-%s.txt\"", runID, kind))
+	}
+	_, _ = w.Write(log.Data)
+}
+
 func (s *Server) handleArtifactDownload(w http.ResponseWriter, r *http.Request) {
 	// Path: /api/runs/{run_id}/results/{result_id}/artifacts/{kind}/download
 	path := strings.TrimPrefix(r.URL.Path, "/api/runs/")
@@ -1166,6 +1765,20 @@ const (
 	defaultMinPoints      = 5
 	defaultBaselineOffset = 3
 	defaultAlpha          = 0.01
+	// defaultBranch is the branch whose latest run is checked by default.
+	defaultBranch = "main"
+
+	// Memory stats regress when they grow beyond both thresholds.
+	defaultMemThresholdPercent = 5.0
+	defaultMemThresholdBytes   = 0
+
+	// Run metrics regress when they grow beyond these percentages. Build
+	// times are wall-clock times and need a wider margin than sizes.
+	defaultSizeThresholdPercent      = 1.0
+	defaultBuildTimeThresholdPercent = 20.0
+	runMetricCategory                = "run"
+
+	timeMetric = "time"
 )
 
 func (s *Server) handleDatabaseDownload(w http.ResponseWriter, r *http.Request) {
@@ -1174,12 +1787,22 @@ func (s *Server) handleDatabaseDownload(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	dbPath := s.db.Path()
+	// Serve a consistent snapshot: in WAL mode recent commits may only be in
+	// the -wal file, and the main file changes whenever it is checkpointed.
+	tmpDir, err := os.MkdirTemp("", "bench-download-")
+	if err != nil {
+		http.Error(w, "Failed to snapshot database", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
 
-	// Checkpoint WAL for consistency
-	_, _ = s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	snapshot := filepath.Join(tmpDir, "bench.db")
+	if _, err := s.db.Exec(`VACUUM INTO ?`, snapshot); err != nil {
+		http.Error(w, "Failed to snapshot database", http.StatusInternalServerError)
+		return
+	}
 
-	f, err := os.Open(dbPath)
+	f, err := os.Open(snapshot)
 	if err != nil {
 		http.Error(w, "Failed to open database", http.StatusInternalServerError)
 		return
@@ -1198,7 +1821,7 @@ func (s *Server) handleDatabaseDownload(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
-	// Parse optional run_id parameter (defaults to latest run)
+	// Parse optional run_id parameter (defaults to the latest run of branch)
 	var runID int64
 	if idStr := r.URL.Query().Get("run_id"); idStr != "" {
 		var err error
@@ -1208,7 +1831,11 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else {
-		latestRun, err := s.db.GetLatestRun()
+		branch := defaultBranch
+		if b := r.URL.Query().Get("branch"); b != "" {
+			branch = b
+		}
+		latestRun, err := s.db.GetLatestRun(branch)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				// No runs yet, return empty response
@@ -1252,8 +1879,46 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	matchEnvironment := true
+	if me := r.URL.Query().Get("match_environment"); me != "" {
+		if b, err := strconv.ParseBool(me); err == nil {
+			matchEnvironment = b
+		}
+	}
+
+	pool, _ := strconv.ParseBool(r.URL.Query().Get("pool"))
+
+	memThreshold := stats.MemThreshold{
+		Bytes:   defaultMemThresholdBytes,
+		Percent: defaultMemThresholdPercent,
+	}
+	if v := r.URL.Query().Get("mem_threshold_bytes"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+			memThreshold.Bytes = n
+		}
+	}
+	if v := r.URL.Query().Get("mem_threshold_pct"); v != "" {
+		if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+			memThreshold.Percent = n
+		}
+	}
+
+	// Run metrics are single values per run and are checked like memory
+	// stats, with a threshold per unit.
+	metricThresholds := map[string]stats.MemThreshold{
+		db.MetricUnitBytes: {Percent: defaultSizeThresholdPercent},
+		db.MetricUnitNs:    {Percent: defaultBuildTimeThresholdPercent},
+	}
+	for param, unit := range map[string]string{"size_threshold_pct": db.MetricUnitBytes, "build_time_threshold_pct": db.MetricUnitNs} {
+		if v := r.URL.Query().Get(param); v != "" {
+			if n, err := strconv.ParseFloat(v, 64); err == nil && n >= 0 {
+				metricThresholds[unit] = stats.MemThreshold{Percent: n}
+			}
+		}
+	}
+
 	// Get comparable runs window
-	runs, err := s.db.GetComparableRunsWindow(runID, window)
+	runs, err := s.db.GetComparableRunsWindow(runID, window, matchEnvironment)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1280,37 +1945,50 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 		runByID[run.ID] = run
 	}
 
-	// Get all benchmark names across these runs
-	benchmarkNames, err := s.db.GetDistinctBenchmarkNames(runIDs)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	// Group the window into history points: one per run, or with pool one
+	// per commit. The latest point is first since runs are sorted DESC.
+	groups := make([][]db.Run, 0, len(runs))
+	groupIndex := make(map[string]int)
+	for _, run := range runs {
+		key := strconv.FormatInt(run.ID, 10)
+		if pool {
+			key = run.PoolKey()
+		}
+		i, ok := groupIndex[key]
+		if !ok {
+			i = len(groups)
+			groupIndex[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], run)
 	}
 
-	// Find the latest run (first in the list since it's sorted DESC)
-	latestRunID := runs[0].ID
-
 	type regression struct {
-		Name                     string   `json:"name"`
-		Category                 string   `json:"category"`
-		LatestResultID           int64    `json:"latest_result_id"`
-		LatestCILowerNs          int64    `json:"latest_ci_lower_ns"`
-		LatestCIUpperNs          int64    `json:"latest_ci_upper_ns"`
-		BaselineRunID            int64    `json:"baseline_run_id"`
-		BaselineCommitHash       string   `json:"baseline_commit_hash"`
-		BaselineCommitHashFull   string   `json:"baseline_commit_hash_full"`
-		BaselineCILowerNs        int64    `json:"baseline_ci_lower_ns"`
-		BaselineCIUpperNs        int64    `json:"baseline_ci_upper_ns"`
-		ChangePercent            float64  `json:"change_percent"`
-		MinEffectPercent         float64  `json:"min_effect_percent"`
-		PValue                   *float64 `json:"p_value,omitempty"`
-		Alpha                    float64  `json:"alpha"`
-		IntroducedRunID          *int64   `json:"introduced_run_id,omitempty"`
-		IntroducedResultID       *int64   `json:"introduced_result_id,omitempty"`
-		IntroducedCommitHash     *string  `json:"introduced_commit_hash,omitempty"`
-		IntroducedCommitHashFull *string  `json:"introduced_commit_hash_full,omitempty"`
-		IntroducedCommitMessage  *string  `json:"introduced_commit_message,omitempty"`
-		IntroducedRunDate        *string  `json:"introduced_run_date,omitempty"`
+		Name                     string              `json:"name"`
+		Category                 string              `json:"category"`
+		Metric                   string              `json:"metric"`
+		LatestBytes              *int64              `json:"latest_bytes,omitempty"`
+		BaselineBytes            *int64              `json:"baseline_bytes,omitempty"`
+		ChangeBytes              *int64              `json:"change_bytes,omitempty"`
+		LatestResultID           int64               `json:"latest_result_id"`
+		LatestCILowerNs          int64               `json:"latest_ci_lower_ns"`
+		LatestCIUpperNs          int64               `json:"latest_ci_upper_ns"`
+		LatestRuns               []pooledRunResponse `json:"latest_runs,omitempty"`
+		BaselineRunID            int64               `json:"baseline_run_id"`
+		BaselineCommitHash       string              `json:"baseline_commit_hash"`
+		BaselineCommitHashFull   string              `json:"baseline_commit_hash_full"`
+		BaselineCILowerNs        int64               `json:"baseline_ci_lower_ns"`
+		BaselineCIUpperNs        int64               `json:"baseline_ci_upper_ns"`
+		ChangePercent            float64             `json:"change_percent"`
+		MinEffectPercent         float64             `json:"min_effect_percent"`
+		PValue                   *float64            `json:"p_value,omitempty"`
+		Alpha                    float64             `json:"alpha"`
+		IntroducedRunID          *int64              `json:"introduced_run_id,omitempty"`
+		IntroducedResultID       *int64              `json:"introduced_result_id,omitempty"`
+		IntroducedCommitHash     *string             `json:"introduced_commit_hash,omitempty"`
+		IntroducedCommitHashFull *string             `json:"introduced_commit_hash_full,omitempty"`
+		IntroducedCommitMessage  *string             `json:"introduced_commit_message,omitempty"`
+		IntroducedRunDate        *string             `json:"introduced_run_date,omitempty"`
 	}
 
 	type regressionsResponse struct {
@@ -1319,48 +1997,146 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 		MinPoints           int          `json:"min_points"`
 		BaselineOffset      int          `json:"baseline_offset"`
 		InsufficientHistory bool         `json:"insufficient_history"`
+		PooledRunIDs        []int64      `json:"pooled_run_ids,omitempty"`
+		MemThresholdBytes   float64      `json:"mem_threshold_bytes"`
+		MemThresholdPercent float64      `json:"mem_threshold_pct"`
+		SizeThreshold       float64      `json:"size_threshold_pct"`
+		BuildTimeThreshold  float64      `json:"build_time_threshold_pct"`
 		Regressions         []regression `json:"regressions"`
 	}
 
-	var regressions []regression
+	// setIntroduced fills in the run that introduced a regression.
+	setIntroduced := func(reg *regression, introducingID *int64, resultsMap map[int64]db.Result) {
+		if introducingID == nil {
+			return
+		}
+		reg.IntroducedRunID = introducingID
+		if introRun, ok := runByID[*introducingID]; ok {
+			reg.IntroducedCommitHash = &introRun.CommitHash
+			reg.IntroducedCommitHashFull = &introRun.CommitHashFull
+			reg.IntroducedCommitMessage = &introRun.CommitMessage
+			reg.IntroducedRunDate = &introRun.RunDate
+		}
+		if introResult, ok := resultsMap[*introducingID]; ok {
+			reg.IntroducedResultID = &introResult.ID
+		}
+	}
+
+	// Load every result of the window at once, indexed by benchmark name
+	// and run.
+	windowResults, err := s.db.GetResultsInRuns(runIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resultsByName := make(map[string]map[int64]db.Result)
+	var benchmarkNames []string
+	for _, result := range windowResults {
+		if resultsByName[result.Name] == nil {
+			resultsByName[result.Name] = make(map[int64]db.Result)
+			benchmarkNames = append(benchmarkNames, result.Name)
+		}
+		resultsByName[result.Name][result.RunID] = result
+	}
+	sort.Strings(benchmarkNames)
+
+	var regressions, memRegressions []regression
 	analyzableBenchmarks := 0
 
 	// Analyze each benchmark
 	for _, benchName := range benchmarkNames {
-		// Get results for this benchmark across all runs
-		resultsMap, err := s.db.GetResultsForBenchmarkInRuns(benchName, runIDs)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		resultsMap := resultsByName[benchName]
+
+		// Pool the results of each history point. A pooled point is
+		// identified by the newest of its runs that has this benchmark.
+		points := make([]*record.PooledResult, len(groups))
+		for i, group := range groups {
+			var perRun [][]db.Result
+			for _, run := range group {
+				if result, ok := resultsMap[run.ID]; ok {
+					perRun = append(perRun, []db.Result{result})
+				}
+			}
+			if len(perRun) > 0 {
+				points[i] = &record.PoolResults(perRun)[0]
+			}
 		}
 
-		// Check if latest run has this benchmark
-		latestResult, hasLatest := resultsMap[latestRunID]
-		if !hasLatest {
+		// Check if latest point has this benchmark
+		if points[0] == nil {
 			continue
 		}
+		latestResult := points[0].Result
 
-		// Build history for baseline computation (exclude latest)
-		var history []stats.RunStat
-		for _, run := range runs {
-			if run.ID == latestRunID {
+		// Memory stats use their own threshold-based baseline
+		for _, latestMem := range latestResult.MemStats {
+			var memHistory []stats.MemPoint
+			for _, point := range points[1:] {
+				if point == nil {
+					continue
+				}
+				for _, ms := range point.MemStats {
+					if ms.StatName == latestMem.StatName {
+						memHistory = append(memHistory, stats.MemPoint{
+							RunID: point.RunID,
+							Mean:  float64(ms.Bytes),
+							Min:   float64(ms.MinBytes),
+						})
+					}
+				}
+			}
+
+			memBaseline, err := stats.ComputeMemBaseline(memHistory, minPoints, baselineOffset)
+			if err != nil {
 				continue
 			}
-			result, ok := resultsMap[run.ID]
-			if !ok {
+			analyzableBenchmarks++
+
+			latestPoint := stats.MemPoint{
+				RunID: latestResult.RunID,
+				Mean:  float64(latestMem.Bytes),
+				Min:   float64(latestMem.MinBytes),
+			}
+			memResult := stats.DetectMemRegression(latestPoint, memBaseline, memThreshold)
+			if memResult.Status != "regressed" {
 				continue
 			}
-			sem := float64(0)
-			if result.SampleCount >= 2 {
-				sem = float64(result.StdDevNs) / math.Sqrt(float64(result.SampleCount))
+
+			chronoHistory := make([]stats.MemPoint, 0, len(memHistory)+1)
+			for i := len(memHistory) - 1; i >= 0; i-- {
+				chronoHistory = append(chronoHistory, memHistory[i])
+			}
+			chronoHistory = append(chronoHistory, latestPoint)
+
+			baselineBytes := int64(math.Round(memBaseline.Median))
+			changeBytes := latestMem.Bytes - baselineBytes
+			reg := regression{
+				Name:             latestResult.Name,
+				Category:         latestResult.Category,
+				Metric:           latestMem.StatName,
+				LatestBytes:      &latestMem.Bytes,
+				BaselineBytes:    &baselineBytes,
+				ChangeBytes:      &changeBytes,
+				LatestResultID:   latestResult.ID,
+				LatestRuns:       newPooledRunResponses(points[0].Runs),
+				BaselineRunID:    memBaseline.RunID,
+				ChangePercent:    memResult.ChangePercent,
+				MinEffectPercent: memThreshold.Percent,
+			}
+			if baselineRun, ok := runByID[memBaseline.RunID]; ok {
+				reg.BaselineCommitHash = baselineRun.CommitHash
+				reg.BaselineCommitHashFull = baselineRun.CommitHashFull
+			}
+			setIntroduced(&reg, stats.FindMemIntroducingRun(chronoHistory, memBaseline, memThreshold), resultsMap)
+			memRegressions = append(memRegressions, reg)
+		}
+
+		// Build history for baseline computation (exclude latest)
+		var history []stats.RunStat
+		for _, point := range points[1:] {
+			if point != nil {
+				history = append(history, record.ResultStat(point.Result))
 			}
-			history = append(history, stats.RunStat{
-				RunID:       run.ID,
-				Mean:        float64(result.AvgNs),
-				Sem:         sem,
-				SampleCount: result.SampleCount,
-				StdDev:      float64(result.StdDevNs),
-			})
 		}
 
 		// Compute baseline
@@ -1372,17 +2148,7 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 		analyzableBenchmarks++
 
 		// Build latest RunStat
-		latestSem := float64(0)
-		if latestResult.SampleCount >= 2 {
-			latestSem = float64(latestResult.StdDevNs) / math.Sqrt(float64(latestResult.SampleCount))
-		}
-		latestStat := stats.RunStat{
-			RunID:       latestRunID,
-			Mean:        float64(latestResult.AvgNs),
-			Sem:         latestSem,
-			SampleCount: latestResult.SampleCount,
-			StdDev:      float64(latestResult.StdDevNs),
-		}
+		latestStat := record.ResultStat(latestResult)
 
 		// Detect regression
 		result := stats.DetectRegression(latestStat, baseline, defaultAlpha)
@@ -1402,9 +2168,11 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 			reg := regression{
 				Name:              latestResult.Name,
 				Category:          latestResult.Category,
+				Metric:            timeMetric,
 				LatestResultID:    latestResult.ID,
 				LatestCILowerNs:   ciLower,
 				LatestCIUpperNs:   ciUpper,
+				LatestRuns:        newPooledRunResponses(points[0].Runs),
 				BaselineRunID:     baseline.RunID,
 				BaselineCILowerNs: int64(baseline.CILower),
 				BaselineCIUpperNs: int64(baseline.CIUpper),
@@ -1421,21 +2189,100 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Add introducing run info
-			if introducingID != nil {
-				reg.IntroducedRunID = introducingID
-				if introRun, ok := runByID[*introducingID]; ok {
-					reg.IntroducedCommitHash = &introRun.CommitHash
-					reg.IntroducedCommitHashFull = &introRun.CommitHashFull
-					reg.IntroducedCommitMessage = &introRun.CommitMessage
-					reg.IntroducedRunDate = &introRun.RunDate
-				}
-				if introResult, ok := resultsMap[*introducingID]; ok {
-					reg.IntroducedResultID = &introResult.ID
+			setIntroduced(&reg, introducingID, resultsMap)
+
+			regressions = append(regressions, reg)
+		}
+	}
+	regressions = append(regressions, memRegressions...)
+
+	// Run metrics: average each history point's runs, newest first.
+	runMetrics, err := s.db.GetRunMetricsInRuns(runIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	metricPoints := make([]map[string]stats.MemPoint, len(groups))
+	metricUnits := make(map[string]string)
+	for i, group := range groups {
+		sums := make(map[string]float64)
+		counts := make(map[string]int)
+		metricPoints[i] = make(map[string]stats.MemPoint)
+		for _, run := range group {
+			for _, m := range runMetrics[run.ID] {
+				if _, ok := metricPoints[i][m.Name]; !ok {
+					metricPoints[i][m.Name] = stats.MemPoint{RunID: run.ID}
 				}
+				sums[m.Name] += float64(m.Value)
+				counts[m.Name]++
+				metricUnits[m.Name] = m.Unit
 			}
+		}
+		for name, p := range metricPoints[i] {
+			p.Mean = sums[name] / float64(counts[name])
+			p.Min = p.Mean
+			metricPoints[i][name] = p
+		}
+	}
 
-			regressions = append(regressions, reg)
+	metricNames := make([]string, 0, len(metricPoints[0]))
+	for name := range metricPoints[0] {
+		metricNames = append(metricNames, name)
+	}
+	sort.Strings(metricNames)
+
+	for _, name := range metricNames {
+		latestPoint := metricPoints[0][name]
+		var history []stats.MemPoint
+		for _, points := range metricPoints[1:] {
+			if p, ok := points[name]; ok {
+				history = append(history, p)
+			}
 		}
+
+		baseline, err := stats.ComputeMemBaseline(history, minPoints, baselineOffset)
+		if err != nil {
+			continue
+		}
+		analyzableBenchmarks++
+
+		threshold := metricThresholds[metricUnits[name]]
+		result := stats.DetectMemRegression(latestPoint, baseline, threshold)
+		if result.Status != "regressed" {
+			continue
+		}
+
+		chronoHistory := make([]stats.MemPoint, 0, len(history)+1)
+		for i := len(history) - 1; i >= 0; i-- {
+			chronoHistory = append(chronoHistory, history[i])
+		}
+		chronoHistory = append(chronoHistory, latestPoint)
+
+		latestValue := int64(math.Round(latestPoint.Mean))
+		baselineValue := int64(math.Round(baseline.Median))
+		reg := regression{
+			Name:             name,
+			Category:         runMetricCategory,
+			Metric:           name,
+			BaselineRunID:    baseline.RunID,
+			ChangePercent:    result.ChangePercent,
+			MinEffectPercent: threshold.Percent,
+		}
+		if metricUnits[name] == db.MetricUnitBytes {
+			changeBytes := latestValue - baselineValue
+			reg.LatestBytes = &latestValue
+			reg.BaselineBytes = &baselineValue
+			reg.ChangeBytes = &changeBytes
+		} else {
+			reg.LatestCILowerNs, reg.LatestCIUpperNs = latestValue, latestValue
+			reg.BaselineCILowerNs, reg.BaselineCIUpperNs = baselineValue, baselineValue
+		}
+		if baselineRun, ok := runByID[baseline.RunID]; ok {
+			reg.BaselineCommitHash = baselineRun.CommitHash
+			reg.BaselineCommitHashFull = baselineRun.CommitHashFull
+		}
+		setIntroduced(&reg, stats.FindMemIntroducingRun(chronoHistory, baseline, threshold), nil)
+		regressions = append(regressions, reg)
 	}
 
 	response := regressionsResponse{
@@ -1444,8 +2291,15 @@ func (s *Server) handleRegressions(w http.ResponseWriter, r *http.Request) {
 		MinPoints:           minPoints,
 		BaselineOffset:      baselineOffset,
 		InsufficientHistory: analyzableBenchmarks == 0,
+		MemThresholdBytes:   memThreshold.Bytes,
+		MemThresholdPercent: memThreshold.Percent,
+		SizeThreshold:       metricThresholds[db.MetricUnitBytes].Percent,
+		BuildTimeThreshold:  metricThresholds[db.MetricUnitNs].Percent,
 		Regressions:         regressions,
 	}
+	if pool {
+		response.PooledRunIDs = runIDsOf(groups[0])
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
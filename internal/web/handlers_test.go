@@ -0,0 +1,94 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opentui-bench/internal/db"
+)
+
+func TestHandleCreateJobRequiresToken(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	for _, tc := range []struct {
+		name       string
+		serverTok  string
+		header     string
+		wantStatus int
+	}{
+		{"no server token", "", "Bearer anything", http.StatusForbidden},
+		{"missing token", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"right token", "secret", "Bearer secret", http.StatusCreated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &Server{db: database, jobsToken: tc.serverTok}
+			req := httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(`{"commit": "main"}`))
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			s.handleCreateJob(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status %d (%s), want %d", rec.Code, strings.TrimSpace(rec.Body.String()), tc.wantStatus)
+			}
+		})
+	}
+
+	jobs, err := database.ListJobs(10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("%d jobs queued, want 1", len(jobs))
+	}
+}
+
+func TestHandleSamples(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "bench.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	runID, err := database.InsertRun(&db.Run{CommitHash: "abc1234", CommitHashFull: "abc1234", RunDate: "2024-01-01T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultID, err := database.InsertResultWithSamples(
+		&db.Result{RunID: runID, Category: "render", Name: "frame", AvgNs: 15, SampleCount: 2},
+		[]db.Sample{{SampleIndex: 0, AvgNs: 10, Iterations: 5}, {SampleIndex: 1, AvgNs: 20, Iterations: 5}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{db: database}
+	for _, tc := range []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{fmt.Sprintf("/api/runs/%d/results/%d/samples", runID, resultID), http.StatusOK,
+			`[{"index":0,"min_ns":0,"avg_ns":10,"max_ns":0,"total_ns":0,"iterations":5},{"index":1,"min_ns":0,"avg_ns":20,"max_ns":0,"total_ns":0,"iterations":5}]`},
+		{fmt.Sprintf("/api/runs/%d/results/%d/samples", runID+1, resultID), http.StatusNotFound, ""},
+		{"/api/runs/x/results/1/samples", http.StatusBadRequest, ""},
+	} {
+		rec := httptest.NewRecorder()
+		s.handleSamples(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.wantStatus {
+			t.Fatalf("%s: status %d (%s), want %d", tc.path, rec.Code, strings.TrimSpace(rec.Body.String()), tc.wantStatus)
+		}
+		if tc.wantBody != "" && strings.TrimSpace(rec.Body.String()) != tc.wantBody {
+			t.Errorf("%s: body %s, want %s", tc.path, rec.Body.String(), tc.wantBody)
+		}
+	}
+}
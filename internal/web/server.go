@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -26,17 +25,13 @@ type Server struct {
 	svgCache      *cache.SVGCache
 	flamegraphSem chan struct{}
 	pprofManager  *PProfManager
+	// jobsToken is required as a bearer token to submit jobs; without it
+	// submissions are refused.
+	jobsToken string
 }
 
 func NewServer(database *db.DB, addr string) *Server {
-	cacheDir := os.Getenv("SVG_CACHE_DIR")
-	if cacheDir == "" {
-		if home, err := os.UserHomeDir(); err == nil {
-			cacheDir = filepath.Join(home, ".cache", "opentui-bench", "svg")
-		} else {
-			cacheDir = "/data/svg-cache"
-		}
-	}
+	cacheDir := cache.DefaultSVGCacheDir()
 
 	maxRuns := 5
 	if envMax := os.Getenv("SVG_CACHE_MAX_RUNS"); envMax != "" {
@@ -63,6 +58,7 @@ func NewServer(database *db.DB, addr string) *Server {
 		svgCache:      svgCache,
 		flamegraphSem: make(chan struct{}, maxConcurrency),
 		pprofManager:  NewPProfManager(),
+		jobsToken:     os.Getenv("JOBS_TOKEN"),
 	}
 }
 
@@ -79,8 +75,11 @@ func (s *Server) Start(openBrowser bool) error {
 	mux.HandleFunc("/api/runs/", s.routeRunsAPI)
 	mux.HandleFunc("/api/compare", s.handleCompare)
 	mux.HandleFunc("/api/trend", s.handleTrend)
+	mux.HandleFunc("/api/mem-trend", s.handleMemTrend)
+	mux.HandleFunc("/api/metric-trend", s.handleMetricTrend)
 	mux.HandleFunc("/api/benchmarks", s.handleBenchmarks)
 	mux.HandleFunc("/api/regressions", s.handleRegressions)
+	mux.HandleFunc("/api/jobs", s.handleJobs)
 	mux.HandleFunc("/api/database/download", s.handleDatabaseDownload)
 
 	if openBrowser {
@@ -162,8 +161,14 @@ func (s *Server) routeRunsAPI(w http.ResponseWriter, r *http.Request) {
 		s.handleFlamegraphSVG(w, r)
 	case strings.Contains(path, "/results/") && strings.HasSuffix(path, "/callgraph"):
 		s.handleCallgraphSVG(w, r)
+	case strings.Contains(path, "/results/") && strings.HasSuffix(path, "/samples"):
+		s.handleSamples(w, r)
 	case strings.HasSuffix(path, "/categories"):
 		s.handleCategories(w, r)
+	case strings.HasSuffix(path, "/export"):
+		s.handleExport(w, r)
+	case strings.Contains(path, "/logs/"):
+		s.handleRunLogDownload(w, r)
 	case strings.HasSuffix(path, "/artifacts"):
 		s.handleArtifactList(w, r)
 	case strings.HasSuffix(path, "/download") && strings.Contains(path, "/artifacts/"):
@@ -1,5 +1,16 @@
 -- OpenTUI Benchmark Tracker Schema
 -- SQLite database for tracking Zig benchmark performance over time
+--
+-- Reference copy of the schema produced by internal/db/migrations. The
+-- migrations are what bench applies; keep this file in sync when adding one
+-- (TestSchemaFileMatchesMigrations checks it).
+
+-- Applied migrations; the highest version is the schema version
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TEXT NOT NULL
+);
 
 -- A single benchmark run (one invocation of `zig build bench`)
 CREATE TABLE IF NOT EXISTS runs (
@@ -12,13 +23,57 @@ CREATE TABLE IF NOT EXISTS runs (
     run_date TEXT NOT NULL,
     machine_id TEXT,
     notes TEXT,
-    zig_optimize TEXT DEFAULT 'ReleaseFast'
+    zig_optimize TEXT DEFAULT 'ReleaseFast',
+    status TEXT NOT NULL DEFAULT 'complete', -- pending, building, running, profiling, complete, failed
+    failure_reason TEXT,
+    commit_seq INTEGER, -- position in first-parent history; orders runs by commit
+    harness TEXT NOT NULL DEFAULT 'zig' -- zig, command or bun; zig_optimize is empty for the others
 );
 
 CREATE INDEX IF NOT EXISTS idx_runs_commit ON runs(commit_hash);
+CREATE INDEX IF NOT EXISTS idx_runs_commit_full_status ON runs(commit_hash_full, status);
+CREATE INDEX IF NOT EXISTS idx_runs_commit_seq ON runs(commit_seq);
 CREATE INDEX IF NOT EXISTS idx_runs_date ON runs(run_date);
 CREATE INDEX IF NOT EXISTS idx_runs_branch ON runs(branch);
 
+-- Machine/toolchain fingerprint captured for each run
+CREATE TABLE IF NOT EXISTS run_environment (
+    run_id INTEGER PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    fingerprint TEXT NOT NULL,
+    cpu_model TEXT,
+    cpu_cores INTEGER,
+    kernel_version TEXT,
+    cpu_governor TEXT,
+    zig_version TEXT,
+    go_version TEXT,
+    bench_version TEXT,
+    perf_event_paranoid INTEGER,
+    profiling_allowed INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_environment_fingerprint ON run_environment(fingerprint);
+
+-- Git metadata of a run's commit
+CREATE TABLE IF NOT EXISTS run_commit (
+    run_id INTEGER PRIMARY KEY REFERENCES runs(id) ON DELETE CASCADE,
+    author_name TEXT,
+    author_email TEXT,
+    body TEXT,
+    parents TEXT,   -- space-separated full hashes
+    tags TEXT,      -- space-separated tag names
+    diff_base TEXT  -- commit run_changed_files is relative to
+);
+
+-- Diffstat of a run's commit against the previously recorded commit
+CREATE TABLE IF NOT EXISTS run_changed_files (
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    additions INTEGER, -- NULL for binary files
+    deletions INTEGER,
+    old_path TEXT,     -- path the file was renamed from; NULL if not renamed
+    PRIMARY KEY (run_id, path)
+);
+
 -- Individual benchmark results within a run
 -- When sample_count > 1, statistics are computed from multiple benchmark invocations
 CREATE TABLE IF NOT EXISTS results (
@@ -42,16 +97,115 @@ CREATE INDEX IF NOT EXISTS idx_results_run ON results(run_id);
 CREATE INDEX IF NOT EXISTS idx_results_name ON results(name);
 CREATE INDEX IF NOT EXISTS idx_results_category ON results(category);
 
--- Memory statistics (optional, per-result)
+-- Memory statistics (optional, per-result), aggregated over all samples
 CREATE TABLE IF NOT EXISTS mem_stats (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
     stat_name TEXT NOT NULL,
-    bytes INTEGER NOT NULL
+    bytes INTEGER NOT NULL,                  -- average across samples
+    min_bytes INTEGER,                       -- NULL for stats recorded before aggregation
+    max_bytes INTEGER,
+    sample_count INTEGER NOT NULL DEFAULT 1
 );
 
 CREATE INDEX IF NOT EXISTS idx_mem_stats_result ON mem_stats(result_id);
 
+-- Raw per-invocation samples that were aggregated into a result
+CREATE TABLE IF NOT EXISTS samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    sample_index INTEGER NOT NULL,
+    min_ns INTEGER NOT NULL,
+    avg_ns INTEGER NOT NULL,
+    max_ns INTEGER NOT NULL,
+    total_ns INTEGER NOT NULL,
+    iterations INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_samples_result ON samples(result_id);
+
+-- Two runs recorded together by an interleaved A/B benchmark (`bench ab`)
+CREATE TABLE IF NOT EXISTS run_pairs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    base_run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    head_run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    rounds INTEGER NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_pairs_base ON run_pairs(base_run_id);
+CREATE INDEX IF NOT EXISTS idx_run_pairs_head ON run_pairs(head_run_id);
+
+-- Queued benchmark requests (POST /api/jobs, executed by `bench worker`)
+CREATE TABLE IF NOT EXISTS jobs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    commit_ref TEXT NOT NULL,
+    config TEXT NOT NULL DEFAULT '{}',
+    priority INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'queued',  -- queued, running, done, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_id INTEGER REFERENCES runs(id) ON DELETE SET NULL,
+    error TEXT,
+    created_at TEXT NOT NULL,
+    started_at TEXT,
+    finished_at TEXT,
+    heartbeat_at TEXT  -- last sign of life from the worker running the job
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_priority ON jobs(status, priority DESC, id);
+
+-- CPU profiles per benchmark as gzip-compressed folded stacks
+CREATE TABLE IF NOT EXISTS flamegraphs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    benchmark_name TEXT NOT NULL,
+    folded_stacks_gz BLOB NOT NULL,
+    sampling_freq INTEGER NOT NULL DEFAULT 997,
+    created_at TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_flamegraphs_run_benchmark ON flamegraphs(run_id, benchmark_name);
+
+-- Profiling artifacts per result (gzipped CPU profiles, cached flamegraph/callgraph SVGs)
+CREATE TABLE IF NOT EXISTS artifacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    result_id INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    data_blob BLOB NOT NULL,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    created_at TEXT NOT NULL,
+    -- Set when the payload is in the blob store (<db>.blobs/), keyed by
+    -- sha256; data_blob is then empty
+    blob_sha256 TEXT,
+    blob_size INTEGER,
+    UNIQUE(result_id, kind)
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_result_kind ON artifacts(result_id, kind);
+CREATE INDEX IF NOT EXISTS idx_artifacts_blob_sha256 ON artifacts(blob_sha256);
+
+-- Gzip-compressed blobs attached to a whole run (e.g. build output of a failed run)
+CREATE TABLE IF NOT EXISTS run_artifacts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    data_gz BLOB NOT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE(run_id, kind)
+);
+
+-- Per-run measurements that are not benchmarks: build_time (ns) and the size
+-- of each build artifact as size:<file> (bytes)
+CREATE TABLE IF NOT EXISTS run_metrics (
+    run_id INTEGER NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    value INTEGER NOT NULL,
+    unit TEXT NOT NULL,                      -- ns, bytes
+    PRIMARY KEY (run_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_metrics_name ON run_metrics(name);
+
 -- View for easy querying with run context
 CREATE VIEW IF NOT EXISTS results_with_run AS
 SELECT 
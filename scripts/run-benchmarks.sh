@@ -7,10 +7,9 @@
 #
 # This script manages the benchmarking process:
 # 1. Sets up repositories (opentui-bench, opentui)
-# 2. Syncs the benchmark database from Fly.io
-# 3. Identifies commits to benchmark
-# 4. Runs benchmarks using 'bench record'
-# 5. Uploads results back to Fly.io
+# 2. Syncs the benchmark database and its blob store from Fly.io
+# 3. Records every new commit on origin/main using 'bench watch --once'
+# 4. Uploads new blobs, then the database, back to Fly.io
 #
 # It is robust, uses locking to prevent concurrent runs, and handles errors gracefully.
 
@@ -44,9 +43,14 @@ readonly SCRIPT_DIR
 readonly REPOS_DIR="$HOME/repos"
 readonly BENCH_REPO="$REPOS_DIR/opentui-bench"
 readonly OPENTUI_REPO="$REPOS_DIR/opentui"
+readonly WORKTREE_DIR="$REPOS_DIR/opentui-bench-worktree"
 readonly DB_FILE="$BENCH_REPO/public-opentui.db"
+readonly BLOBS_DIR="${DB_FILE}.blobs"
 readonly LOG_FILE="$HOME/benchmark.log"
 readonly FLY_APP="opentui-bench"
+readonly FLY_DB="/data/bench.db"
+readonly FLY_BLOBS_DIR="${FLY_DB}.blobs"
+readonly FLY_BLOBS_TAR="/tmp/bench-blobs.tar"
 
 # Export PATH to include necessary binaries
 export PATH="$HOME/.cargo/bin:$HOME/anyzig:$HOME/.fly/bin:/usr/local/go/bin:$PATH"
@@ -257,6 +261,115 @@ setup_repos() {
 	git fetch simonklee
 }
 
+# fly_get_db downloads the server's DB to $1, together with its WAL, which
+# holds the server's recent writes (such as submitted jobs) until SQLite
+# checkpoints them into the DB file.
+fly_get_db() {
+	local dest="$1"
+	rm -f "$dest" "${dest}-wal" "${dest}-shm" # flyctl sftp refuses to overwrite existing files
+
+	flyctl ssh sftp get "$FLY_DB" "$dest" --app "$FLY_APP" || return 1
+	if ! flyctl ssh sftp get "${FLY_DB}-wal" "${dest}-wal" --app "$FLY_APP" >/dev/null 2>&1; then
+		rm -f "${dest}-wal"
+	fi
+}
+
+# fly_exec runs a shell command on the Fly machine. The command must not
+# contain single quotes.
+fly_exec() {
+	flyctl ssh console --app "$FLY_APP" -C "sh -c '$1'"
+}
+
+# blob_paths turns `find .` output of a blob store into sorted <xx>/<sha>
+# paths, skipping the temp files of interrupted writes.
+blob_paths() {
+	tr -d '\r' | sed -n 's|^\./||; /\.tmp$/d; /^[0-9a-f][0-9a-f]\/[0-9a-f]*$/p' | LC_ALL=C sort
+}
+
+# blob_move_in moves the blobs extracted into staging dir $1 into blob store
+# $2. Each file is renamed into place, so the store never holds a partly
+# written blob under its final name.
+blob_move_in() {
+	local staging="$1" store="$2" path
+	while IFS= read -r path; do
+		mkdir -p "$store/${path%/*}"
+		mv -f "$staging/$path" "$store/$path"
+	done < <(cd "$staging" && find . -type f | blob_paths)
+}
+
+# fly_put_blobs uploads the blobs of the local blob store the server does not
+# have yet, creating the server's store if needed. Blob files are named by
+# their content and never change, so the missing files are all there is to
+# sync.
+fly_put_blobs() {
+	local work rc=0
+	work="$(mktemp -d)"
+	_fly_put_blobs "$work" || rc=$?
+	rm -rf "$work"
+	return "$rc"
+}
+
+_fly_put_blobs() {
+	local work="$1"
+
+	fly_exec "mkdir -p $FLY_BLOBS_DIR && cd $FLY_BLOBS_DIR && find . -type f" | blob_paths >"$work/remote" || return 1
+	(cd "$BLOBS_DIR" && find . -type f) | blob_paths >"$work/local"
+	comm -23 "$work/local" "$work/remote" >"$work/missing"
+	if [[ ! -s "$work/missing" ]]; then
+		info "Fly already has every blob"
+		return 0
+	fi
+
+	log "Uploading $(wc -l <"$work/missing") new blobs to Fly..."
+	tar -C "$BLOBS_DIR" -cf "$work/blobs.tar" -T "$work/missing"
+	fly_exec "rm -f $FLY_BLOBS_TAR" >/dev/null || return 1
+	flyctl ssh sftp put "$work/blobs.tar" "$FLY_BLOBS_TAR" --app "$FLY_APP" || return 1
+	# Unpacked next to the store and renamed into it one file at a time, so
+	# an interrupted upload leaves no truncated blob in the store.
+	local staging="${FLY_BLOBS_DIR}.incoming"
+	fly_exec "set -e; rm -rf $staging; mkdir -p $staging; tar -C $staging -xf $FLY_BLOBS_TAR; cd $staging; for f in */*; do mkdir -p $FLY_BLOBS_DIR/\${f%/*}; mv -f \$f $FLY_BLOBS_DIR/\$f; done; cd /; rm -rf $staging $FLY_BLOBS_TAR"
+}
+
+# fly_get_blobs downloads the blobs of the server's blob store missing
+# locally, creating the local store if needed. It does nothing if the server
+# has no blob store.
+fly_get_blobs() {
+	local work rc=0
+	work="$(mktemp -d)"
+	_fly_get_blobs "$work" || rc=$?
+	rm -rf "$work"
+	return "$rc"
+}
+
+_fly_get_blobs() {
+	local work="$1"
+
+	fly_exec "if [ -d $FLY_BLOBS_DIR ]; then echo store; cd $FLY_BLOBS_DIR && find . -type f; fi" | tr -d '\r' >"$work/listing" || return 1
+	if [[ "$(head -n 1 "$work/listing")" != "store" ]]; then
+		info "Fly has no blob store"
+		return 0
+	fi
+	blob_paths <"$work/listing" >"$work/remote"
+	mkdir -p "$BLOBS_DIR"
+	(cd "$BLOBS_DIR" && find . -type f) | blob_paths >"$work/local"
+	comm -13 "$work/local" "$work/remote" >"$work/missing"
+	if [[ ! -s "$work/missing" ]]; then
+		info "Already have every blob on Fly"
+		return 0
+	fi
+
+	log "Downloading $(wc -l <"$work/missing") new blobs from Fly..."
+	fly_exec "rm -f $FLY_BLOBS_TAR ${FLY_BLOBS_TAR}.list" >/dev/null || return 1
+	flyctl ssh sftp put "$work/missing" "${FLY_BLOBS_TAR}.list" --app "$FLY_APP" || return 1
+	fly_exec "tar -C $FLY_BLOBS_DIR -cf $FLY_BLOBS_TAR -T ${FLY_BLOBS_TAR}.list" || return 1
+	flyctl ssh sftp get "$FLY_BLOBS_TAR" "$work/blobs.tar" --app "$FLY_APP" || return 1
+	fly_exec "rm -f $FLY_BLOBS_TAR ${FLY_BLOBS_TAR}.list" >/dev/null || true
+
+	mkdir -p "$work/staging"
+	tar -C "$work/staging" -xf "$work/blobs.tar"
+	blob_move_in "$work/staging" "$BLOBS_DIR"
+}
+
 sync_db_down() {
 	log "Downloading DB from Fly.io..."
 	cd "$BENCH_REPO"
@@ -268,14 +381,25 @@ sync_db_down() {
 
 	local tmp_db
 	tmp_db="$(mktemp "${DB_FILE}.XXXXXX")"
-	rm -f "$tmp_db" # flyctl sftp refuses to overwrite existing files
 
-	if flyctl ssh sftp get /data/bench.db "$tmp_db" --app "$FLY_APP"; then
+	if fly_get_db "$tmp_db"; then
 		log "Downloaded DB from Fly"
+		# The blobs are fetched after the DB, so they include every blob
+		# the downloaded DB references.
+		if ! fly_get_blobs; then
+			rm -f "$tmp_db" "${tmp_db}-wal"
+			err "Failed to download blobs from Fly"
+			return 1
+		fi
+		# A WAL left next to the old file would be replayed into the new one
+		rm -f "${DB_FILE}-wal" "${DB_FILE}-shm"
 		mv -f "$tmp_db" "$DB_FILE"
+		if [[ -f "${tmp_db}-wal" ]]; then
+			mv -f "${tmp_db}-wal" "${DB_FILE}-wal"
+		fi
 		chmod u+w "$DB_FILE" || true
 	else
-		rm -f "$tmp_db"
+		rm -f "$tmp_db" "${tmp_db}-wal"
 		err "Failed to download DB from Fly"
 		return 1
 	fi
@@ -294,11 +418,36 @@ sync_db_up() {
 		return 1
 	fi
 
-	# Remove remote DB first as sftp put doesn't overwrite
+	# The upload replaces the server's DB, so jobs submitted to it since
+	# sync_db_down are merged into the local DB first. Jobs submitted in the
+	# few seconds between this download and the removal below are still lost.
+	local remote_db
+	remote_db="$(mktemp "${DB_FILE}.remote.XXXXXX")"
+	if ! fly_get_db "$remote_db"; then
+		rm -f "$remote_db" "${remote_db}-wal"
+		err "Failed to download DB from Fly to merge its jobs; not uploading"
+		return 1
+	fi
+	if ! ./bench db merge-jobs --db "$DB_FILE" "$remote_db"; then
+		rm -f "$remote_db" "${remote_db}-wal" "${remote_db}-shm"
+		err "Failed to merge jobs from Fly; not uploading"
+		return 1
+	fi
+	rm -f "$remote_db" "${remote_db}-wal" "${remote_db}-shm"
+
+	# Blobs go up before the DB that references them; the server refuses a
+	# DB whose blob store is missing.
+	if [[ -d "$BLOBS_DIR" ]] && ! fly_put_blobs; then
+		err "Failed to upload blobs to Fly; not uploading the DB"
+		return 1
+	fi
+
+	# Remove remote DB first as sftp put doesn't overwrite, together with its
+	# WAL files, which would otherwise be replayed into the uploaded DB
 	log "Removing existing remote DB on machine $machine_id..."
-	flyctl ssh console --machine "$machine_id" --app "$FLY_APP" -C "rm -f /data/bench.db" || true
+	flyctl ssh console --machine "$machine_id" --app "$FLY_APP" -C "rm -f $FLY_DB ${FLY_DB}-wal ${FLY_DB}-shm" || true
 
-	if flyctl ssh sftp put "$DB_FILE" /data/bench.db --machine "$machine_id" --app "$FLY_APP"; then
+	if flyctl ssh sftp put "$DB_FILE" "$FLY_DB" --machine "$machine_id" --app "$FLY_APP"; then
 		log "Uploaded DB to Fly"
 	else
 		err "Failed to upload DB to Fly!"
@@ -309,61 +458,31 @@ sync_db_up() {
 	flyctl apps restart "$FLY_APP"
 }
 
-reset_opentui() {
-	if [[ -d "$OPENTUI_REPO" ]]; then
-		cd "$OPENTUI_REPO"
-		git reset --hard HEAD 2>/dev/null || true
-	fi
-}
-
 run_benchmarks() {
-	cd "$OPENTUI_REPO"
-
-	# Find latest recorded commit
-	local latest_recorded
-	latest_recorded=$("$BENCH_REPO/bench" latest-commit --db "$DB_FILE" 2>/dev/null || echo "")
-
-	local commits
-	if [[ -n "$latest_recorded" ]] && git cat-file -e "$latest_recorded" 2>/dev/null; then
-		log "Latest recorded: ${latest_recorded:0:7}"
-		commits=$(git log --reverse --format='%H' "${latest_recorded}..origin/main")
-	else
-		log "No recorded commits found, checking last commit on main"
-		commits=$(git log --format='%H' origin/main -1)
-	fi
+	cd "$BENCH_REPO"
 
-	local next_commit="${commits%%$'\n'*}"
-	if [[ -z "$next_commit" ]]; then
-		log "All commits already recorded, nothing to do"
+	# bench watch finds the unrecorded commits on origin/main (oldest first),
+	# records them in a worktree and holds its own file lock while doing so.
+	if $dry_run; then
+		log "Dry run: would exec ./bench watch --once ..."
 		return 0
 	fi
 
-	log "Processing commit: ${next_commit:0:7}"
-
-	# Register cleanup to reset repo if we fail during processing
-	cleanup_register "reset_opentui"
+	local before after
+	before=$(./bench latest-commit --branch main --db "$DB_FILE" 2>/dev/null || echo "")
 
-	# Checkout the commit
-	git checkout "$next_commit"
+	./bench watch --once --repo "$OPENTUI_REPO" --remote origin --branch main \
+		--worktree "$WORKTREE_DIR" --db "$DB_FILE" \
+		--samples 3 --notes "Hetzner CCX13" --profile cpu --time-build
 
-	# Run benchmarks
-	cd "$BENCH_REPO"
-	# Samples set to 3 for dedicated hardware (adjust as needed)
-	if $dry_run; then
-		log "Dry run: would exec ./bench record ..."
-	else
-		./bench record --repo "$OPENTUI_REPO" --db "$DB_FILE" --samples 3 --notes "Hetzner CCX13" --profile cpu
-	fi
-
-	# Reset opentui repo
-	reset_opentui
-
-	# Upload results
-	if ! $dry_run; then
-		sync_db_up
+	after=$(./bench latest-commit --branch main --db "$DB_FILE" 2>/dev/null || echo "")
+	if [[ "$before" == "$after" ]]; then
+		log "No new commits recorded"
+		return 0
 	fi
 
-	log "Benchmark run complete for ${next_commit:0:7}"
+	sync_db_up
+	log "Benchmark run complete up to ${after:0:7}"
 }
 
 # --- Main ---